@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+// ValidateAdminToken checks the bearer token of admin routes
+func ValidateAdminToken(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	jsonRender := render.New()
+	// admin routes are disabled unless the token is set
+	adminToken := config.Auth.AdminToken
+	if adminToken == "" {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
+
+	tok, err := getAccessToken(r)
+	if err != nil || subtle.ConstantTimeCompare([]byte(tok), []byte(adminToken)) != 1 {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	next(w, r)
+}
+
+// GetLogLevelEndpoint ...
+func GetLogLevelEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]string{"level": appLog.Level().String()})
+}
+
+// SetLogLevelEndpoint changes the application log level to the form value level
+func SetLogLevelEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	if err := r.ParseForm(); err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+
+	level, err := ParseLogLevel(r.Form.Get("level"))
+	if err != nil || level == LOG_FATAL {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+
+	appLog.SetLevel(level)
+	appLog.Warn("log level is changed", F("level", level.String()))
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]string{"level": level.String()})
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp/v3"
+	"github.com/unrolled/render"
+)
+
+const (
+	CATALOGUE_FORMAT_JSON = "json"
+	CATALOGUE_FORMAT_CSV  = "csv"
+
+	// CATALOGUE_MAX_SIZE limits the size of an uploaded catalogue
+	CATALOGUE_MAX_SIZE = 32 << 20
+)
+
+var (
+	// catalogueCsvColumns are the columns of a csv catalogue, a perfum per
+	// row. composition is a list of note:component pairs separated by ";",
+	// small_image and large_image are paths in the data dir.
+	catalogueCsvColumns = []string{"id", "name", "year", "description_ru", "description_en", "brand", "gender",
+		"group", "country", "season", "tsod", "type", "shop", "small_image", "large_image", "composition"}
+
+	// reference tables of catalogue items are exported by english names
+	catalogueLang = NameFields["en"]
+)
+
+// CatalogueImage is a pair of image files relative to the data dir
+type CatalogueImage struct {
+	Small string `json:"small,omitempty"`
+	Large string `json:"large,omitempty"`
+}
+
+// CatalogueComposition is a note and component of a perfum
+type CatalogueComposition struct {
+	Note      string `json:"note"`
+	Component string `json:"component"`
+}
+
+// CatalogueItem is a perfum of a catalogue file. The perfum is identified by
+// id or, without one, by name and brand. References to reference tables,
+// notes and components are uuids or names.
+type CatalogueItem struct {
+	Id            string                 `json:"id,omitempty"`
+	Name          string                 `json:"name"`
+	Year          int64                  `json:"year,omitempty"`
+	DescriptionRu string                 `json:"description_ru,omitempty"`
+	DescriptionEn string                 `json:"description_en,omitempty"`
+	Brand         string                 `json:"brand,omitempty"`
+	Gender        string                 `json:"gender,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Country       string                 `json:"country,omitempty"`
+	Season        string                 `json:"season,omitempty"`
+	Tsod          string                 `json:"tsod,omitempty"`
+	Type          string                 `json:"type,omitempty"`
+	Shop          string                 `json:"shop,omitempty"`
+	Image         *CatalogueImage        `json:"image,omitempty"`
+	Composition   []CatalogueComposition `json:"composition"`
+}
+
+// CatalogueImportError describes a rejected item, numbered from 1
+type CatalogueImportError struct {
+	Item  int    `json:"item"`
+	Error string `json:"error"`
+}
+
+// CatalogueImportResult ...
+type CatalogueImportResult struct {
+	Inserted int                    `json:"inserted"`
+	Updated  int                    `json:"updated"`
+	Errors   []CatalogueImportError `json:"errors"`
+}
+
+// Validate checks the fields which don't need the database
+func (item *CatalogueItem) Validate() error {
+	if strings.TrimSpace(item.Name) == "" {
+		return errors.New("name is empty")
+	}
+	if item.Id != "" && !uuidRegex.MatchString(item.Id) {
+		return fmt.Errorf("invalid id %q", item.Id)
+	}
+	if item.Year < 0 || item.Year > int64(time.Now().Year()+1) {
+		return fmt.Errorf("invalid year %d", item.Year)
+	}
+	for _, c := range item.Composition {
+		if c.Note == "" || c.Component == "" {
+			return errors.New("composition needs note and component")
+		}
+	}
+	if item.Image != nil {
+		// the images are served from the data dir, so their paths must stay in it
+		for _, path := range []string{item.Image.Small, item.Image.Large} {
+			if path != "" && !filepath.IsLocal(filepath.Clean(path)) {
+				return fmt.Errorf("invalid image path %q", path)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadCatalogue parses a catalogue in format
+func ReadCatalogue(in io.Reader, format string) ([]CatalogueItem, error) {
+	switch format {
+	case CATALOGUE_FORMAT_JSON:
+		var items []CatalogueItem
+		if err := json.NewDecoder(in).Decode(&items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	case CATALOGUE_FORMAT_CSV:
+		return readCatalogueCsv(in)
+	}
+	return nil, errors.New("unknown catalogue format " + format)
+}
+
+func readCatalogueCsv(in io.Reader) ([]CatalogueItem, error) {
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("column name is missing")
+	}
+
+	var items []CatalogueItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := CatalogueItem{
+			Id:            field("id"),
+			Name:          field("name"),
+			DescriptionRu: field("description_ru"),
+			DescriptionEn: field("description_en"),
+			Brand:         field("brand"),
+			Gender:        field("gender"),
+			Group:         field("group"),
+			Country:       field("country"),
+			Season:        field("season"),
+			Tsod:          field("tsod"),
+			Type:          field("type"),
+			Shop:          field("shop"),
+		}
+		if year := field("year"); year != "" {
+			if item.Year, err = strconv.ParseInt(year, 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid year %q", line, year)
+			}
+		}
+		if field("small_image") != "" || field("large_image") != "" {
+			item.Image = &CatalogueImage{Small: field("small_image"), Large: field("large_image")}
+		}
+		for _, pair := range strings.Split(field("composition"), ";") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			parts := strings.SplitN(pair, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("line %d: invalid composition %q", line, pair)
+			}
+			item.Composition = append(item.Composition, CatalogueComposition{
+				Note:      strings.TrimSpace(parts[0]),
+				Component: strings.TrimSpace(parts[1]),
+			})
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// WriteCatalogue writes items in format
+func WriteCatalogue(out io.Writer, items []CatalogueItem, format string) error {
+	switch format {
+	case CATALOGUE_FORMAT_JSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	case CATALOGUE_FORMAT_CSV:
+		writer := csv.NewWriter(out)
+		if err := writer.Write(catalogueCsvColumns); err != nil {
+			return err
+		}
+		for _, item := range items {
+			image := CatalogueImage{}
+			if item.Image != nil {
+				image = *item.Image
+			}
+			composition := make([]string, len(item.Composition))
+			for i, c := range item.Composition {
+				composition[i] = c.Note + ":" + c.Component
+			}
+			year := ""
+			if item.Year != 0 {
+				year = strconv.FormatInt(item.Year, 10)
+			}
+			if err := writer.Write([]string{item.Id, item.Name, year, item.DescriptionRu, item.DescriptionEn,
+				item.Brand, item.Gender, item.Group, item.Country, item.Season, item.Tsod, item.Type, item.Shop,
+				image.Small, image.Large, strings.Join(composition, ";")}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return errors.New("unknown catalogue format " + format)
+}
+
+// ImportCatalogue upserts items in one transaction. Invalid items are
+// skipped and reported in the result, each item is applied in a savepoint so
+// a rejected one doesn't abort the transaction.
+func ImportCatalogue(ctx context.Context, items []CatalogueItem) (*CatalogueImportResult, error) {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := importCatalogue(tx, items)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importCatalogue upserts items in tx, which the caller rolls back on error
+func importCatalogue(tx *gorp.Transaction, items []CatalogueItem) (*CatalogueImportResult, error) {
+	result := &CatalogueImportResult{Errors: []CatalogueImportError{}}
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			result.Errors = append(result.Errors, CatalogueImportError{Item: i + 1, Error: err.Error()})
+			continue
+		}
+
+		if err := tx.Savepoint("catalogue_item"); err != nil {
+			return nil, err
+		}
+		inserted, err := upsertCatalogueItem(tx, &items[i])
+		if err != nil {
+			if err := tx.RollbackToSavepoint("catalogue_item"); err != nil {
+				return nil, err
+			}
+			result.Errors = append(result.Errors, CatalogueImportError{Item: i + 1, Error: err.Error()})
+			continue
+		}
+		if err := tx.ReleaseSavepoint("catalogue_item"); err != nil {
+			return nil, err
+		}
+
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// nameColumns returns the name columns of the entity table, ru first
+func nameColumns(e *EntityDesc) []string {
+	ru, en := e.NameField(NameFields["ru"]), e.NameField(NameFields["en"])
+	if ru == en {
+		return []string{ru}
+	}
+	return []string{ru, en}
+}
+
+// entityRefId returns the id of the row of the entity with the uuid or name
+// ref, null if ref is empty
+func entityRefId(tx *gorp.Transaction, e *EntityDesc, ref string) (sql.NullInt64, error) {
+	if ref == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	var id int64
+	var err error
+	if uuidRegex.MatchString(ref) {
+		id, err = tx.SelectInt("SELECT id FROM "+e.Table+" WHERE uuid=$1", ref)
+	} else {
+		columns := nameColumns(e)
+		conditions := make([]string, len(columns))
+		for i, column := range columns {
+			conditions[i] = column + "=$1"
+		}
+		id, err = tx.SelectInt("SELECT id FROM "+e.Table+" WHERE "+strings.Join(conditions, " OR ")+" ORDER BY id LIMIT 1", ref)
+	}
+	if err != nil {
+		return sql.NullInt64{}, err
+	} else if id == 0 {
+		return sql.NullInt64{}, fmt.Errorf("%s %q is not found", strings.ToLower(e.Name), ref)
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// saveImage updates the image id or inserts a new one if id is null and
+// returns its id
+func saveImage(tx *gorp.Transaction, id sql.NullInt64, image CatalogueImage) (int64, error) {
+	split := func(path string) (dir, name sql.NullString) {
+		if path == "" {
+			return
+		}
+		d, n := filepath.Split(filepath.Clean(path))
+		return sql.NullString{String: d, Valid: true}, sql.NullString{String: n, Valid: true}
+	}
+	smallPath, smallName := split(image.Small)
+	largePath, largeName := split(image.Large)
+
+	if id.Valid {
+		_, err := tx.Exec("UPDATE images SET small_img_filename=$2, small_img_path=$3, large_img_filename=$4, large_img_path=$5 WHERE id=$1",
+			id.Int64, smallName, smallPath, largeName, largePath)
+		return id.Int64, err
+	}
+
+	uuid, err := NewUuid()
+	if err != nil {
+		return 0, err
+	}
+	return tx.SelectInt("INSERT INTO images (uuid, small_img_filename, small_img_path, large_img_filename, large_img_path) "+
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id", uuid, smallName, smallPath, largeName, largePath)
+}
+
+// catalogueRef is a reference of a catalogue item to a reference table
+type catalogueRef struct {
+	Entity *EntityDesc
+	Ref    string
+}
+
+// refs returns the references of item, brand first
+func (item *CatalogueItem) refs() []catalogueRef {
+	return []catalogueRef{
+		{brandEntity, item.Brand},
+		{genderEntity, item.Gender},
+		{groupEntity, item.Group},
+		{countryEntity, item.Country},
+		{seasonEntity, item.Season},
+		{timeOfDayEntity, item.Tsod},
+		{typeEntity, item.Type},
+		{shopEntity, item.Shop},
+	}
+}
+
+// upsertCatalogueItem inserts or updates the perfum of item and replaces its
+// composition, returns true if the perfum is new
+func upsertCatalogueItem(tx *gorp.Transaction, item *CatalogueItem) (bool, error) {
+	refs := item.refs()
+	refIds := make([]interface{}, len(refs))
+	for i, ref := range refs {
+		id, err := entityRefId(tx, ref.Entity, ref.Ref)
+		if err != nil {
+			return false, err
+		}
+		refIds[i] = id
+	}
+
+	existing := struct {
+		Id            int64         `db:"id"`
+		Uuid          string        `db:"uuid"`
+		DescriptionId sql.NullInt64 `db:"description_id"`
+		ImageId       sql.NullInt64 `db:"image_id"`
+	}{}
+	var err error
+	if item.Id != "" {
+		err = tx.SelectOne(&existing, "SELECT id, uuid, description_id, image_id FROM parfum_info WHERE uuid=$1", item.Id)
+	} else {
+		err = tx.SelectOne(&existing, "SELECT id, uuid, description_id, image_id FROM parfum_info "+
+			"WHERE name=$1 AND brand_id IS NOT DISTINCT FROM $2 ORDER BY id LIMIT 1", item.Name, refIds[0])
+	}
+	inserted := err == sql.ErrNoRows
+	if err != nil && !inserted {
+		return false, err
+	}
+
+	if existing.DescriptionId.Valid {
+		if _, err := tx.Exec("UPDATE descriptions SET description_ru=$2, description_en=$3 WHERE id=$1",
+			existing.DescriptionId.Int64, item.DescriptionRu, item.DescriptionEn); err != nil {
+			return false, err
+		}
+	} else {
+		uuid, err := NewUuid()
+		if err != nil {
+			return false, err
+		}
+		if existing.DescriptionId.Int64, err = tx.SelectInt("INSERT INTO descriptions (uuid, description_ru, description_en) "+
+			"VALUES ($1, $2, $3) RETURNING id", uuid, item.DescriptionRu, item.DescriptionEn); err != nil {
+			return false, err
+		}
+		existing.DescriptionId.Valid = true
+	}
+
+	// an item without image keeps the stored one
+	if item.Image != nil && (item.Image.Small != "" || item.Image.Large != "") {
+		if existing.ImageId.Int64, err = saveImage(tx, existing.ImageId, *item.Image); err != nil {
+			return false, err
+		}
+		existing.ImageId.Valid = true
+	}
+
+	values := append([]interface{}{item.Name, item.Year, existing.DescriptionId, existing.ImageId}, refIds...)
+	if inserted {
+		if existing.Uuid = item.Id; existing.Uuid == "" {
+			if existing.Uuid, err = NewUuid(); err != nil {
+				return false, err
+			}
+		}
+		existing.Id, err = tx.SelectInt("INSERT INTO parfum_info (name, year, description_id, image_id, brand_id, "+
+			"gender_id, group_id, country_id, season_id, tsod_id, type_id, shop_id, uuid) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id",
+			append(values, existing.Uuid)...)
+	} else {
+		_, err = tx.Exec("UPDATE parfum_info SET name=$1, year=$2, description_id=$3, image_id=$4, brand_id=$5, "+
+			"gender_id=$6, group_id=$7, country_id=$8, season_id=$9, tsod_id=$10, type_id=$11, shop_id=$12 WHERE id=$13",
+			append(values, existing.Id)...)
+	}
+	if err != nil {
+		return false, err
+	}
+	item.Id = existing.Uuid
+
+	// the composition of the item replaces the stored one
+	if _, err := tx.Exec("DELETE FROM parfums WHERE parfum_info_id=$1", existing.Id); err != nil {
+		return false, err
+	}
+	for _, c := range item.Composition {
+		noteId, err := entityRefId(tx, noteEntity, c.Note)
+		if err != nil {
+			return false, err
+		}
+		componentId, err := entityRefId(tx, componentEntity, c.Component)
+		if err != nil {
+			return false, err
+		}
+		uuid, err := NewUuid()
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec("INSERT INTO parfums (uuid, parfum_info_id, note_id, component_id) VALUES ($1, $2, $3, $4)",
+			uuid, existing.Id, noteId, componentId); err != nil {
+			return false, err
+		}
+	}
+
+	return inserted, nil
+}
+
+// ExportCatalogue returns every perfum as a catalogue item, references are
+// english names
+func ExportCatalogue(ctx context.Context) ([]CatalogueItem, error) {
+	lf := catalogueLang
+	type infoRow struct {
+		Id            int64          `db:"id"`
+		Uuid          string         `db:"uuid"`
+		Name          string         `db:"name"`
+		Year          int64          `db:"year"`
+		DescriptionRu string         `db:"description_ru"`
+		DescriptionEn string         `db:"description_en"`
+		Brand         string         `db:"brand"`
+		Gender        string         `db:"gender"`
+		Group         string         `db:"group_name"`
+		Country       string         `db:"country"`
+		Season        string         `db:"season"`
+		Tsod          string         `db:"tsod"`
+		Type          string         `db:"type"`
+		Shop          string         `db:"shop"`
+		SmallPath     sql.NullString `db:"small_img_path"`
+		SmallName     sql.NullString `db:"small_img_filename"`
+		LargePath     sql.NullString `db:"large_img_path"`
+		LargeName     sql.NullString `db:"large_img_filename"`
+	}
+	var infos []infoRow
+	if err := readSelect(ctx, &infos, "catalogue_perfums", "SELECT parfum_info.id, parfum_info.uuid, parfum_info.name, parfum_info.year, "+
+		"COALESCE(descriptions.description_ru, '') AS description_ru, COALESCE(descriptions.description_en, '') AS description_en, "+
+		"COALESCE(brands."+lf.BrandsName+", '') AS brand, COALESCE(gender."+lf.GenderName+", '') AS gender, "+
+		"COALESCE(groups."+lf.GroupsName+", '') AS group_name, COALESCE(countries."+lf.CountriesName+", '') AS country, "+
+		"COALESCE(seasons."+lf.SeasonsName+", '') AS season, COALESCE(times_of_day."+lf.TsodName+", '') AS tsod, "+
+		"COALESCE(types."+lf.TypesName+", '') AS type, COALESCE(shops."+lf.ShopsName+", '') AS shop, "+
+		"images.small_img_path, images.small_img_filename, images.large_img_path, images.large_img_filename "+
+		"FROM parfum_info LEFT JOIN descriptions ON parfum_info.description_id=descriptions.id "+
+		"LEFT JOIN brands ON parfum_info.brand_id=brands.id LEFT JOIN gender ON parfum_info.gender_id=gender.id "+
+		"LEFT JOIN groups ON parfum_info.group_id=groups.id LEFT JOIN countries ON parfum_info.country_id=countries.id "+
+		"LEFT JOIN seasons ON parfum_info.season_id=seasons.id LEFT JOIN times_of_day ON parfum_info.tsod_id=times_of_day.id "+
+		"LEFT JOIN types ON parfum_info.type_id=types.id LEFT JOIN shops ON parfum_info.shop_id=shops.id "+
+		"LEFT JOIN images ON parfum_info.image_id=images.id ORDER BY parfum_info.id"); err != nil {
+		return nil, err
+	}
+
+	type compositionRow struct {
+		InfoId    int64  `db:"parfum_info_id"`
+		Note      string `db:"note"`
+		Component string `db:"component"`
+	}
+	var composition []compositionRow
+	if err := readSelect(ctx, &composition, "catalogue_composition", "SELECT parfums.parfum_info_id, notes."+lf.NotesName+" AS note, "+
+		"components."+lf.ComponentsName+" AS component FROM parfums INNER JOIN notes ON parfums.note_id=notes.id "+
+		"INNER JOIN components ON parfums.component_id=components.id ORDER BY parfums.parfum_info_id, parfums.id"); err != nil {
+		return nil, err
+	}
+	compositionOf := make(map[int64][]CatalogueComposition)
+	for _, c := range composition {
+		compositionOf[c.InfoId] = append(compositionOf[c.InfoId], CatalogueComposition{Note: c.Note, Component: c.Component})
+	}
+
+	imagePath := func(dir, name sql.NullString) string {
+		if !name.Valid {
+			return ""
+		}
+		return filepath.Join(dir.String, name.String)
+	}
+	items := make([]CatalogueItem, len(infos))
+	for i, info := range infos {
+		items[i] = CatalogueItem{
+			Id:            info.Uuid,
+			Name:          info.Name,
+			Year:          info.Year,
+			DescriptionRu: info.DescriptionRu,
+			DescriptionEn: info.DescriptionEn,
+			Brand:         info.Brand,
+			Gender:        info.Gender,
+			Group:         info.Group,
+			Country:       info.Country,
+			Season:        info.Season,
+			Tsod:          info.Tsod,
+			Type:          info.Type,
+			Shop:          info.Shop,
+			Composition:   compositionOf[info.Id],
+		}
+		if items[i].Composition == nil {
+			items[i].Composition = []CatalogueComposition{}
+		}
+		if small, large := imagePath(info.SmallPath, info.SmallName), imagePath(info.LargePath, info.LargeName); small != "" || large != "" {
+			items[i].Image = &CatalogueImage{Small: small, Large: large}
+		}
+	}
+	return items, nil
+}
+
+// catalogueFormat returns the format of the request body or of the format
+// query parameter, json by default
+func catalogueFormat(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "text/csv" {
+			format = CATALOGUE_FORMAT_CSV
+		} else {
+			format = CATALOGUE_FORMAT_JSON
+		}
+	}
+	if format != CATALOGUE_FORMAT_JSON && format != CATALOGUE_FORMAT_CSV {
+		return "", errors.New("unknown catalogue format " + format)
+	}
+	return format, nil
+}
+
+// ImportCatalogueEndpoint upserts the catalogue of the request body, json or
+// csv by content type or the format query parameter
+func ImportCatalogueEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+
+	format, err := catalogueFormat(r)
+	if err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+	items, err := ReadCatalogue(http.MaxBytesReader(w, r.Body, CATALOGUE_MAX_SIZE), format)
+	if err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request", "error": err.Error()})
+		return
+	}
+
+	result, err := store.Catalogue.Import(r.Context(), items)
+	if err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
+	appLog.Info("catalogue is imported", F("inserted", result.Inserted), F("updated", result.Updated), F("skipped", len(result.Errors)))
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, result)
+}
+
+// ExportCatalogueEndpoint dumps the catalogue in the format of the query
+// parameter, json by default
+func ExportCatalogueEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+
+	format, err := catalogueFormat(r)
+	if err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+	items, err := store.Catalogue.Export(r.Context())
+	if err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
+
+	contentType := "application/json; charset=UTF-8"
+	if format == CATALOGUE_FORMAT_CSV {
+		contentType = "text/csv; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=catalogue."+format)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := WriteCatalogue(w, items, format); err != nil {
+		TracePrintError(err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCatalogueImagePaths(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Image CatalogueImage
+		Valid bool
+	}{
+		{"Relative", CatalogueImage{Small: "images/small/a.jpg", Large: "images/large/a.jpg"}, true},
+		{"Empty", CatalogueImage{}, true},
+		{"CleanedInside", CatalogueImage{Small: "images/../images/small/a.jpg"}, true},
+		{"Absolute", CatalogueImage{Small: "/etc/passwd"}, false},
+		{"Parent", CatalogueImage{Small: "../../etc/passwd"}, false},
+		{"ParentAfterClean", CatalogueImage{Large: "images/../../etc/passwd"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			image := c.Image
+			item := CatalogueItem{Name: "Shalimar", Image: &image}
+			if err := item.Validate(); (err == nil) != c.Valid {
+				t.Errorf("Validate() = %v, expected valid %v", err, c.Valid)
+			}
+		})
+	}
+}
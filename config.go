@@ -0,0 +1,454 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// ENV_FILE is loaded into the environment if present, before env overrides
+// are applied
+const ENV_FILE = "openshift.env"
+
+// Config is the configuration of the service. It is loaded from a YAML file,
+// then overridden by environment variables listed in configEnv.
+type Config struct {
+	Server     ServerConfig     `yaml:"server" json:"server"`
+	Database   DatabaseConfig   `yaml:"database" json:"database"`
+	Paths      PathsConfig      `yaml:"paths" json:"paths"`
+	Auth       AuthConfig       `yaml:"auth" json:"auth"`
+	Cache      CacheConfig      `yaml:"cache" json:"cache"`
+	Pagination PaginationConfig `yaml:"pagination" json:"pagination"`
+	Log        LogConfig        `yaml:"log" json:"log"`
+	Tracing    TracingConfig    `yaml:"tracing" json:"tracing"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit" json:"rate_limit"`
+	Debug      DebugConfig      `yaml:"debug" json:"debug"`
+}
+
+type ServerConfig struct {
+	Host                string        `yaml:"host" json:"host"`
+	Port                string        `yaml:"port" json:"port"`
+	Protocol            string        `yaml:"protocol" json:"protocol"`       // of links in responses
+	PublicHost          string        `yaml:"public_host" json:"public_host"` // of links in responses
+	ReadTimeout         time.Duration `yaml:"read_timeout" json:"read_timeout"`
+	WriteTimeout        time.Duration `yaml:"write_timeout" json:"write_timeout"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period" json:"shutdown_grace_period"`
+}
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url" json:"url"`
+	Name            string        `yaml:"name" json:"name"`
+	MaxOpenConns    int           `yaml:"max_open_conns" json:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" json:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" json:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" json:"conn_max_idle_time"`
+	SSLMode         string        `yaml:"ssl_mode" json:"ssl_mode"`           // disable, require, verify-ca or verify-full
+	SSLRootCert     string        `yaml:"ssl_root_cert" json:"ssl_root_cert"` // CA of the server certificate
+	SSLCert         string        `yaml:"ssl_cert" json:"ssl_cert"`           // client certificate
+	SSLKey          string        `yaml:"ssl_key" json:"ssl_key"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" json:"connect_timeout"`
+	StartupTimeout  time.Duration `yaml:"startup_timeout" json:"startup_timeout"` // retrying to reach the database on start
+	ReadRetries     int           `yaml:"read_retries" json:"read_retries"`       // of read queries failed on a lost connection
+	AutoMigrate     bool          `yaml:"auto_migrate" json:"auto_migrate"`       // apply pending migrations on start
+	// QueryTimeout bounds the time the queries of a request may take, 0
+	// disables it. QueryTimeouts overrides it for the routes of its keys.
+	QueryTimeout  time.Duration            `yaml:"query_timeout" json:"query_timeout"`
+	QueryTimeouts map[string]time.Duration `yaml:"query_timeouts" json:"query_timeouts"`
+}
+
+type PathsConfig struct {
+	RepoDir string `yaml:"repo_dir" json:"repo_dir"` // contains fixtures
+	DataDir string `yaml:"data_dir" json:"data_dir"` // contains client_secret.json and images
+}
+
+type AuthConfig struct {
+	AccessTokenLifetime  time.Duration `yaml:"access_token_lifetime" json:"access_token_lifetime"`
+	RefreshTokenLifetime time.Duration `yaml:"refresh_token_lifetime" json:"refresh_token_lifetime"`
+	AdminToken           string        `yaml:"admin_token" json:"admin_token"`
+	// CertURL serves x509 certificates of id token signing keys by key id
+	CertURL string `yaml:"cert_url" json:"cert_url"`
+	// IdTokenIssuer is the expected iss of id tokens, the secure token
+	// service of the OAuth project if empty
+	IdTokenIssuer string `yaml:"id_token_issuer" json:"id_token_issuer"`
+}
+
+type CacheConfig struct {
+	PerfumsCountRefreshInterval time.Duration `yaml:"perfums_count_refresh_interval" json:"perfums_count_refresh_interval"`
+	// pause between tables to spread the load of a refresh
+	PerfumsCountRefreshPause time.Duration `yaml:"perfums_count_refresh_pause" json:"perfums_count_refresh_pause"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int64 `yaml:"default_limit" json:"default_limit"`
+	MaxLimit     int64 `yaml:"max_limit" json:"max_limit"`
+}
+
+type LogConfig struct {
+	Level               string  `yaml:"level" json:"level"`
+	Format              string  `yaml:"format" json:"format"`
+	AccessLogFormat     string  `yaml:"access_log_format" json:"access_log_format"`
+	AccessLogOutput     string  `yaml:"access_log_output" json:"access_log_output"` // stdout, stderr or file path
+	AccessLogSampleRate float64 `yaml:"access_log_sample_rate" json:"access_log_sample_rate"`
+}
+
+type TracingConfig struct {
+	Exporter   string  `yaml:"exporter" json:"exporter"`
+	SampleRate float64 `yaml:"sample_rate" json:"sample_rate"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TrustedProxies are the ips or CIDR networks of the proxies whose
+	// X-Forwarded-For header gives the client ip
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+}
+
+type DebugConfig struct {
+	Addr  string `yaml:"addr" json:"addr"` // debug server is disabled if empty
+	Token string `yaml:"token" json:"token"`
+}
+
+// config is the configuration of the running service, set by main
+var config = DefaultConfig()
+
+// DefaultConfig returns the configuration for local development
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Host:                "127.0.0.1",
+			Port:                "8080",
+			Protocol:            "http",
+			PublicHost:          "localhost:8080",
+			ReadTimeout:         15 * time.Second,
+			WriteTimeout:        30 * time.Second,
+			IdleTimeout:         120 * time.Second,
+			ShutdownGracePeriod: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			URL:             "postgres://postgres@localhost:5432",
+			Name:            "fragrances",
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			SSLMode:         "disable",
+			ConnectTimeout:  5 * time.Second,
+			StartupTimeout:  time.Minute,
+			ReadRetries:     2,
+			QueryTimeout:    10 * time.Second,
+			QueryTimeouts: map[string]time.Duration{
+				"ImportCatalogue": 30 * time.Second,
+				"ExportCatalogue": 30 * time.Second,
+			},
+		},
+		Paths: PathsConfig{
+			RepoDir: ".",
+			DataDir: "data",
+		},
+		Auth: AuthConfig{
+			AccessTokenLifetime:  24 * time.Hour,
+			RefreshTokenLifetime: 7 * 24 * time.Hour,
+			CertURL:              certificateURL,
+		},
+		Cache: CacheConfig{
+			PerfumsCountRefreshInterval: 4 * time.Hour,
+			PerfumsCountRefreshPause:    10 * time.Second,
+		},
+		Pagination: PaginationConfig{
+			DefaultLimit: 10,
+			MaxLimit:     100,
+		},
+		Log: LogConfig{
+			Level:               LOG_INFO.String(),
+			Format:              LOG_FORMAT_LOGFMT,
+			AccessLogFormat:     LOG_FORMAT_LOGFMT,
+			AccessLogOutput:     "stdout",
+			AccessLogSampleRate: 1,
+		},
+		Tracing: TracingConfig{
+			Exporter:   TRACING_EXPORTER_NONE,
+			SampleRate: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+		},
+	}
+}
+
+// configEnv maps environment variables to the fields they override. The
+// OpenShift names are kept for existing deployments.
+func configEnv(c *Config) map[string]interface{} {
+	return map[string]interface{}{
+		"OPENSHIFT_GO_IP":                           &c.Server.Host,
+		"OPENSHIFT_GO_PORT":                         &c.Server.Port,
+		"FRAGRANCES_API_PROTOCOL":                   &c.Server.Protocol,
+		"OPENSHIFT_APP_DNS":                         &c.Server.PublicHost,
+		"FRAGRANCES_HTTP_READ_TIMEOUT":              &c.Server.ReadTimeout,
+		"FRAGRANCES_HTTP_WRITE_TIMEOUT":             &c.Server.WriteTimeout,
+		"FRAGRANCES_HTTP_IDLE_TIMEOUT":              &c.Server.IdleTimeout,
+		"FRAGRANCES_SHUTDOWN_GRACE_PERIOD":          &c.Server.ShutdownGracePeriod,
+		"OPENSHIFT_POSTGRESQL_DB_URL":               &c.Database.URL,
+		"FRAGRANCES_DB_NAME":                        &c.Database.Name,
+		"FRAGRANCES_DB_MAX_OPEN_CONNS":              &c.Database.MaxOpenConns,
+		"FRAGRANCES_DB_MAX_IDLE_CONNS":              &c.Database.MaxIdleConns,
+		"FRAGRANCES_DB_CONN_MAX_LIFETIME":           &c.Database.ConnMaxLifetime,
+		"FRAGRANCES_DB_CONN_MAX_IDLE_TIME":          &c.Database.ConnMaxIdleTime,
+		"FRAGRANCES_DB_SSL_MODE":                    &c.Database.SSLMode,
+		"FRAGRANCES_DB_SSL_ROOT_CERT":               &c.Database.SSLRootCert,
+		"FRAGRANCES_DB_SSL_CERT":                    &c.Database.SSLCert,
+		"FRAGRANCES_DB_SSL_KEY":                     &c.Database.SSLKey,
+		"FRAGRANCES_DB_CONNECT_TIMEOUT":             &c.Database.ConnectTimeout,
+		"FRAGRANCES_DB_STARTUP_TIMEOUT":             &c.Database.StartupTimeout,
+		"FRAGRANCES_DB_READ_RETRIES":                &c.Database.ReadRetries,
+		"FRAGRANCES_DB_AUTO_MIGRATE":                &c.Database.AutoMigrate,
+		"FRAGRANCES_DB_QUERY_TIMEOUT":               &c.Database.QueryTimeout,
+		"OPENSHIFT_REPO_DIR":                        &c.Paths.RepoDir,
+		"OPENSHIFT_DATA_DIR":                        &c.Paths.DataDir,
+		"FRAGRANCES_ACCESS_TOKEN_LIFETIME":          &c.Auth.AccessTokenLifetime,
+		"FRAGRANCES_REFRESH_TOKEN_LIFETIME":         &c.Auth.RefreshTokenLifetime,
+		"FRAGRANCES_ADMIN_TOKEN":                    &c.Auth.AdminToken,
+		"FRAGRANCES_CERT_URL":                       &c.Auth.CertURL,
+		"FRAGRANCES_ID_TOKEN_ISSUER":                &c.Auth.IdTokenIssuer,
+		"FRAGRANCES_PERFUMS_COUNT_REFRESH_INTERVAL": &c.Cache.PerfumsCountRefreshInterval,
+		"FRAGRANCES_PERFUMS_COUNT_REFRESH_PAUSE":    &c.Cache.PerfumsCountRefreshPause,
+		"FRAGRANCES_DEFAULT_PAGE_LIMIT":             &c.Pagination.DefaultLimit,
+		"FRAGRANCES_MAX_PAGE_LIMIT":                 &c.Pagination.MaxLimit,
+		"FRAGRANCES_LOG_LEVEL":                      &c.Log.Level,
+		"FRAGRANCES_LOG_FORMAT":                     &c.Log.Format,
+		"FRAGRANCES_ACCESS_LOG_FORMAT":              &c.Log.AccessLogFormat,
+		"FRAGRANCES_ACCESS_LOG_OUTPUT":              &c.Log.AccessLogOutput,
+		"FRAGRANCES_ACCESS_LOG_SAMPLE_RATE":         &c.Log.AccessLogSampleRate,
+		"FRAGRANCES_TRACING_EXPORTER":               &c.Tracing.Exporter,
+		"FRAGRANCES_TRACING_SAMPLE_RATE":            &c.Tracing.SampleRate,
+		"FRAGRANCES_RATE_LIMIT_ENABLED":             &c.RateLimit.Enabled,
+		"FRAGRANCES_TRUSTED_PROXIES":                &c.RateLimit.TrustedProxies,
+		"FRAGRANCES_DEBUG_ADDR":                     &c.Debug.Addr,
+		"FRAGRANCES_DEBUG_TOKEN":                    &c.Debug.Token,
+	}
+}
+
+// ConfigError lists all problems of a configuration
+type ConfigError []string
+
+func (e ConfigError) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// LoadConfig returns the default configuration overridden by the YAML file
+// path, if not empty, and by the environment. Every invalid value is
+// reported in the returned ConfigError.
+func LoadConfig(path string) (*Config, error) {
+	c := DefaultConfig()
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil {
+			return nil, errors.New("config file " + path + ": " + err.Error())
+		}
+	}
+
+	_ = godotenv.Load(ENV_FILE)
+
+	env := configEnv(c)
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ConfigError
+	for _, name := range names {
+		field := env[name]
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setConfigField(field, value); err != nil {
+			errs = append(errs, "variable "+name+": "+err.Error())
+		}
+	}
+
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return c, nil
+}
+
+func setConfigField(field interface{}, value string) error {
+	var err error
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *int:
+		*f, err = strconv.Atoi(value)
+	case *int64:
+		*f, err = strconv.ParseInt(value, 10, 64)
+	case *float64:
+		*f, err = strconv.ParseFloat(value, 64)
+	case *bool:
+		*f, err = strconv.ParseBool(value)
+	case *time.Duration:
+		*f, err = time.ParseDuration(value)
+	case *[]string: // comma separated
+		*f = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*f = append(*f, item)
+			}
+		}
+	default:
+		err = errors.New("unsupported type")
+	}
+	if err != nil {
+		return errors.New("invalid value " + strconv.Quote(value))
+	}
+	return nil
+}
+
+func (c *Config) validate() []string {
+	var errs []string
+	check := func(ok bool, msg string) {
+		if !ok {
+			errs = append(errs, msg)
+		}
+	}
+
+	check(c.Server.Host != "", "server.host is empty")
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, "server.port must be a port number")
+	}
+	check(c.Server.Protocol == "http" || c.Server.Protocol == "https", "server.protocol must be http or https")
+	check(c.Server.PublicHost != "", "server.public_host is empty")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout is negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout is negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout is negative")
+	check(c.Server.ShutdownGracePeriod > 0, "server.shutdown_grace_period must be positive")
+
+	if u, err := url.Parse(c.Database.URL); err != nil || u.Scheme == "" {
+		errs = append(errs, "database.url must be an url")
+	}
+	check(c.Database.Name != "", "database.name is empty")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns is negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns is negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns is greater than database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime is negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time is negative")
+	check(sslModes[c.Database.SSLMode], "database.ssl_mode must be disable, require, verify-ca or verify-full")
+	check(c.Database.SSLMode != "verify-ca" && c.Database.SSLMode != "verify-full" || c.Database.SSLRootCert != "",
+		"database.ssl_root_cert is needed to verify the server certificate")
+	check((c.Database.SSLCert == "") == (c.Database.SSLKey == ""), "database.ssl_cert and database.ssl_key must be set together")
+	check(c.Database.ConnectTimeout >= 0, "database.connect_timeout is negative")
+	check(c.Database.StartupTimeout > 0, "database.startup_timeout must be positive")
+	check(c.Database.ReadRetries >= 0, "database.read_retries is negative")
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout is negative")
+	routes := make([]string, 0, len(c.Database.QueryTimeouts))
+	for name := range c.Database.QueryTimeouts {
+		routes = append(routes, name)
+	}
+	sort.Strings(routes)
+	for _, name := range routes {
+		check(isRouteName(name), "database.query_timeouts: unknown route "+name)
+		check(c.Database.QueryTimeouts[name] >= 0, "database.query_timeouts: timeout of "+name+" is negative")
+	}
+
+	check(c.Paths.RepoDir != "", "paths.repo_dir is empty")
+	check(c.Paths.DataDir != "", "paths.data_dir is empty")
+
+	check(c.Auth.AccessTokenLifetime > 0, "auth.access_token_lifetime must be positive")
+	check(c.Auth.RefreshTokenLifetime > 0, "auth.refresh_token_lifetime must be positive")
+	if u, err := url.Parse(c.Auth.CertURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, "auth.cert_url must be an url")
+	}
+
+	check(c.Cache.PerfumsCountRefreshInterval > 0, "cache.perfums_count_refresh_interval must be positive")
+	check(c.Cache.PerfumsCountRefreshPause >= 0, "cache.perfums_count_refresh_pause is negative")
+
+	check(c.Pagination.DefaultLimit > 0, "pagination.default_limit must be positive")
+	check(c.Pagination.MaxLimit >= c.Pagination.DefaultLimit, "pagination.max_limit is less than pagination.default_limit")
+
+	if _, err := ParseLogLevel(c.Log.Level); err != nil {
+		errs = append(errs, "log.level: "+err.Error())
+	}
+	check(c.Log.Format == LOG_FORMAT_JSON || c.Log.Format == LOG_FORMAT_LOGFMT, "log.format must be json or logfmt")
+	check(c.Log.AccessLogFormat == LOG_FORMAT_JSON || c.Log.AccessLogFormat == LOG_FORMAT_LOGFMT,
+		"log.access_log_format must be json or logfmt")
+	check(c.Log.AccessLogOutput != "", "log.access_log_output is empty")
+	check(c.Log.AccessLogSampleRate >= 0 && c.Log.AccessLogSampleRate <= 1, "log.access_log_sample_rate must be from 0 to 1")
+
+	switch c.Tracing.Exporter {
+	case TRACING_EXPORTER_NONE, TRACING_EXPORTER_STDOUT, TRACING_EXPORTER_OTLP:
+	default:
+		errs = append(errs, "tracing.exporter must be none, stdout or otlp")
+	}
+	check(c.Tracing.SampleRate >= 0 && c.Tracing.SampleRate <= 1, "tracing.sample_rate must be from 0 to 1")
+
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "rate_limit.trusted_proxies: "+proxy+" is not an ip or CIDR")
+	}
+
+	// the debug server is open to everyone who reaches it without a token
+	if c.Debug.Addr != "" && c.Debug.Token == "" && c.Auth.AdminToken == "" {
+		check(isLoopbackAddr(c.Debug.Addr), "debug.token or auth.admin_token is needed to serve debug.addr "+c.Debug.Addr)
+	}
+
+	return errs
+}
+
+// isLoopbackAddr reports whether the host:port addr only listens on loopback
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// RouteQueryTimeout returns the query timeout of the route named name
+func (c DatabaseConfig) RouteQueryTimeout(name string) time.Duration {
+	if timeout, ok := c.QueryTimeouts[name]; ok {
+		return timeout
+	}
+	return c.QueryTimeout
+}
+
+// BaseUrl returns the url links in responses start with
+func (c *Config) BaseUrl() string {
+	return c.Server.Protocol + "://" + c.Server.PublicHost + API_PATH
+}
+
+// Redacted returns a copy of the configuration with secrets hidden
+func (c *Config) Redacted() *Config {
+	r := *c
+	if r.Auth.AdminToken != "" {
+		r.Auth.AdminToken = REDACTED
+	}
+	if r.Debug.Token != "" {
+		r.Debug.Token = REDACTED
+	}
+	r.Database.URL = redactUrl(r.Database.URL)
+	return &r
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestValidateDebugAddr(t *testing.T) {
+	cases := []struct {
+		Addr, Token, AdminToken string
+		Valid                   bool
+	}{
+		{"", "", "", true},
+		{"127.0.0.1:6060", "", "", true},
+		{"localhost:6060", "", "", true},
+		{"[::1]:6060", "", "", true},
+		{":6060", "", "", false},
+		{"0.0.0.0:6060", "", "", false},
+		{"10.0.0.5:6060", "", "", false},
+		{":6060", "debug-token", "", true},
+		{":6060", "", "admin-token", true},
+	}
+
+	for _, c := range cases {
+		cfg := DefaultConfig()
+		cfg.Debug.Addr, cfg.Debug.Token, cfg.Auth.AdminToken = c.Addr, c.Token, c.AdminToken
+		errs := cfg.validate()
+		if (len(errs) == 0) != c.Valid {
+			t.Errorf("debug addr %q with token %q and admin token %q: errors %v, expected valid %v",
+				c.Addr, c.Token, c.AdminToken, errs, c.Valid)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DB_RETRY_MIN_DELAY = 200 * time.Millisecond
+	DB_RETRY_MAX_DELAY = 10 * time.Second
+)
+
+// sslModes are the sslmode values of lib/pq, verify-ca and verify-full check
+// the server certificate against the CA of ssl_root_cert
+var sslModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// dbDataSource returns the connection url of the database of c
+func dbDataSource(c DatabaseConfig) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + c.Name
+
+	query := u.Query()
+	query.Set("sslmode", c.SSLMode)
+	if c.SSLRootCert != "" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+	if c.SSLCert != "" {
+		query.Set("sslcert", c.SSLCert)
+	}
+	if c.SSLKey != "" {
+		query.Set("sslkey", c.SSLKey)
+	}
+	if c.ConnectTimeout > 0 {
+		query.Set("connect_timeout", strconv.FormatInt(int64(c.ConnectTimeout/time.Second), 10))
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// OpenDb opens the pool of database connections of c and pings the database
+// until it is reachable, ctx is done or c.StartupTimeout is elapsed
+func OpenDb(ctx context.Context, c DatabaseConfig) (*sql.DB, error) {
+	dsn, err := dbDataSource(c)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(SQL_LOG_DRIVER, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, c.StartupTimeout)
+	defer cancel()
+
+	delay := DB_RETRY_MIN_DELAY
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+		appLog.Warn("database is not reachable", F("attempt", attempt), F("retry_in", delay.String()), F("error", err))
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, errors.New("database is not reachable: " + err.Error())
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > DB_RETRY_MAX_DELAY {
+			delay = DB_RETRY_MAX_DELAY
+		}
+	}
+}
+
+// isTransientDbError reports whether err is caused by a lost or refused
+// connection, so the statement may succeed on another one
+func isTransientDbError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// a done context is a net.Error, the query must not be run again
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// connection exceptions and server shutdowns
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryRead runs the read only query name of fn again on transient errors,
+// up to the configured number of retries. holder, if not nil, is a pointer to
+// the slice fn appends rows to and is emptied before each retry.
+func retryRead(ctx context.Context, name string, holder interface{}, fn func() error) error {
+	delay := DB_RETRY_MIN_DELAY
+	for retry := 0; ; retry++ {
+		err := fn()
+		if err == nil || retry >= config.Database.ReadRetries || !isTransientDbError(err) {
+			return err
+		}
+		appLog.Warn("read query is retried", F("query", name), F("retry", retry+1), F("error", err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		resetSlice(holder)
+	}
+}
+
+// resetSlice truncates the slice holder points to
+func resetSlice(holder interface{}) {
+	if holder == nil {
+		return
+	}
+	v := reflect.ValueOf(holder)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		v.Elem().SetLen(0)
+	}
+}
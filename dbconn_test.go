@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// flakyDriver answers every query with a single row of 42 after failing the
+// first failures queries with a reset connection
+type flakyDriver struct {
+	mu       sync.Mutex
+	failures int
+	queries  int
+}
+
+var testFlakyDriver = &flakyDriver{}
+
+func init() {
+	sql.Register("fragrances-flaky", testFlakyDriver)
+}
+
+func (d *flakyDriver) reset(failures int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failures, d.queries = failures, 0
+}
+
+func (d *flakyDriver) Open(name string) (driver.Conn, error) {
+	return flakyConn{d}, nil
+}
+
+type flakyConn struct {
+	d *flakyDriver
+}
+
+func (c flakyConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c flakyConn) Close() error {
+	return nil
+}
+
+func (c flakyConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c flakyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.queries++
+	if c.d.queries <= c.d.failures {
+		return nil, syscall.ECONNRESET
+	}
+	return &flakyRows{}, nil
+}
+
+type flakyRows struct {
+	done bool
+}
+
+func (r *flakyRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *flakyRows) Close() error {
+	return nil
+}
+
+func (r *flakyRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(42)
+	return nil
+}
+
+// withFlakyDb points dbmap to a database of the flaky driver during the test
+func withFlakyDb(t *testing.T) {
+	t.Helper()
+	withDefaultConfig(t)
+	db, err := sql.Open("fragrances-flaky", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := dbmap
+	t.Cleanup(func() {
+		dbmap = prev
+		db.Close()
+	})
+	dbmap = NewDbMap(db)
+	testFlakyDriver.reset(0)
+}
+
+// TestRepositoryReadRetry checks reads of the repositories are run again on
+// transient errors, up to the configured number of retries
+func TestRepositoryReadRetry(t *testing.T) {
+	withFlakyDb(t)
+	ctx := context.Background()
+
+	testFlakyDriver.reset(config.Database.ReadRetries)
+	if id, err := (pgPerfumRepository{}).IdByUuid(ctx, SNAPSHOT_UUID); err != nil || id != 42 {
+		t.Errorf("id is %d: %v", id, err)
+	}
+	if testFlakyDriver.queries != config.Database.ReadRetries+1 {
+		t.Errorf("query is run %d times, expected %d", testFlakyDriver.queries, config.Database.ReadRetries+1)
+	}
+
+	testFlakyDriver.reset(config.Database.ReadRetries + 1)
+	if _, err := (pgReviewRepository{}).Count(ctx, nil); !isTransientDbError(err) {
+		t.Errorf("error is %v, expected a reset connection", err)
+	}
+
+	// a done context stops the retries
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	testFlakyDriver.reset(1)
+	if _, err := (pgFavoriteRepository{}).Count(canceled, nil); err == nil {
+		t.Error("canceled read is retried")
+	}
+}
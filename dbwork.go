@@ -1,21 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
 	"errors"
-	"os"
-	"path/filepath"
+	"fmt"
 	"regexp"
-	"strconv"
 	"sync"
-	"text/template"
+	"sync/atomic"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/go-gorp/gorp/v3"
 	_ "github.com/lib/pq"
-	"gopkg.in/gorp.v1"
 )
 
+// backgroundJobs tracks goroutines to wait for on shutdown
+var backgroundJobs sync.WaitGroup
+
 var (
 	NameFields = map[string]LangField{
 		"ru": LangField{
@@ -63,61 +65,19 @@ var (
 	}
 
 	dbmap           *gorp.DbMap
-	regex           *regexp.Regexp
-	PfumsCountCache = map[string]PfumsCountCacheItem{
-		"brands": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT brands.id AS id, brands.uuid AS uid FROM brands",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE brand_id=$1",
-			count:           make(map[string]int64),
-		},
-		"components": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT components.id AS id, components.uuid AS uid FROM components",
-			getCountDbQuery: "SELECT COUNT(DISTINCT parfum_info_id) FROM parfums WHERE component_id=$1",
-			count:           make(map[string]int64),
-		},
-		"countries": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT countries.id AS id, countries.uuid AS uid FROM countries",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE country_id=$1",
-			count:           make(map[string]int64),
-		},
-		"genders": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT gender.id AS id, gender.uuid AS uid FROM gender",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE gender_id=$1",
-			count:           make(map[string]int64),
-		},
-		"groups": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT groups.id AS id, groups.uuid AS uid FROM groups",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE group_id=$1",
-			count:           make(map[string]int64),
-		},
-		"notes": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT notes.id AS id, notes.uuid AS uid FROM notes",
-			getCountDbQuery: "SELECT COUNT(DISTINCT parfum_info_id) FROM parfums WHERE note_id=$1",
-			count:           make(map[string]int64),
-		},
-		"seasons": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT seasons.id AS id, seasons.uuid AS uid FROM seasons",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE season_id=$1",
-			count:           make(map[string]int64),
-		},
-		"timesOfDay": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT times_of_day.id AS id, times_of_day.uuid AS uid FROM times_of_day",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE tsod_id=$1",
-			count:           make(map[string]int64),
-		},
-		"types": PfumsCountCacheItem{
-			getItemsDbQuery: "SELECT types.id AS id, types.uuid AS uid FROM types",
-			getCountDbQuery: "SELECT COUNT(*) FROM parfum_info WHERE type_id=$1",
-			count:           make(map[string]int64),
-		},
-	}
+	regex           = regexp.MustCompile(`(([\p{L}|\p{Nd}]+\s*[-|&]?\s*)+[\p{L}|\p{Nd}]+[']?([\p{L}|\p{Nd}]+\s*[-|&]?\s*)+[\p{L}|\p{Nd}]*)|([\p{L}|\p{Nd}]{1,2})`)
+	PfumsCountCache = newPfumsCountCache()
 )
 
 type PfumsCountCacheItem struct {
-	getItemsDbQuery string
-	getCountDbQuery string
-	mutex           sync.RWMutex
-	count           map[string]int64
+	// hits, misses and unix time of the last successful refresh, first to be
+	// 64-bit aligned for atomic access
+	hits     uint64
+	misses   uint64
+	cachedAt int64
+	desc     *EntityDesc
+	mutex    sync.RWMutex
+	count    map[string]int64
 }
 
 type LangField struct {
@@ -135,280 +95,6 @@ type LangField struct {
 	PerfumsDescription string
 }
 
-type ConditionParams struct {
-	AuxConditionString   string
-	WhereConditionString string
-	AndConditionString   string
-}
-
-type ConditionSelectTemplateParams struct {
-	ConditionTableField string
-	ConditionTableName  string
-	ConditionUuid       string
-}
-
-type CountTemplateParams struct {
-	DistinctTableField string
-}
-
-type BaseQueryTemplateParams struct {
-	Order         string
-	Offset        string
-	Limit         string
-	FromTableName string
-}
-
-type QueryTemplateParams struct {
-	LangField
-	ConditionParams
-	ConditionSelectTemplateParams
-	CountTemplateParams
-	BaseQueryTemplateParams
-}
-
-type SearchQueryTemplateParams struct {
-	LangField
-	BaseQueryTemplateParams
-	InfoUid      string
-	Name         string
-	YearFrom     string
-	YearTo       string
-	DescUid      string
-	Desc         string
-	BrandUid     string
-	Brand        string
-	GenderUid    string
-	Gender       string
-	GroupUid     string
-	Group        string
-	CountryUid   string
-	Country      string
-	SeasonUid    string
-	Season       string
-	TsodUid      string
-	Tsod         string
-	TypeUid      string
-	Type         string
-	PerfumUid    string
-	NoteUid      string
-	Note         string
-	ComponentUid string
-	Component    string
-}
-
-func NewSearchQueryTemplateParams() *SearchQueryTemplateParams {
-	return &SearchQueryTemplateParams{}
-}
-
-func (sp *SearchQueryTemplateParams) ParseBaseParams(params *BaseParams) error {
-	sp.BaseQueryTemplateParams.Order = "name"
-
-	if params.Lang.Valid {
-		sp.LangField = getNameFields(params.Lang.String)
-	} else {
-		sp.LangField = getNameFields("default")
-	}
-
-	if params.Offset.Valid {
-		sp.BaseQueryTemplateParams.Offset = strconv.FormatInt(params.Offset.Int64, 10)
-	} else {
-		sp.BaseQueryTemplateParams.Offset = strconv.FormatInt(DEFAULT_OFFSET, 10)
-	}
-
-	if params.Limit.Valid {
-		sp.BaseQueryTemplateParams.Limit = strconv.FormatInt(params.Limit.Int64, 10)
-	} else {
-		sp.BaseQueryTemplateParams.Limit = strconv.FormatInt(DEFAULT_LIMIT, 10)
-	}
-
-	return nil
-}
-
-func (sp *SearchQueryTemplateParams) ParseSearchParams(params *SearchParams) error {
-	sp.ParseBaseParams(&params.Base)
-
-	if params.InfoUid.Valid && len(params.InfoUid.String) > 0 {
-		sp.InfoUid = addUidToQuery(params.InfoUid.String, "parfum_info.uuid")
-	}
-
-	if params.Name.Valid && len(params.Name.String) > 0 {
-		sp.Name = addSubstringToQuery(params.Name.String, "parfum_info."+sp.LangField.PerfumInfo, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.YearFrom.Valid && len(params.YearFrom.Int64) > 0 {
-		sp.YearFrom = addIntToQueryConditionGE(params.YearFrom.Int64, "parfum_info.year")
-	}
-
-	if params.YearTo.Valid && len(params.YearTo.Int64) > 0 {
-		sp.YearTo = addIntToQueryConditionLE(params.YearTo.Int64, "parfum_info.year")
-	}
-
-	if params.DescUid.Valid && len(params.DescUid.String) > 0 {
-		sp.DescUid = addUidToQuery(params.DescUid.String, "descriptions.uuid")
-	}
-
-	if params.Desc.Valid && len(params.Desc.String) > 0 {
-		sp.Desc = addSubstringToQuery(params.Desc.String, "descriptions."+sp.LangField.PerfumsDescription, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.BrandUid.Valid && len(params.BrandUid.String) > 0 {
-		sp.BrandUid = addUidToQuery(params.BrandUid.String, "brands.uuid")
-	}
-
-	if params.Brand.Valid && len(params.Brand.String) > 0 {
-		sp.Brand = addSubstringToQuery(params.Brand.String, "brands."+sp.LangField.BrandsName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.GenderUid.Valid && len(params.GenderUid.String) > 0 {
-		sp.GenderUid = addUidToQuery(params.GenderUid.String, "gender.uuid")
-	}
-
-	if params.Gender.Valid && len(params.Gender.String) > 0 {
-		sp.Gender = addSubstringToQuery(params.Gender.String, "gender."+sp.LangField.GenderName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.GroupUid.Valid && len(params.GroupUid.String) > 0 {
-		sp.GroupUid = addUidToQuery(params.GroupUid.String, "groups.uuid")
-	}
-
-	if params.Group.Valid && len(params.Group.String) > 0 {
-		sp.Group = addSubstringToQuery(params.Group.String, "groups."+sp.LangField.GroupsName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.CountryUid.Valid && len(params.CountryUid.String) > 0 {
-		sp.CountryUid = addUidToQuery(params.CountryUid.String, "countries.uuid")
-	}
-
-	if params.Country.Valid && len(params.Country.String) > 0 {
-		sp.Country = addSubstringToQuery(params.Country.String, "countries."+sp.LangField.CountriesName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.SeasonUid.Valid && len(params.SeasonUid.String) > 0 {
-		sp.SeasonUid = addUidToQuery(params.SeasonUid.String, "seasons.uuid")
-	}
-
-	if params.Season.Valid && len(params.Season.String) > 0 {
-		sp.Season = addSubstringToQuery(params.Season.String, "seasons."+sp.LangField.SeasonsName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.TsodUid.Valid && len(params.TsodUid.String) > 0 {
-		sp.TsodUid = addUidToQuery(params.TsodUid.String, "times_of_day.uuid")
-	}
-
-	if params.Tsod.Valid && len(params.Tsod.String) > 0 {
-		sp.Tsod = addSubstringToQuery(params.Tsod.String, "times_of_day."+sp.LangField.TsodName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.TypeUid.Valid && len(params.TypeUid.String) > 0 {
-		sp.TypeUid = addUidToQuery(params.TypeUid.String, "types.uuid")
-	}
-
-	if params.Type.Valid && len(params.Type.String) > 0 {
-		sp.Type = addSubstringToQuery(params.Type.String, "types."+sp.LangField.TypesName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.PerfumUid.Valid && len(params.PerfumUid.String) > 0 {
-		sp.PerfumUid = addUidToQuery(params.PerfumUid.String, "parfums.uuid")
-	}
-
-	if params.NoteUid.Valid && len(params.NoteUid.String) > 0 {
-		sp.NoteUid = addUidToQuery(params.NoteUid.String, "notes.uuid")
-	}
-
-	if params.Note.Valid && len(params.Note.String) > 0 {
-		sp.Note = addSubstringToQuery(params.Note.String, "notes."+sp.LangField.NotesName, params.CompareMode, params.CaseSensitive)
-	}
-
-	if params.ComponentUid.Valid && len(params.ComponentUid.String) > 0 {
-		sp.ComponentUid = addUidToQuery(params.ComponentUid.String, "components.uuid")
-	}
-
-	if params.Component.Valid && len(params.Component.String) > 0 {
-		sp.Component = addSubstringToQuery(params.Component.String, "components."+sp.LangField.ComponentsName, params.CompareMode, params.CaseSensitive)
-	}
-
-	return nil
-}
-
-func addUidToQuery(slice []string, fieldId string) string {
-	ret := ""
-	if num := len(slice); num > 0 {
-		for i, value := range slice {
-			if normalized := regex.FindString(value); normalized != "" {
-				if i > 0 {
-					ret += " OR "
-				}
-				ret += fieldId + "='" + normalized + "'"
-			}
-		}
-	}
-
-	return ret
-}
-
-func addSubstringToQuery(slice []string, fieldId string, cm, cs NullString) string {
-	ret := ""
-	if num := len(slice); num > 0 {
-		for i, value := range slice {
-			if normalized := regex.FindString(value); normalized != "" {
-				if i > 0 {
-					ret += " OR "
-				}
-
-				if !cs.Valid || cs.String != "y" {
-					ret += `LOWER`
-				}
-
-				ret += `(` + fieldId + `) LIKE `
-
-				if !cs.Valid || cs.String != "y" {
-					ret += `LOWER`
-				}
-
-				if cm.String == "st" { //strict
-					ret += `('` + normalized + `')`
-				} else if cm.String == "bw" { //begin with
-					ret += `('` + normalized + `%')`
-				} else if cm.String == "ew" { //end with
-					ret += `('%` + normalized + `')`
-				} else { //at any position in the string
-					ret += `('%` + normalized + `%')`
-				}
-			}
-		}
-	}
-
-	return ret
-}
-
-func addIntToQueryConditionGE(slice []int64, fieldId string) string {
-	ret := ""
-	if num := len(slice); num > 0 {
-		for i, value := range slice {
-			if i > 0 {
-				ret += " OR "
-			}
-			ret += fieldId + ">=" + strconv.FormatInt(value, 10)
-		}
-	}
-
-	return ret
-}
-
-func addIntToQueryConditionLE(slice []int64, fieldId string) string {
-	ret := ""
-	if num := len(slice); num > 0 {
-		for i, value := range slice {
-			if i > 0 {
-				ret += " OR "
-			}
-			ret += fieldId + "<=" + strconv.FormatInt(value, 10)
-		}
-	}
-
-	return ret
-}
-
 // DBObjecter ...
 type DBObjecter interface {
 	GetRecords(params *BaseParams) ([]interface{}, error)
@@ -442,123 +128,169 @@ type ImageDB struct {
 	Note          sql.NullString `db:"note"`
 }
 
-var templateFile = "query_templates.tmpl"
-var tmpl *template.Template
-var whereIsUsed = false
-
-func init() {
-	_ = godotenv.Load("openshift.env")
-	dir := os.Getenv("OPENSHIFT_REPO_DIR")
-	if dir == "" {
-		TraceFatal("Variable OPENSHIFT_REPO_DIR is not defined")
-		os.Exit(-1)
+// InitDb opens the database, applies pending migrations if auto_migrate is
+// set and starts refreshing of the perfums count cache, which runs until ctx
+// is done
+func InitDb(ctx context.Context) (*gorp.DbMap, error) {
+	db, err := OpenDb(ctx, config.Database)
+	if err != nil {
+		return nil, err
+	}
+	if config.Database.AutoMigrate {
+		migrator, err := NewMigrator(db)
+		if err == nil {
+			_, err = migrator.Up(ctx)
+		}
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
+	dbmap = NewDbMap(db)
+	store = NewPgStore()
+	RegisterDbMetrics(db)
 
-	templateFile = filepath.Join(dir, templateFile)
-	tmpl = template.Must(template.New("").Funcs(
-		template.FuncMap{
-			"SetWhereIsUsed": func(newValue bool) bool {
-				whereIsUsed = newValue
-				return whereIsUsed
-			},
-			"GetWhereIsUsed": func() bool {
-				return whereIsUsed
-			},
-		},
-	).ParseFiles(templateFile))
+	backgroundJobs.Add(1)
+	go func() {
+		defer backgroundJobs.Done()
+		refreshPerfumsCount(ctx)
+	}()
 
-	regex = regexp.MustCompile(`(([\p{L}|\p{Nd}]+\s*[-|&]?\s*)+[\p{L}|\p{Nd}]+[']?([\p{L}|\p{Nd}]+\s*[-|&]?\s*)+[\p{L}|\p{Nd}]*)|([\p{L}|\p{Nd}]{1,2})`)
+	return dbmap, nil
 }
 
-// InitDb ...
-func InitDb() *gorp.DbMap {
-	db, err := sql.Open("postgres", os.Getenv("OPENSHIFT_POSTGRESQL_DB_URL")+"/"+os.Getenv("FRAGRANCES_DB_NAME")+"?sslmode=disable")
-	if err != nil {
-		TracePrintError(err)
-		os.Exit(-1)
-	}
-	dbmap = &gorp.DbMap{Db: db, Dialect: gorp.PostgresDialect{}}
+// NewDbMap maps the tables of db
+func NewDbMap(db *sql.DB) *gorp.DbMap {
+	dbmap := &gorp.DbMap{Db: db, Dialect: gorp.PostgresDialect{}}
 	dbmap.AddTableWithName(UserDB{}, "users").SetKeys(false, "UserId")
-	dbmap.AddTableWithName(BrandV1{}, "brands").SetKeys(false, "Id")
 	dbmap.AddTableWithName(ImageDB{}, "images").SetKeys(false, "Id")
 	dbmap.AddTableWithName(PerfumInfoV1{}, "parfum_info").SetKeys(false, "Id")
-	dbmap.AddTableWithName(ComponentV1{}, "components").SetKeys(false, "Id")
-	dbmap.AddTableWithName(CountryV1{}, "countries").SetKeys(false, "Id")
-	dbmap.AddTableWithName(GenderV1{}, "gender").SetKeys(false, "Id")
-	dbmap.AddTableWithName(GroupV1{}, "groups").SetKeys(false, "Id")
-	dbmap.AddTableWithName(SeasonV1{}, "seasons").SetKeys(false, "Id")
-	dbmap.AddTableWithName(TimeOfDayV1{}, "times_of_day").SetKeys(false, "Id")
-	dbmap.AddTableWithName(TypeV1{}, "types").SetKeys(false, "Id")
 	dbmap.AddTableWithName(PerfumCompositionDBRecordV1{}, "parfums").SetKeys(false, "PerfumId")
-	// dbmap.TraceOn("[gorp]", log.New(os.Stdout, "fga:", log.Lmicroseconds))
+	dbmap.AddTableWithName(ReviewDB{}, "reviews").SetKeys(true, "Id")
+	dbmap.AddTableWithName(OfferDB{}, "offers").SetKeys(true, "Id")
+	dbmap.AddTableWithName(FavoriteDB{}, "favorites").SetKeys(true, "Id")
+	return dbmap
+}
 
-	go func() {
-		c := time.Tick(time.Duration(4) * time.Hour)
-		for _, pcci := range PfumsCountCache {
-			CachePerfumsCount(&pcci)
+// refreshPerfumsCount caches perfums count of every table on start and every
+// configured interval until ctx is done. Tables failed on start are retried
+// with backoff, the service is not ready until all of them are cached.
+func refreshPerfumsCount(ctx context.Context) {
+	ticker := time.NewTicker(config.Cache.PerfumsCountRefreshInterval)
+	defer ticker.Stop()
+
+	pending := make([]*PfumsCountCacheItem, 0, len(PfumsCountCache))
+	for _, pcci := range PfumsCountCache {
+		pending = append(pending, pcci)
+	}
+	delay := DB_RETRY_MIN_DELAY
+	for {
+		var failed []*PfumsCountCacheItem
+		for _, pcci := range pending {
+			if err := CachePerfumsCount(ctx, pcci); ctx.Err() != nil {
+				return
+			} else if err != nil {
+				TracePrintError(err)
+				failed = append(failed, pcci)
+			}
+		}
+		if pending = failed; len(pending) == 0 {
+			break
 		}
 
-		for _ = range c {
-			for _, pcci := range PfumsCountCache {
-				CachePerfumsCount(&pcci)
-				time.Sleep(time.Duration(10) * time.Second)
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
 		}
-	}()
+		if delay *= 2; delay > DB_RETRY_MAX_DELAY {
+			delay = DB_RETRY_MAX_DELAY
+		}
+	}
 
-	return dbmap
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, pcci := range PfumsCountCache {
+			if err := CachePerfumsCount(ctx, pcci); ctx.Err() != nil {
+				return
+			} else if err != nil {
+				TracePrintError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(config.Cache.PerfumsCountRefreshPause):
+			}
+		}
+	}
 }
 
 func GetDbMap() *gorp.DbMap {
 	return dbmap
 }
 
+// dbWithContext returns dbmap running its statements with ctx, a statement
+// still running when ctx is done is cancelled on the server
+func dbWithContext(ctx context.Context) gorp.SqlExecutor {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return dbmap.WithContext(ctx)
+}
+
+// beginTx starts a transaction, it is rolled back if ctx is done before it
+// is committed
+func beginTx(ctx context.Context) (*gorp.Transaction, error) {
+	return dbWithContext(ctx).(*gorp.DbMap).Begin()
+}
+
 // GetUserByUserId ...
-func GetUserByUserId(UserId string) (user *UserDB, err error) {
+func GetUserByUserId(ctx context.Context, UserId string) (user *UserDB, err error) {
 	if UserId == "" {
 		TracePrint("user == nil")
 		return nil, errors.New("bad arg")
 	}
-	obj, err := dbmap.Get(UserDB{}, UserId)
+	user, err = store.Users.Get(ctx, UserId)
 	if err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
-	if obj == nil {
-		return nil, nil
-	}
-	user = obj.(*UserDB)
 	return user, nil
 }
 
-// GetUserByAccessToken ...
-func GetUserByAccessToken(tok string) (*UserDB, error) {
+// GetUserByAccessToken returns nil if no user has the token
+func GetUserByAccessToken(ctx context.Context, tok string) (*UserDB, error) {
 	if tok == "" {
 		return nil, errors.New("bad arg")
 	}
-	var user UserDB
-	if err := dbmap.SelectOne(&user, "SELECT * FROM users WHERE access_token=$1", tok); err != nil {
+	user, err := store.Users.GetByAccessToken(ctx, tok)
+	if err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-// GetUserByRefreshToken ...
-func GetUserByRefreshToken(tok string) (*UserDB, error) {
+// GetUserByRefreshToken returns nil if no user has the token
+func GetUserByRefreshToken(ctx context.Context, tok string) (*UserDB, error) {
 	if tok == "" {
 		return nil, errors.New("bad arg")
 	}
-	var user UserDB
-	if err := dbmap.SelectOne(&user, "SELECT * FROM users WHERE refresh_token=$1", tok); err != nil {
+	user, err := store.Users.GetByRefreshToken(ctx, tok)
+	if err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
 // UserInsert ...
-func UserInsert(userId, accessToken, refreshToken string, expiresAt int64) (*UserDB, error) {
+func UserInsert(ctx context.Context, userId, accessToken, refreshToken string, expiresAt int64) (*UserDB, error) {
 	if accessToken == "" || userId == "" {
 		return nil, errors.New("bad arg")
 	}
@@ -572,7 +304,7 @@ func UserInsert(userId, accessToken, refreshToken string, expiresAt int64) (*Use
 		CreatedAt:    now.Unix(),
 		UpdatedAt:    now.Unix(),
 	}
-	if err := dbmap.Insert(newUser); err != nil {
+	if err := store.Users.Insert(ctx, newUser); err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
@@ -580,7 +312,7 @@ func UserInsert(userId, accessToken, refreshToken string, expiresAt int64) (*Use
 }
 
 // Update ...
-func (u *UserDB) Update(accessToken, refreshToken string, expiresAt int64) (bool, error) {
+func (u *UserDB) Update(ctx context.Context, accessToken, refreshToken string, expiresAt int64) (bool, error) {
 	if u.UserId == "" || accessToken == "" || refreshToken == "" {
 		return false, errors.New("bad arg")
 	}
@@ -589,64 +321,25 @@ func (u *UserDB) Update(accessToken, refreshToken string, expiresAt int64) (bool
 	u.RefreshToken = refreshToken
 	u.ExpiresAt = expiresAt
 	u.UpdatedAt = time.Now().Unix()
-	count, err := dbmap.Update(u)
+	updated, err := store.Users.Update(ctx, u)
 	if err != nil {
 		TracePrintError(err)
 		return false, err
-	} else if count == 0 {
-		return false, nil
 	}
-	return true, nil
+	return updated, nil
 }
 
 // Delete ...
-func (u *UserDB) Delete() (bool, error) {
+func (u *UserDB) Delete(ctx context.Context) (bool, error) {
 	if u.UserId == "" {
 		return false, errors.New("bad arg")
 	}
-	count, err := dbmap.Delete(u)
+	deleted, err := store.Users.Delete(ctx, u)
 	if err != nil {
 		TracePrintError(err)
 		return false, err
-	} else if count == 0 {
-		return false, nil
-	}
-	return true, nil
-}
-
-func addIdsToQuery(ids []string, fieldId string) string {
-	ret := ""
-	if idsLen := len(ids); idsLen > 0 {
-		for i, id := range ids {
-			ret += fieldId + "='" + id + "'"
-			if i < (idsLen - 1) {
-				ret += " OR "
-			}
-		}
-	}
-
-	return ret
-}
-
-func addParamsToQuery(base string, params *BaseParams, condition string) string {
-	if condition != "" {
-		base += " WHERE (" + condition + ")"
-	}
-	base += " ORDER BY name ASC"
-
-	if params.Offset.Valid {
-		base += " OFFSET " + strconv.FormatInt(params.Offset.Int64, 10)
-	} else {
-		base += " OFFSET " + strconv.FormatInt(DEFAULT_OFFSET, 10)
-	}
-
-	if params.Limit.Valid {
-		base += " LIMIT " + strconv.FormatInt(params.Limit.Int64, 10)
-	} else {
-		base += " LIMIT " + strconv.FormatInt(DEFAULT_LIMIT, 10)
 	}
-
-	return base
+	return deleted, nil
 }
 
 func getNameFields(lang string) LangField {
@@ -659,82 +352,63 @@ func getNameFields(lang string) LangField {
 	return NameFields["default"]
 }
 
-func setDbQueryBaseParams(params *BaseParams, dbParams *QueryTemplateParams) error {
-	dbParams.Order = "name"
-
-	if params.Lang.Valid {
-		dbParams.LangField = getNameFields(params.Lang.String)
-	} else {
-		dbParams.LangField = getNameFields("default")
-	}
-
-	if params.Offset.Valid {
-		dbParams.Offset = strconv.FormatInt(params.Offset.Int64, 10)
-	} else {
-		dbParams.Offset = strconv.FormatInt(DEFAULT_OFFSET, 10)
-	}
-
-	if params.Limit.Valid {
-		dbParams.Limit = strconv.FormatInt(params.Limit.Int64, 10)
-	} else {
-		dbParams.Limit = strconv.FormatInt(DEFAULT_LIMIT, 10)
+// NewUuid generates random (version 4) uuid
+func NewUuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-
-	return nil
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
-// GetImageById ...
-func GetImageById(id int64) (*ImageDB, error) {
-	image := ImageDB{}
-	if err := dbmap.SelectOne(&image, "SELECT * FROM images WHERE id=$1", id); err != nil {
+// GetImageById returns nil if the image is not found
+func GetImageById(ctx context.Context, id int64) (*ImageDB, error) {
+	image, err := store.Images.GetById(ctx, id)
+	if err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
-	return &image, nil
+	return image, nil
 }
 
-// GetImageByUuid ...
-func GetImageByUuid(uuid string) (*ImageDB, error) {
-	image := ImageDB{}
-	if err := dbmap.SelectOne(&image, "SELECT * FROM images WHERE uuid=$1", uuid); err != nil {
+// GetImageByUuid returns nil if the image is not found
+func GetImageByUuid(ctx context.Context, uuid string) (*ImageDB, error) {
+	image, err := store.Images.GetByUuid(ctx, uuid)
+	if err != nil {
 		TracePrintError(err)
 		return nil, err
 	}
-	return &image, nil
+	return image, nil
 }
 
-func CachePerfumsCount(cacheItem *PfumsCountCacheItem) error {
-	type DbItem struct {
-		Id  string `db:"id"`
-		Uid string `db:"uid"`
-	}
-
-	var items []DbItem
-	if _, err := dbmap.Select(&items, cacheItem.getItemsDbQuery); err != nil {
+// CachePerfumsCount caches perfums count of the items of the table of
+// cacheItem, its queries are cancelled when ctx is done
+func CachePerfumsCount(ctx context.Context, cacheItem *PfumsCountCacheItem) error {
+	counts, err := store.Entities.PerfumsCount(ctx, cacheItem.desc)
+	if err != nil {
 		return err
 	}
 
-	if len(items) > 0 {
-		temp := make(map[string]int64)
-		for _, b := range items {
-			count, err := dbmap.SelectInt(cacheItem.getCountDbQuery, b.Id)
-			if err != nil {
-				return err
-			}
-			temp[b.Uid] = count
-		}
-		if len(temp) > 0 {
-			cacheItem.mutex.Lock()
-			for k, v := range temp {
-				cacheItem.count[k] = v
-			}
-			cacheItem.mutex.Unlock()
+	if len(counts) > 0 {
+		cacheItem.mutex.Lock()
+		for k, v := range counts {
+			cacheItem.count[k] = v
 		}
+		cacheItem.mutex.Unlock()
 	}
+	atomic.StoreInt64(&cacheItem.cachedAt, time.Now().Unix())
 
 	return nil
 }
 
+// Stats returns hits and misses of GetPerfumsCount and unix time of the last
+// successful CachePerfumsCount, zero if it never succeeded
+func (cacheItem *PfumsCountCacheItem) Stats() (hits, misses uint64, cachedAt int64) {
+	return atomic.LoadUint64(&cacheItem.hits), atomic.LoadUint64(&cacheItem.misses), atomic.LoadInt64(&cacheItem.cachedAt)
+}
+
 func GetPerfumsCount(table, uid string) (int64, bool) {
 	cacheItem, found := PfumsCountCache[table]
 	if !found {
@@ -744,6 +418,11 @@ func GetPerfumsCount(table, uid string) (int64, bool) {
 	cacheItem.mutex.RLock()
 	value, found := cacheItem.count[uid]
 	cacheItem.mutex.RUnlock()
+	if found {
+		atomic.AddUint64(&cacheItem.hits, 1)
+	} else {
+		atomic.AddUint64(&cacheItem.misses, 1)
+	}
 
 	return value, found
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeEntityRepository counts perfums of the entities by perfumsCount
+type fakeEntityRepository struct {
+	EntityRepository
+	perfumsCount func(ctx context.Context, e *EntityDesc) (map[string]int64, error)
+}
+
+func (r fakeEntityRepository) PerfumsCount(ctx context.Context, e *EntityDesc) (map[string]int64, error) {
+	return r.perfumsCount(ctx, e)
+}
+
+// withPerfumsCount makes perfumsCount the source of a new perfums count
+// cache during the test
+func withPerfumsCount(t *testing.T, perfumsCount func(ctx context.Context, e *EntityDesc) (map[string]int64, error)) {
+	withDefaultConfig(t)
+	prevStore, prevCache := store, PfumsCountCache
+	t.Cleanup(func() { store, PfumsCountCache = prevStore, prevCache })
+	store = &Store{Entities: fakeEntityRepository{perfumsCount: perfumsCount}}
+	PfumsCountCache = newPfumsCountCache()
+}
+
+// TestRefreshPerfumsCountStops checks the refresher cancels the count of a
+// table and leaves the others when its context is done
+func TestRefreshPerfumsCountStops(t *testing.T) {
+	var calls int32
+	withPerfumsCount(t, func(ctx context.Context, e *EntityDesc) (map[string]int64, error) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refreshPerfumsCount(ctx)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refresher is not stopped")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("perfums of %d tables are counted, expected 1", n)
+	}
+}
+
+// TestRefreshPerfumsCountRetries checks tables failed on start are cached
+// soon after the database is back, not at the next refresh
+func TestRefreshPerfumsCountRetries(t *testing.T) {
+	var failures int32 = 3
+	withPerfumsCount(t, func(ctx context.Context, e *EntityDesc) (map[string]int64, error) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			return nil, syscall.ECONNREFUSED
+		}
+		return map[string]int64{}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	go func() {
+		refreshPerfumsCount(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for checkPerfumsCountCacheReady(ctx) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("perfums count cache is not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
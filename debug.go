@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+
+	"github.com/unrolled/render"
+)
+
+const REDACTED = "*****"
+
+// NewDebugServer returns the server of pprof and runtime endpoints or nil if
+// its address is not configured
+func NewDebugServer() *http.Server {
+	debugAddr := config.Debug.Addr
+	if debugAddr == "" {
+		return nil
+	}
+	// the admin token protects the debug server unless it has its own one
+	debugToken := config.Debug.Token
+	if debugToken == "" {
+		debugToken = config.Auth.AdminToken
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/cache", GetCacheStatsEndpoint)
+	mux.HandleFunc("/debug/config", GetConfigEndpoint)
+
+	return &http.Server{Addr: debugAddr, Handler: validateDebugToken(debugToken, mux)}
+}
+
+// validateDebugToken checks the bearer token of debug endpoints if any is set
+func validateDebugToken(debugToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if debugToken != "" {
+			tok, err := getAccessToken(r)
+			if err != nil || subtle.ConstantTimeCompare([]byte(tok), []byte(debugToken)) != 1 {
+				jsonRender := render.New()
+				jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetCacheStatsEndpoint ...
+func GetCacheStatsEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+
+	type CacheStats struct {
+		Hits     uint64 `json:"hits"`
+		Misses   uint64 `json:"misses"`
+		Items    int    `json:"items,omitempty"`
+		CachedAt int64  `json:"cached_at,omitempty"`
+	}
+
+	stats := make(map[string]CacheStats)
+	hits, misses, items := certCache.Stats()
+	stats["cert"] = CacheStats{Hits: hits, Misses: misses, Items: items}
+	for table, cacheItem := range PfumsCountCache {
+		hits, misses, cachedAt := cacheItem.Stats()
+		stats["perfums_count_"+table] = CacheStats{Hits: hits, Misses: misses, CachedAt: cachedAt}
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, stats)
+}
+
+// GetConfigEndpoint dumps the configuration the service is running with,
+// secrets are redacted
+func GetConfigEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, config.Redacted())
+}
+
+// redactUrl hides the password of the url
+func redactUrl(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), REDACTED)
+			return u.String()
+		}
+	}
+	return value
+}
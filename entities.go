@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+// EntityDesc describes a reference table (brands, notes, seasons, ...) of the
+// catalogue. Routes, handlers, perfums count cache and links are generated from
+// entityRegistry, so a new dimension only needs a new entry there.
+type EntityDesc struct {
+	Name       string // used in route names: "Brand", "TimeOfDay"
+	PluralName string // used in route names: "Brands", "TimesOfDay"
+	Rel        string // prefix of link rels: "Brand" -> "BrandInfo", "BrandPerfums"
+	Path       string // path segment of a single item: "/brand/{brandId}"
+	PluralPath string // path segment of the list: "/brands"
+	IdVar      string // mux variable holding the item uuid
+	Table      string // database table
+	ListKey    string // json key of the list in responses
+	NameField  func(LangField) string
+	HasImage   bool // table has image_id referencing images
+	// ForeignTable is the table referencing Table.id through ForeignKey,
+	// either "parfum_info" or "parfums" (perfum composition).
+	ForeignTable string
+	ForeignKey   string
+	// SearchKey is the query parameter used by /find: <key>_id for uuids and
+	// <key> for names. Empty disables the find route.
+	SearchKey string
+	// InfoUuid returns the entity uuid referenced by a perfum info. Nil for
+	// entities linked through the perfum composition.
+	InfoUuid func(*PerfumInfoV1) string
+}
+
+var (
+	brandEntity = &EntityDesc{
+		Name:         "Brand",
+		PluralName:   "Brands",
+		Rel:          "Brand",
+		Path:         "brand",
+		PluralPath:   "brands",
+		IdVar:        "brandId",
+		Table:        "brands",
+		ListKey:      "brands_list",
+		NameField:    func(lf LangField) string { return lf.BrandsName },
+		HasImage:     true,
+		ForeignTable: "parfum_info",
+		ForeignKey:   "brand_id",
+		SearchKey:    "brand",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.BrandUuid },
+	}
+	componentEntity = &EntityDesc{
+		Name:         "Component",
+		PluralName:   "Components",
+		Rel:          "Component",
+		Path:         "component",
+		PluralPath:   "components",
+		IdVar:        "componentId",
+		Table:        "components",
+		ListKey:      "components",
+		NameField:    func(lf LangField) string { return lf.ComponentsName },
+		HasImage:     true,
+		ForeignTable: "parfums",
+		ForeignKey:   "component_id",
+		SearchKey:    "component",
+	}
+	countryEntity = &EntityDesc{
+		Name:         "Country",
+		PluralName:   "Countries",
+		Rel:          "Country",
+		Path:         "country",
+		PluralPath:   "countries",
+		IdVar:        "countryId",
+		Table:        "countries",
+		ListKey:      "countries_list",
+		NameField:    func(lf LangField) string { return lf.CountriesName },
+		HasImage:     true,
+		ForeignTable: "parfum_info",
+		ForeignKey:   "country_id",
+		SearchKey:    "country",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.CountryUuid },
+	}
+	genderEntity = &EntityDesc{
+		Name:         "Gender",
+		PluralName:   "Genders",
+		Rel:          "Gender",
+		Path:         "gender",
+		PluralPath:   "genders",
+		IdVar:        "genderId",
+		Table:        "gender",
+		ListKey:      "gender_list",
+		NameField:    func(lf LangField) string { return lf.GenderName },
+		HasImage:     true,
+		ForeignTable: "parfum_info",
+		ForeignKey:   "gender_id",
+		SearchKey:    "gender",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.GenderUuid },
+	}
+	groupEntity = &EntityDesc{
+		Name:         "Group",
+		PluralName:   "Groups",
+		Rel:          "Group",
+		Path:         "group",
+		PluralPath:   "groups",
+		IdVar:        "groupId",
+		Table:        "groups",
+		ListKey:      "groups_list",
+		NameField:    func(lf LangField) string { return lf.GroupsName },
+		ForeignTable: "parfum_info",
+		ForeignKey:   "group_id",
+		SearchKey:    "group",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.GroupUuid },
+	}
+	noteEntity = &EntityDesc{
+		Name:         "Note",
+		PluralName:   "Notes",
+		Rel:          "Note",
+		Path:         "note",
+		PluralPath:   "notes",
+		IdVar:        "noteId",
+		Table:        "notes",
+		ListKey:      "notes_list",
+		NameField:    func(lf LangField) string { return lf.NotesName },
+		ForeignTable: "parfums",
+		ForeignKey:   "note_id",
+		SearchKey:    "note",
+	}
+	seasonEntity = &EntityDesc{
+		Name:         "Season",
+		PluralName:   "Seasons",
+		Rel:          "Season",
+		Path:         "season",
+		PluralPath:   "seasons",
+		IdVar:        "seasonId",
+		Table:        "seasons",
+		ListKey:      "seasons_list",
+		NameField:    func(lf LangField) string { return lf.SeasonsName },
+		ForeignTable: "parfum_info",
+		ForeignKey:   "season_id",
+		SearchKey:    "season",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.SeasonUuid },
+	}
+	shopEntity = &EntityDesc{
+		Name:         "Shop",
+		PluralName:   "Shops",
+		Rel:          "Shop",
+		Path:         "shop",
+		PluralPath:   "shops",
+		IdVar:        "shopId",
+		Table:        "shops",
+		ListKey:      "shops_list",
+		NameField:    func(lf LangField) string { return lf.ShopsName },
+		ForeignTable: "parfum_info",
+		ForeignKey:   "shop_id",
+		SearchKey:    "shop",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.ShopUuid.String },
+	}
+	timeOfDayEntity = &EntityDesc{
+		Name:         "TimeOfDay",
+		PluralName:   "TimesOfDay",
+		Rel:          "Timeofday",
+		Path:         "timeofday",
+		PluralPath:   "timesofday",
+		IdVar:        "tsodId",
+		Table:        "times_of_day",
+		ListKey:      "timeofday_list",
+		NameField:    func(lf LangField) string { return lf.TsodName },
+		ForeignTable: "parfum_info",
+		ForeignKey:   "tsod_id",
+		SearchKey:    "tsod",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.TsodUuid },
+	}
+	typeEntity = &EntityDesc{
+		Name:         "Type",
+		PluralName:   "Types",
+		Rel:          "Type",
+		Path:         "type",
+		PluralPath:   "types",
+		IdVar:        "typeId",
+		Table:        "types",
+		ListKey:      "types_list",
+		NameField:    func(lf LangField) string { return lf.TypesName },
+		ForeignTable: "parfum_info",
+		ForeignKey:   "type_id",
+		SearchKey:    "type",
+		InfoUuid:     func(info *PerfumInfoV1) string { return info.TypeUuid },
+	}
+
+	// entityRegistry lists every reference table exposed by the API.
+	// The order defines the order of routes and of links in perfum details.
+	entityRegistry = []*EntityDesc{
+		brandEntity,
+		componentEntity,
+		countryEntity,
+		genderEntity,
+		groupEntity,
+		noteEntity,
+		seasonEntity,
+		shopEntity,
+		timeOfDayEntity,
+		typeEntity,
+	}
+)
+
+// Links returns the info and perfums links of the entity item with uuid.
+func (e *EntityDesc) Links(uuid string) []LinkV1 {
+	return []LinkV1{
+		LinkV1{
+			Href:   baseUrl + "/" + e.Path + "/" + uuid,
+			Rel:    e.Rel + "Info",
+			Method: "GET",
+		},
+		LinkV1{
+			Href:   baseUrl + "/" + e.Path + "/" + uuid + "/perfums",
+			Rel:    e.Rel + "Perfums",
+			Method: "GET",
+		},
+	}
+}
+
+// Routes returns list, find, get and perfums routes of the entity.
+func (e *EntityDesc) Routes() Routes {
+	routes := Routes{
+		Route{
+			"Get" + e.PluralName,
+			"GET",
+			"/" + e.PluralPath,
+			GetEntitiesEndpoint(e),
+		},
+	}
+	if e.SearchKey != "" {
+		routes = append(routes, Route{
+			"Get" + e.PluralName + "Find",
+			"GET",
+			"/" + e.PluralPath + "/find",
+			GetEntitiesFindEndpoint(e),
+		})
+	}
+	routes = append(routes,
+		Route{
+			"Get" + e.Name,
+			"GET",
+			"/" + e.Path + "/{" + e.IdVar + "}",
+			GetEntityEndpoint(e),
+		},
+		Route{
+			"GetPerfumsBy" + e.Name,
+			"GET",
+			"/" + e.Path + "/{" + e.IdVar + "}/perfums",
+			GetEntityPerfumsEndpoint(e),
+		},
+	)
+
+	return routes
+}
+
+func entityRoutes() Routes {
+	routes := Routes{}
+	for _, e := range entityRegistry {
+		routes = append(routes, e.Routes()...)
+	}
+	return routes
+}
+
+func newPfumsCountCache() map[string]*PfumsCountCacheItem {
+	cache := make(map[string]*PfumsCountCacheItem)
+	for _, e := range entityRegistry {
+		cache[e.PluralPath] = &PfumsCountCacheItem{
+			desc:  e,
+			count: make(map[string]int64),
+		}
+	}
+	return cache
+}
+
+// EntityV1 ...
+type EntityV1 struct {
+	Id           int64          `db:"id" json:"-"`
+	Uuid         string         `db:"entity_uuid" json:"id"`
+	Name         string         `db:"name" json:"name"`
+	ImageId      sql.NullString `db:"img_uuid" json:"-"`
+	PerfumsCount int64          `db:"-" json:"perfums_count"`
+	Links        []LinkV1       `db:"-" json:"links"`
+	SmallImgUrl  string         `db:"-" json:"small_img_url"`
+	LargeImgUrl  string         `db:"-" json:"large_img_url"`
+}
+
+func (e *EntityDesc) fillEntityList(list []EntityV1) {
+	for i := 0; i < len(list); i++ {
+		list[i].PerfumsCount, _ = GetPerfumsCount(e.PluralPath, list[i].Uuid)
+		list[i].Links = e.Links(list[i].Uuid)
+
+		if list[i].ImageId.Valid {
+			list[i].SmallImgUrl = baseUrl + "/image/" + list[i].ImageId.String + "/small"
+			list[i].LargeImgUrl = baseUrl + "/image/" + list[i].ImageId.String + "/large"
+		}
+	}
+}
+
+func marshalEntityList(key string, list []EntityV1, total, offset, amount int64) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		key:      list,
+		"total":  total,
+		"offset": offset,
+		"amount": amount,
+	})
+}
+
+// EntitiesV1 ...
+type EntitiesV1 struct {
+	desc    *EntityDesc
+	ObjList []EntityV1
+	Total   int64
+	Offset  int64
+	Amount  int64
+}
+
+func NewEntitiesFactory(desc *EntityDesc, version string) Objecter {
+	switch version {
+	case "v1":
+		return &EntitiesV1{desc: desc, ObjList: make([]EntityV1, 0)}
+	}
+
+	return nil
+}
+
+func (obj *EntitiesV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+
+	filter := EntityFilter{}
+	if params.Base.Ids.Valid {
+		filter.Uuids = params.Base.Ids.String
+	}
+
+	list, err := store.Entities.List(ctx, obj.desc, NewListQuery(&params.Base), filter)
+	if err != nil {
+		return nil, err
+	}
+	obj.ObjList = list
+
+	obj.Total = params.Total
+	obj.Offset = params.Base.Offset.Int64
+	obj.Amount = int64(len(obj.ObjList))
+	obj.desc.fillEntityList(obj.ObjList)
+
+	return obj, nil
+}
+
+func (obj *EntitiesV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	if params == nil || len(uids) == 0 {
+		return nil, errors.New("invalid args")
+	}
+
+	params.Filter.Entity = obj.desc
+	params.Filter.EntityUuid = uids[0]
+
+	pinfos := NewPerfumsInfoFactory(params.Base.Version)
+	return pinfos.MakeObj(ctx, params)
+}
+
+func (obj *EntitiesV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Entities.Count(ctx, obj.desc, EntityFilter{})
+}
+
+func (obj *EntitiesV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Perfums.Count(ctx, PerfumFilter{Entity: obj.desc, EntityUuid: uids[0]})
+}
+
+func (obj *EntitiesV1) MarshalJSON() ([]byte, error) {
+	return marshalEntityList(obj.desc.ListKey, obj.ObjList, obj.Total, obj.Offset, obj.Amount)
+}
+
+func (obj *EntitiesV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
+
+// EntitiesSearchResultV1 ...
+type EntitiesSearchResultV1 struct {
+	desc    *EntityDesc
+	ObjList []EntityV1
+	Total   int64
+	Offset  int64
+	Amount  int64
+}
+
+func NewEntitiesSearchResultFactory(desc *EntityDesc, version string) Objecter {
+	switch version {
+	case "v1":
+		return &EntitiesSearchResultV1{desc: desc, ObjList: make([]EntityV1, 0)}
+	}
+
+	return nil
+}
+
+func (obj *EntitiesSearchResultV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*SearchParams)
+
+	list, err := store.Entities.List(ctx, obj.desc, NewListQuery(&params.Base), EntityFilter{Search: params})
+	if err != nil {
+		return nil, err
+	}
+	obj.ObjList = list
+
+	obj.Total = params.Total
+	obj.Offset = params.Base.Offset.Int64
+	obj.Amount = int64(len(obj.ObjList))
+	obj.desc.fillEntityList(obj.ObjList)
+
+	return obj, nil
+}
+
+func (obj *EntitiesSearchResultV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+func (obj *EntitiesSearchResultV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	params := pParams.(*SearchParams)
+	return store.Entities.Count(ctx, obj.desc, EntityFilter{Search: params})
+}
+
+func (obj *EntitiesSearchResultV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	return 0, nil
+}
+
+func (obj *EntitiesSearchResultV1) MarshalJSON() ([]byte, error) {
+	return marshalEntityList(obj.desc.ListKey, obj.ObjList, obj.Total, obj.Offset, obj.Amount)
+}
+
+func (obj *EntitiesSearchResultV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// fakeIssuer is a local secure token service. It signs id tokens with a
+// generated RSA key and serves the certificate of the key the way Google
+// serves its x509 metadata: a json object of PEM certificates by key id with
+// Cache-Control max-age and Age headers.
+type fakeIssuer struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	cert      string
+	projectId string
+
+	mu sync.Mutex
+	// maxAge and age are sent in the headers of the certificates
+	maxAge, age  int
+	certRequests int
+}
+
+func newFakeIssuer(projectId string) (*fakeIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "securetoken.fragrances.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fakeIssuer{
+		key:       key,
+		kid:       "fake-key-1",
+		cert:      string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		projectId: projectId,
+		maxAge:    3600,
+	}
+	fi.server = httptest.NewServer(http.HandlerFunc(fi.serveCerts))
+	return fi, nil
+}
+
+func (fi *fakeIssuer) serveCerts(w http.ResponseWriter, r *http.Request) {
+	fi.mu.Lock()
+	fi.certRequests++
+	maxAge, age := fi.maxAge, fi.age
+	fi.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge)+", must-revalidate, no-transform")
+	w.Header().Set("Age", strconv.Itoa(age))
+	json.NewEncoder(w).Encode(map[string]string{fi.kid: fi.cert})
+}
+
+// CertURL is the url to configure as auth.cert_url
+func (fi *fakeIssuer) CertURL() string {
+	return fi.server.URL + "/certs"
+}
+
+// Issuer is the iss of the issued tokens, to configure as
+// auth.id_token_issuer
+func (fi *fakeIssuer) Issuer() string {
+	return fi.server.URL + "/" + fi.projectId
+}
+
+// SetCacheHeaders changes the max-age and Age of the next certificate
+// responses
+func (fi *fakeIssuer) SetCacheHeaders(maxAge, age int) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.maxAge, fi.age = maxAge, age
+}
+
+// CertRequests returns the number of certificate requests served
+func (fi *fakeIssuer) CertRequests() int {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return fi.certRequests
+}
+
+// IdToken returns an id token of userId with the claims overridden by
+// claims, signed by the key of kid
+func (fi *fakeIssuer) IdToken(userId string, claims jwt.MapClaims) (string, error) {
+	now := time.Now()
+	tokenClaims := jwt.MapClaims{
+		"iss":     fi.Issuer(),
+		"aud":     fi.projectId,
+		"sub":     userId,
+		"user_id": userId,
+		"iat":     now.Add(-time.Minute).Unix(),
+		"exp":     now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		tokenClaims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, tokenClaims)
+	token.Header["kid"] = fi.kid
+	return token.SignedString(fi.key)
+}
+
+func (fi *fakeIssuer) Close() {
+	fi.server.Close()
+}
+
+// mustIdTokenOfKey returns an id token claiming to be signed by the key of
+// kid, which the issuer doesn't serve
+func (fi *fakeIssuer) mustIdTokenOfKey(t *testing.T, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": fi.Issuer(), "aud": fi.projectId, "sub": "test-user-login", "user_id": "test-user-login",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(fi.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// mustIdToken is IdToken failing the test on error
+func (fi *fakeIssuer) mustIdToken(t *testing.T, userId string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := fi.IdToken(userId, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+// FavoriteDB is a perfum liked by user
+type FavoriteDB struct {
+	Id           int64  `db:"id"`
+	UserId       string `db:"user_id"`
+	PerfumInfoId int64  `db:"parfum_info_id"`
+	CreatedAt    int64  `db:"created_at"`
+}
+
+// parseFavoritesForm returns perfum uuids of the perfum_id form values,
+// comma separated lists are accepted
+func parseFavoritesForm(r *http.Request) ([]string, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	ids := NullSliceString{}
+	for _, value := range r.Form["perfum_id"] {
+		ids.append(value)
+	}
+	for _, id := range ids.String {
+		if !uuidRegex.MatchString(id) {
+			return nil, errors.New("invalid perfum id")
+		}
+	}
+	return ids.String, nil
+}
+
+// FavoritesAdd marks perfums with uuids as liked by user. Already liked and
+// unknown perfums are skipped. Returns number of added perfums.
+func FavoritesAdd(ctx context.Context, userId string, uuids []string) (int64, error) {
+	if userId == "" || len(uuids) == 0 {
+		return 0, errors.New("bad arg")
+	}
+
+	added, err := store.Favorites.Add(ctx, userId, uuids)
+	if err != nil {
+		TracePrintError(err)
+		return 0, err
+	}
+	return added, nil
+}
+
+// FavoritesReplace sets the liked perfums of user to uuids
+func FavoritesReplace(ctx context.Context, userId string, uuids []string) error {
+	if userId == "" {
+		return errors.New("bad arg")
+	}
+
+	if err := store.Favorites.Replace(ctx, userId, uuids); err != nil {
+		TracePrintError(err)
+		return err
+	}
+	return nil
+}
+
+// FavoritesRemove removes perfums with uuids from liked by user, all of them
+// if uuids is empty. Returns number of removed perfums.
+func FavoritesRemove(ctx context.Context, userId string, uuids []string) (int64, error) {
+	if userId == "" {
+		return 0, errors.New("bad arg")
+	}
+
+	removed, err := store.Favorites.Remove(ctx, userId, uuids)
+	if err != nil {
+		TracePrintError(err)
+		return 0, err
+	}
+	return removed, nil
+}
+
+// FavoritesV1 is the paginated list of perfums liked by user, latest first
+type FavoritesV1 struct {
+	PerfumsInfoV1
+}
+
+func NewFavoritesFactory(version string) Objecter {
+	switch version {
+	case "v1":
+		return &FavoritesV1{PerfumsInfoV1{ObjList: make([]PerfumInfoV1, 0)}}
+	}
+
+	return nil
+}
+
+// MakeObj selects perfums liked by user params.Id
+func (obj *FavoritesV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+	if params.Id == "" {
+		return nil, errors.New("invalid args")
+	}
+
+	offset := int64(DEFAULT_OFFSET)
+	if params.Base.Offset.Valid {
+		offset = params.Base.Offset.Int64
+	}
+	limit := config.Pagination.DefaultLimit
+	if params.Base.Limit.Valid {
+		limit = params.Base.Limit.Int64
+	}
+
+	uuids, err := store.Favorites.List(ctx, params.Id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Total = params.Total
+	obj.Offset = offset
+	if len(uuids) == 0 {
+		return obj, nil
+	}
+
+	infoParams := MakeObjParams{Base: params.Base, Total: params.Total}
+	infoParams.Base.Ids = NullSliceString{String: uuids, Valid: true}
+	infoParams.Base.Offset = NullInt64{Int64: 0, Valid: true}
+	infoParams.Base.Limit = NullInt64{Int64: int64(len(uuids)), Valid: true}
+	if _, err := obj.PerfumsInfoV1.MakeObj(ctx, &infoParams); err != nil {
+		return nil, err
+	}
+	infoByUuid := make(map[string]PerfumInfoV1)
+	for _, info := range obj.ObjList {
+		infoByUuid[info.Uuid] = info
+	}
+
+	// perfums are listed in the order of the favorites, latest first
+	obj.ObjList = obj.ObjList[:0]
+	for _, uuid := range uuids {
+		if info, ok := infoByUuid[uuid]; ok {
+			obj.ObjList = append(obj.ObjList, info)
+		}
+	}
+	obj.Amount = int64(len(obj.ObjList))
+	obj.Offset = offset
+
+	return obj, nil
+}
+
+func (obj *FavoritesV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+func (obj *FavoritesV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Favorites.Count(ctx, nil)
+}
+
+// ExtraCount returns number of perfums liked by users with uids
+func (obj *FavoritesV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Favorites.Count(ctx, uids)
+}
+
+func (obj *FavoritesV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
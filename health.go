@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/unrolled/render"
+)
+
+const (
+	HEALTHZ_PATH = "/healthz"
+	READYZ_PATH  = "/readyz"
+
+	READYZ_DB_TIMEOUT = 2 * time.Second
+)
+
+// readinessCheck returns nil if the component is ready
+type readinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+var readinessChecks = []readinessCheck{
+	{"database", checkDbReady},
+	{"oauth", checkOAuthReady},
+	{"perfums_count_cache", checkPerfumsCountCacheReady},
+}
+
+func checkDbReady(ctx context.Context) error {
+	if dbmap == nil {
+		return errors.New("database is not initialized")
+	}
+	ctx, cancel := context.WithTimeout(ctx, READYZ_DB_TIMEOUT)
+	defer cancel()
+	return dbmap.Db.PingContext(ctx)
+}
+
+func checkOAuthReady(ctx context.Context) error {
+	if oAuthCred == nil || oAuthCred.ProjectID == "" {
+		return errors.New("oauth credentials are not loaded")
+	}
+	return nil
+}
+
+func checkPerfumsCountCacheReady(ctx context.Context) error {
+	for table, cacheItem := range PfumsCountCache {
+		if _, _, cachedAt := cacheItem.Stats(); cachedAt == 0 {
+			return errors.New("perfums count of " + table + " is not cached yet")
+		}
+	}
+	return nil
+}
+
+// HealthzEndpoint reports the process is up
+func HealthzEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzEndpoint reports statuses of the components needed to serve requests,
+// 503 if any of them is not ready
+func ReadyzEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+
+	status := http.StatusOK
+	components := make(map[string]string, len(readinessChecks))
+	for _, check := range readinessChecks {
+		if err := check.Check(r.Context()); err != nil {
+			status = http.StatusServiceUnavailable
+			components[check.Name] = err.Error()
+			continue
+		}
+		components[check.Name] = "ok"
+	}
+
+	result := map[string]interface{}{"status": "ok", "components": components}
+	if status != http.StatusOK {
+		result["status"] = "unavailable"
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, status, result)
+}
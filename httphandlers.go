@@ -1,10 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/unrolled/render"
 	"io"
@@ -14,20 +14,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
-	"fmt"
 )
 
-var (
-	resourcesDir = ""
-)
-
-func init() {
-	if resourcesDir = os.Getenv("OPENSHIFT_DATA_DIR"); resourcesDir == "" {
-		TraceFatal("Variable OPENSHIFT_DATA_DIR is not defined")
-		os.Exit(-1)
-	}
-}
-
 // LoginEndpoint ...
 func LoginEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
@@ -45,7 +33,7 @@ func LoginEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idTokenClaims, err := CheckIdToken(idToken[0])
+	idTokenClaims, err := CheckIdToken(r.Context(), idToken[0])
 	if err != nil {
 		TracePrintError(err)
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "unauthorized"})
@@ -55,33 +43,33 @@ func LoginEndpoint(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := NewAccessToken(idTokenClaims.Aud, idTokenClaims.Sub)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	fmt.Println("Access token:", accessToken.tokenString)
+	appLog.Debug("access token is issued", F("user_id", idTokenClaims.Sub))
 
 	refreshToken, err := NewRefreshToken(idTokenClaims.Aud, idTokenClaims.Sub)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	user, err := GetUserByUserId(idTokenClaims.Sub)
+	user, err := GetUserByUserId(r.Context(), idTokenClaims.Sub)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Cache-Control", "no-cache")
 	if user != nil {
 		// update existing user
-		updated, err := user.Update(accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
+		updated, err := user.Update(r.Context(), accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
 		if err != nil {
 			TracePrintError(err)
-			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			renderServerError(w, r, err)
 			return
 		} else if !updated {
 			TracePrint("user not updated")
@@ -90,10 +78,10 @@ func LoginEndpoint(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// create new user
-		createdUser, err := UserInsert(idTokenClaims.Sub, accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
+		createdUser, err := UserInsert(r.Context(), idTokenClaims.Sub, accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
 		if err != nil || createdUser == nil {
 			TracePrint("new user not created")
-			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			renderServerError(w, r, err)
 			return
 		}
 		w.Header().Set("Location", baseUrl+"/users/"+createdUser.UserId)
@@ -156,10 +144,10 @@ func TokenEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := GetUserByRefreshToken(token[0])
+	user, err := GetUserByRefreshToken(r.Context(), token[0])
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		renderServerError(w, r, err)
 		return
 	}
 	if user == nil {
@@ -177,20 +165,20 @@ func TokenEndpoint(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := NewAccessToken(tokenClaims.Audience, tokenClaims.Subject)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 	refreshToken, err := NewRefreshToken(tokenClaims.Audience, tokenClaims.Subject)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 	w.Header().Set("Cache-Control", "no-cache")
-	updated, err := user.Update(accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
+	updated, err := user.Update(r.Context(), accessToken.tokenString, refreshToken.tokenString, accessToken.ExpiresAt)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	} else if !updated {
 		TracePrint("user not updated")
@@ -210,9 +198,9 @@ func LogoutEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
 	userId := vars["userId"]
-	user := context.Get(r, "user").(*UserDB)
+	user := CurrentUser(r)
 	if user == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
 
@@ -221,10 +209,10 @@ func LogoutEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := user.Update(user.AccessToken, user.RefreshToken, time.Now().Unix())
+	updated, err := user.Update(r.Context(), user.AccessToken, user.RefreshToken, time.Now().Unix())
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	} else if !updated {
 		TracePrint("user not updated")
@@ -240,8 +228,8 @@ func LogoutEndpoint(w http.ResponseWriter, r *http.Request) {
 // 	jsonRender := render.New()
 // 	vars := mux.Vars(r)
 // 	userId := vars["userId"]
-// 	token := context.Get(r, "token").(AccessTokenClaims)
-// 	user := context.Get(r, "user").(*UserDB)
+// 	token, _ := CurrentToken(r)
+// 	user := CurrentUser(r)
 // 	if user == nil {
 // 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 // 		return
@@ -260,7 +248,7 @@ func LogoutEndpoint(w http.ResponseWriter, r *http.Request) {
 // 	}
 
 // 	w.Header().Set("Cache-Control", "no-cache")
-// 	updated, err := user.Update(accessToken.tokenString, user.RefreshToken, accessToken.ExpiresAt)
+// 	updated, err := user.Update(r.Context(), accessToken.tokenString, user.RefreshToken, accessToken.ExpiresAt)
 // 	if err != nil {
 // 		log.Println("ERROR RefreshTokenEndpoint: user.Update >>", err)
 // 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
@@ -281,9 +269,9 @@ func GetUserEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
 	userId := vars["userId"]
-	user := context.Get(r, "user").(*UserDB)
+	user := CurrentUser(r)
 	if user == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
 
@@ -307,6 +295,16 @@ func GetUserEndpoint(w http.ResponseWriter, r *http.Request) {
 				Rel:    "RefreshToken",
 				Method: "GET",
 			},
+			LinkV1{
+				Href:   baseUrl + "/user/" + userId + "/favorites",
+				Rel:    "Favorites",
+				Method: "GET",
+			},
+			LinkV1{
+				Href:   baseUrl + "/user/" + userId + "/recommendations",
+				Rel:    "Recommendations",
+				Method: "GET",
+			},
 		},
 	})
 }
@@ -315,11 +313,11 @@ func GetUserEndpoint(w http.ResponseWriter, r *http.Request) {
 func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	user := context.Get(r, "user").(*UserDB)
+	user := CurrentUser(r)
 	userId := vars["userId"]
 
 	if user == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
 
@@ -327,10 +325,10 @@ func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request) {
 		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
 		return
 	}
-	deleted, err := user.Delete()
+	deleted, err := user.Delete(r.Context())
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	} else if !deleted {
 		TracePrint("user is not deleted")
@@ -354,22 +352,200 @@ func DeleteUserEndpoint(w http.ResponseWriter, r *http.Request) {
 
 // GetUserFavoritesEndpoint ...
 func GetUserFavoritesEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	if userId != user.UserId {
+		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
+		return
+	}
+
+	params := NewBaseParams("perfums")
+	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+
+	obj := NewFavoritesFactory(params.Version)
+	if obj == nil {
+		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		return
+	}
+
+	count, err := obj.ExtraCount(r.Context(), []string{userId})
+	if err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
 
+	if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count, Id: userId}); err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
 }
 
-// CreateUserFavoritesEndpoint ...
+// CreateUserFavoritesEndpoint adds perfum_id perfums to the liked by user
 func CreateUserFavoritesEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	if userId != user.UserId {
+		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
+		return
+	}
+
+	uuids, err := parseFavoritesForm(r)
+	if err != nil || len(uuids) == 0 {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
 
+	added, err := FavoritesAdd(r.Context(), userId, uuids)
+	if err != nil {
+		renderServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Location", baseUrl+"/user/"+userId+"/favorites")
+	jsonRender.JSON(w, http.StatusCreated, map[string]interface{}{"status": "created", "added": added})
 }
 
-// UpdateUserFavoritesEndpoint ...
+// UpdateUserFavoritesEndpoint replaces the liked by user perfums with perfum_id perfums
 func UpdateUserFavoritesEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	if userId != user.UserId {
+		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
+		return
+	}
+
+	uuids, err := parseFavoritesForm(r)
+	if err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+
+	if err := FavoritesReplace(r.Context(), userId, uuids); err != nil {
+		renderServerError(w, r, err)
+		return
+	}
 
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-// DeleteUserFavoritesEndpoint ...
+// DeleteUserFavoritesEndpoint removes perfum_id perfums from the liked by user, all if none given
 func DeleteUserFavoritesEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	if userId != user.UserId {
+		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
+		return
+	}
+
+	uuids, err := parseFavoritesForm(r)
+	if err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+
+	removed, err := FavoritesRemove(r.Context(), userId, uuids)
+	if err != nil {
+		renderServerError(w, r, err)
+		return
+	}
 
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "removed": removed})
+}
+
+// GetUserRecommendationsEndpoint ...
+func GetUserRecommendationsEndpoint(w http.ResponseWriter, r *http.Request) {
+	jsonRender := render.New()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+
+	if userId != user.UserId {
+		jsonRender.JSON(w, http.StatusForbidden, map[string]string{"status": "forbidden"})
+		return
+	}
+
+	params := NewRecommendParams()
+	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+	params.UserId = userId
+
+	obj := NewRecommendationsFactory(params.Base.Version)
+	if obj == nil {
+		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		return
+	}
+
+	count, err := obj.Count(r.Context(), params)
+	if err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
+
+	params.Total = count
+	if _, err := obj.MakeObj(r.Context(), params); err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
+		return
+	}
 }
 
 func getFileHash(file string) (string, error) {
@@ -397,10 +573,14 @@ func GetSmallImageEndpoint(w http.ResponseWriter, r *http.Request) {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
-	imageDb, err := GetImageByUuid(uid)
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
+	imageDb, err := GetImageByUuid(r.Context(), uid)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		renderServerError(w, r, err)
 		return
 	} else if imageDb == nil {
 		TracePrint("Image not found")
@@ -414,7 +594,7 @@ func GetSmallImageEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	fp := filepath.Join(
-		resourcesDir,
+		config.Paths.DataDir,
 		imageDb.SmallImgPath.String,
 		imageDb.SmallImgFname.String)
 
@@ -444,10 +624,14 @@ func GetLargeImageEndpoint(w http.ResponseWriter, r *http.Request) {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
-	imageDb, err := GetImageByUuid(uid)
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
+	imageDb, err := GetImageByUuid(r.Context(), uid)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		renderServerError(w, r, err)
 		return
 	} else if imageDb == nil {
 		TracePrint("Image not found")
@@ -461,7 +645,7 @@ func GetLargeImageEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	fp := path.Join(
-		resourcesDir,
+		config.Paths.DataDir,
 		imageDb.LargeImgPath.String,
 		imageDb.LargeImgFname.String)
 	// hash, err := getFileHash(fp)
@@ -481,1378 +665,651 @@ func GetLargeImageEndpoint(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, fp)
 }
 
-// GetBrandsEndpoint ...
-func GetBrandsEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumsEndpoint ...
+func GetPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
-	params := NewBaseParams("brands")
+	params := NewBaseParams("perfums")
 	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
 
-	obj := NewBrandsFactory(params.Version)
+	obj := NewPerfumsInfoFactory(params.Version)
 	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	count, err := obj.Count(&params)
+	count, err := obj.Count(r.Context(), params)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
+	if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count}); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-// GetBrandEndpoint ...
-func GetBrandEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumDetailedInfoEndpoint ...
+func GetPerfumDetailedInfoEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["brandId"]
+	uid, ok := vars["perfumId"]
 	if !ok {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
 
-	params := NewBaseParams("brands")
+	params := NewBaseParams("perfums")
 	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
 
-	obj := NewBrandsFactory(params.Version)
+	obj := NewPerfumsInfoFactory(params.Version)
 	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
+	count, err := obj.ExtraCount(r.Context(), []string{uid})
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
+		return
+	} else if count == 0 {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
 	params.Ids.String = []string{uid}
 	params.Ids.Valid = true
 
-	if _, err := obj.MakeObj(
+	composition, err := obj.MakeExtraObj(
+		r.Context(),
 		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
+			Base:  *params,
+			Total: count,
+			Id:    uid,
 		},
-	); err != nil {
+		[]string{uid},
+	)
+	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	if err := renderJson(w, r, composition, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-// GetBrandPerfums ...
-func GetBrandPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumFindEndpoint ...
+func GetPerfumsFindEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["brandId"]
-	if !ok {
+	params := NewSearchParams()
+	params.Parse(r)
+	if !params.ValidIds() {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
-
-	params := NewBaseParams("brands")
-	params.Parse(r)
-
-	obj := NewBrandsFactory(params.Version)
+	obj := NewPerfumsSearchResultFactory(params.Base.Version)
 	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
+	count, err := obj.Count(r.Context(), params)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
+	params.Total = count
+	if _, err := obj.MakeObj(r.Context(), params); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-func GetComponentsEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumReviewsEndpoint ...
+func GetPerfumReviewsEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
-	params := NewBaseParams("components")
+	vars := mux.Vars(r)
+	uid, ok := vars["perfumId"]
+	if !ok {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
+
+	params := NewBaseParams("reviews")
 	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		return
+	}
 
-	obj := NewComponentsFactory(params.Version)
+	obj := NewReviewsFactory(params.Version)
 	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	count, err := obj.Count(&params)
+	perfumInfoId, err := GetPerfumInfoIdByUuid(r.Context(), uid)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
+		return
+	} else if perfumInfoId == 0 {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+		return
+	}
+
+	count, err := obj.ExtraCount(r.Context(), []string{uid})
+	if err != nil {
+		TracePrintError(err)
+		renderServerError(w, r, err)
 		return
 	}
 
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
+	if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count, Id: uid}); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-func GetComponentEndpoint(w http.ResponseWriter, r *http.Request) {
+// CreatePerfumReviewEndpoint adds the review of the current user, one per perfum
+func CreatePerfumReviewEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["componentId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+	uid := vars["perfumId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
+		return
+	}
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	params := NewBaseParams("components")
-	params.Parse(r)
-
-	obj := NewComponentsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	form := ReviewForm{}
+	if err := form.Parse(r); err != nil || !form.Score.Valid {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
+	perfumInfoId, err := GetPerfumInfoIdByUuid(r.Context(), uid)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
+		return
+	} else if perfumInfoId == 0 {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
+	review, err := ReviewInsert(r.Context(), perfumInfoId, user.UserId, &form)
+	if err == ErrReviewExists {
+		jsonRender.JSON(w, http.StatusConflict, map[string]string{"status": "conflict"})
+		return
+	} else if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Location", baseUrl+"/perfum/"+uid+"/reviews")
+	if err := renderJson(w, r, NewReviewV1(review, uid), http.StatusCreated); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
 	}
 }
 
-func GetComponentPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
+// UpdatePerfumReviewEndpoint changes the review of the current user
+func UpdatePerfumReviewEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["componentId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+	uid := vars["perfumId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
-
-	params := NewBaseParams("components")
-	params.Parse(r)
-
-	obj := NewComponentsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	form := ReviewForm{}
+	if err := form.Parse(r); err != nil {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
 
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
+	review, err := getUserReview(r.Context(), uid, user)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
-	}
-}
-
-// GetCountriesEndpoint ...
-func GetCountriesEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("countries")
-	params.Parse(r)
-
-	obj := NewCountriesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	} else if review == nil {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.Count(&params)
+	updated, err := review.Update(r.Context(), &form)
 	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !updated {
+		// removed since it was read
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := renderJson(w, r, NewReviewV1(review, uid), http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
 	}
 }
 
-// GetCountryEndpoint ...
-func GetCountryEndpoint(w http.ResponseWriter, r *http.Request) {
+// DeletePerfumReviewEndpoint removes the review of the current user
+func DeletePerfumReviewEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["countryId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+	uid := vars["perfumId"]
+	user := CurrentUser(r)
+	if user == nil {
+		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
-
-	params := NewBaseParams("countries")
-	params.Parse(r)
-
-	obj := NewCountriesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
+	review, err := getUserReview(r.Context(), uid, user)
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
+		return
+	} else if review == nil {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	deleted, err := review.Delete(r.Context())
+	if err != nil {
+		renderServerError(w, r, err)
 		return
 	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !deleted {
+		// removed since it was read
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonRender.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// getUserReview returns the review of the perfum uid written by user, nil
+// if there is no such perfum or review
+func getUserReview(ctx context.Context, uid string, user *UserDB) (*ReviewDB, error) {
+	perfumInfoId, err := GetPerfumInfoIdByUuid(ctx, uid)
+	if err != nil || perfumInfoId == 0 {
+		return nil, err
+	}
+	return GetReviewByUser(ctx, perfumInfoId, user.UserId)
 }
 
-// GetCountryPerfumsEndpoint ...
-func GetCountryPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumSimilarEndpoint ...
+func GetPerfumSimilarEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["countryId"]
+	uid, ok := vars["perfumId"]
 	if !ok {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
-
-	params := NewBaseParams("countries")
-	params.Parse(r)
-
-	obj := NewCountriesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	params := NewBaseParams("perfums")
+	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
 
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
+	obj := NewSimilarPerfumsFactory(params.Version)
+	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
+	perfumInfoId, err := GetPerfumInfoIdByUuid(r.Context(), uid)
+	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
-	}
-}
-
-// GetGendersEndpoint ...
-func GetGendersEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("gender")
-	params.Parse(r)
-
-	obj := NewGendersFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	} else if perfumInfoId == 0 {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.Count(&params)
+	count, err := obj.ExtraCount(r.Context(), []string{uid})
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
+	if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count, Id: uid}); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-// GetCountryEndpoint ...
-func GetGenderEndpoint(w http.ResponseWriter, r *http.Request) {
+// GetPerfumOffersEndpoint ...
+func GetPerfumOffersEndpoint(w http.ResponseWriter, r *http.Request) {
 	jsonRender := render.New()
 	vars := mux.Vars(r)
-	uid, ok := vars["genderId"]
+	uid, ok := vars["perfumId"]
 	if !ok {
 		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
-
-	params := NewBaseParams("gender")
-	params.Parse(r)
-
-	obj := NewGendersFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	if !uuidRegex.MatchString(uid) {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	params := NewBaseParams("offers")
+	params.Parse(r)
+	if !params.ValidIds() {
+		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
 		return
 	}
 
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
+	obj := NewOffersFactory(params.Version)
+	if obj == nil {
 		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
 		return
 	}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
+	perfumInfoId, err := GetPerfumInfoIdByUuid(r.Context(), uid)
+	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetGenderPerfumsEndpoint ...
-func GetGenderPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["genderId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+		renderServerError(w, r, err)
 		return
-	}
-
-	params := NewBaseParams("gender")
-	params.Parse(r)
-
-	obj := NewGendersFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+	} else if perfumInfoId == 0 {
+		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
 		return
 	}
 
-	count, err := obj.ExtraCount([]string{uid})
+	count, err := obj.ExtraCount(r.Context(), []string{uid})
 	if err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
+	if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count, Id: uid}); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
+	if err := renderJson(w, r, obj, http.StatusOK); err != nil {
 		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+		renderServerError(w, r, err)
 		return
 	}
 }
 
-// GetGroupsEndpoint ...
-func GetGroupsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("groups")
-	params.Parse(r)
+// GetEntitiesEndpoint returns handler listing items of the registry entity
+func GetEntitiesEndpoint(e *EntityDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonRender := render.New()
+		params := NewBaseParams(e.Table)
+		params.Parse(r)
+		if !params.ValidIds() {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
 
-	obj := NewGroupsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		obj := NewEntitiesFactory(e, params.Version)
+		if obj == nil {
+			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			return
+		}
 
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		count, err := obj.Count(r.Context(), params)
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		if _, err := obj.MakeObj(r.Context(), &MakeObjParams{Base: *params, Total: count}); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
+		if err := renderJson(w, r, obj, http.StatusOK); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 	}
 }
 
-// GetGroupEndpoint ...
-func GetGroupEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["groupId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("groups")
-	params.Parse(r)
+// GetEntityEndpoint returns handler of a single item of the registry entity
+func GetEntityEndpoint(e *EntityDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonRender := render.New()
+		vars := mux.Vars(r)
+		uid, ok := vars[e.IdVar]
+		if !ok {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
+		if !uuidRegex.MatchString(uid) {
+			jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+			return
+		}
 
-	obj := NewGroupsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		params := NewBaseParams(e.Table)
+		params.Parse(r)
+		if !params.ValidIds() {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
 
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		obj := NewEntitiesFactory(e, params.Version)
+		if obj == nil {
+			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			return
+		}
 
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
+		count, err := obj.ExtraCount(r.Context(), []string{uid})
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		params.Ids.String = []string{uid}
+		params.Ids.Valid = true
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetGroupPerfumsEndpoint ...
-func GetGroupPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["groupId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("groups")
-	params.Parse(r)
-
-	obj := NewGroupsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetNotesEndpoint ...
-func GetNotesEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("notes")
-	params.Parse(r)
-
-	obj := NewNotesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetNoteEndpoint ...
-func GetNoteEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["noteId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("notes")
-	params.Parse(r)
-
-	obj := NewNotesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetNotePerfumsEndpoint ...
-func GetNotePerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["noteId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("notes")
-	params.Parse(r)
-
-	obj := NewNotesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetSeasonsEndpoint ...
-func GetSeasonsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("seasons")
-	params.Parse(r)
-
-	obj := NewSeasonsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetSeasonEndpoint ...
-func GetSeasonEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["seasonId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("seasons")
-	params.Parse(r)
-
-	obj := NewSeasonsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetSeasonPerfumsEndpoint ...
-func GetSeasonPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["seasonId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("seasons")
-	params.Parse(r)
-
-	obj := NewSeasonsFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTimesOfDayEndpoint ...
-func GetTimesOfDayEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("tsod")
-	params.Parse(r)
-
-	obj := NewTimesOfDayFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTimeOfDayEndpoint ...
-func GetTimeOfDayEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["tsodId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("tsod")
-	params.Parse(r)
-
-	obj := NewTimesOfDayFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTimeOfDayPerfumsEndpoint ...
-func GetTimeOfDayPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["tsodId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("tsod")
-	params.Parse(r)
-
-	obj := NewTimesOfDayFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTypesEndpoint ...
-func GetTypesEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("types")
-	params.Parse(r)
-
-	obj := NewTypesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTypeEndpoint ...
-func GetTypeEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["typeId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("types")
-	params.Parse(r)
-
-	obj := NewTypesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	if _, err := obj.MakeObj(
-		&MakeObjParams{
-			Base:       *params,
-			Total:      1,
-			PerfumsNum: NullInt64{Valid: true, Int64: count},
-		},
-	); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetTypePerfumsEndpoint ...
-func GetTypePerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["typeId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("types")
-	params.Parse(r)
-
-	obj := NewTypesFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	pinfos, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := pinfos.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetPerfumsEndpoint ...
-func GetPerfumsEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewBaseParams("perfums")
-	params.Parse(r)
-
-	obj := NewPerfumsInfoFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(&params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if _, err := obj.MakeObj(&MakeObjParams{Base: *params, Total: count}); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetPerfumDetailedInfoEndpoint ...
-func GetPerfumDetailedInfoEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	vars := mux.Vars(r)
-	uid, ok := vars["perfumId"]
-	if !ok {
-		jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
-		return
-	}
-
-	params := NewBaseParams("perfums")
-	params.Parse(r)
-
-	obj := NewPerfumsInfoFactory(params.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.ExtraCount([]string{uid})
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	} else if count == 0 {
-		jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
-		return
-	}
-
-	params.Ids.String = []string{uid}
-	params.Ids.Valid = true
-
-	composition, err := obj.MakeExtraObj(
-		&MakeObjParams{
-			Base:  *params,
-			Total: count,
-			Id:    uid,
-		},
-		[]string{uid},
-	)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := composition.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetPerfumFindEndpoint ...
-func GetPerfumsFindEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewSearchParams()
-	params.Parse(r)
-	obj := NewPerfumsSearchResultFactory(params.Base.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Total = count
-	if _, err := obj.MakeObj(params); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
-
-// GetBrandsFindEndpoint ...
-func GetBrandsFindEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewSearchParams()
-	params.Parse(r)
-	obj := NewBrandsSearchResultFactory(params.Base.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Total = count
-	if _, err := obj.MakeObj(params); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		res, err := obj.MakeObj(
+			r.Context(),
+			&MakeObjParams{
+				Base:       *params,
+				Total:      1,
+				PerfumsNum: NullInt64{Valid: true, Int64: count},
+			},
+		)
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
+		if list, ok := res.(*EntitiesV1); ok && list.Amount == 0 {
+			jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+			return
+		}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
+		if err := renderJson(w, r, obj, http.StatusOK); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 	}
 }
 
-// GetComponentsFindEndpoint ...
-func GetComponentsFindEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewSearchParams()
-	params.Parse(r)
-	obj := NewComponentsSearchResultFactory(params.Base.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	count, err := obj.Count(params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-
-	params.Total = count
-	if _, err := obj.MakeObj(params); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+// GetEntityPerfumsEndpoint returns handler listing perfums of an item of the registry entity
+func GetEntityPerfumsEndpoint(e *EntityDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonRender := render.New()
+		vars := mux.Vars(r)
+		uid, ok := vars[e.IdVar]
+		if !ok {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
+		if !uuidRegex.MatchString(uid) {
+			jsonRender.JSON(w, http.StatusNotFound, map[string]string{"status": "not found"})
+			return
+		}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
-}
+		params := NewBaseParams(e.Table)
+		params.Parse(r)
+		if !params.ValidIds() {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
 
-// GetCountriesFindEndpoint ...
-func GetCountriesFindEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewSearchParams()
-	params.Parse(r)
-	obj := NewCountriesSearchResultFactory(params.Base.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		obj := NewEntitiesFactory(e, params.Version)
+		if obj == nil {
+			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			return
+		}
 
-	count, err := obj.Count(params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		count, err := obj.ExtraCount(r.Context(), []string{uid})
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	params.Total = count
-	if _, err := obj.MakeObj(params); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		pinfos, err := obj.MakeExtraObj(
+			r.Context(),
+			&MakeObjParams{
+				Base:  *params,
+				Total: count,
+			},
+			[]string{uid},
+		)
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
+		if err := renderJson(w, r, pinfos, http.StatusOK); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 	}
 }
 
-// GetGroupsFindEndpoint ...
-func GetGroupsFindEndpoint(w http.ResponseWriter, r *http.Request) {
-	jsonRender := render.New()
-	params := NewSearchParams()
-	params.Parse(r)
-	obj := NewGroupsSearchResultFactory(params.Base.Version)
-	if obj == nil {
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+// GetEntitiesFindEndpoint returns handler searching items of the registry entity
+func GetEntitiesFindEndpoint(e *EntityDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonRender := render.New()
+		params := NewSearchParams()
+		params.Parse(r).ParseEntity(r, e.SearchKey)
+		if !params.ValidIds() {
+			jsonRender.JSON(w, http.StatusBadRequest, map[string]string{"status": "bad request"})
+			return
+		}
+		obj := NewEntitiesSearchResultFactory(e, params.Base.Version)
+		if obj == nil {
+			jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
+			return
+		}
 
-	count, err := obj.Count(params)
-	if err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		count, err := obj.Count(r.Context(), params)
+		if err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	params.Total = count
-	if _, err := obj.MakeObj(params); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
-	}
+		params.Total = count
+		if _, err := obj.MakeObj(r.Context(), params); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 
-	if err := obj.Json(w, http.StatusOK); err != nil {
-		TracePrintError(err)
-		jsonRender.JSON(w, http.StatusInternalServerError, map[string]string{"status": "internal server error"})
-		return
+		if err := renderJson(w, r, obj, http.StatusOK); err != nil {
+			TracePrintError(err)
+			renderServerError(w, r, err)
+			return
+		}
 	}
 }
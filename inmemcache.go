@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +17,9 @@ const (
 )
 
 type cache struct {
+	// hits and misses of Get, first to be 64-bit aligned for atomic access
+	hits              uint64
+	misses            uint64
 	defaultExpiration time.Duration
 	items             map[string]Item
 	mu                sync.RWMutex
@@ -42,16 +46,27 @@ func (c *cache) Get(k string) (interface{}, bool) {
 	defer c.mu.RUnlock()
 	item, found := c.items[k]
 	if !found {
+		atomic.AddUint64(&c.misses, 1)
 		return nil, false
 	}
 	if item.Expiration > 0 {
 		if time.Now().Unix() > item.Expiration {
+			atomic.AddUint64(&c.misses, 1)
 			return nil, false
 		}
 	}
+	atomic.AddUint64(&c.hits, 1)
 	return item.Object, true
 }
 
+// Stats returns number of hits and misses of Get and number of items
+func (c *cache) Stats() (hits, misses uint64, items int) {
+	c.mu.RLock()
+	items = len(c.items)
+	c.mu.RUnlock()
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses), items
+}
+
 func (c *cache) Delete(k string) {
 	c.mu.Lock()
 	delete(c.items, k)
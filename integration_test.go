@@ -0,0 +1,286 @@
+//go:build integration
+// +build integration
+
+package main
+
+// Integration tests run the router against a disposable PostgreSQL seeded
+// with the repo fixtures:
+//
+//	go test -tags integration ./...
+//
+// The database is FRAGRANCES_TEST_DB_URL if set, a fresh database is created
+// there and dropped afterwards. Otherwise a throwaway cluster is started with
+// initdb and pg_ctl found in FRAGRANCES_TEST_PG_BIN or PATH. Without either
+// the tests are skipped.
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const TEST_DB_NAME = "fragrances_test"
+
+// testPostgres is a database for the test run
+type testPostgres struct {
+	url     string
+	dataDir string
+	pgCtl   string
+	// dropDb drops the database created in FRAGRANCES_TEST_DB_URL
+	dropDb func()
+}
+
+func (pg *testPostgres) stop() {
+	if pg.dropDb != nil {
+		pg.dropDb()
+	}
+	if pg.pgCtl != "" {
+		exec.Command(pg.pgCtl, "-D", filepath.Join(pg.dataDir, "data"), "-m", "immediate", "stop").Run()
+	}
+	if pg.dataDir != "" {
+		os.RemoveAll(pg.dataDir)
+	}
+}
+
+// startTestPostgres returns nil if there is no postgres to test with
+func startTestPostgres() (*testPostgres, error) {
+	if dbUrl := os.Getenv("FRAGRANCES_TEST_DB_URL"); dbUrl != "" {
+		pg := &testPostgres{url: dbUrl}
+		if err := pg.createDb(); err != nil {
+			return nil, err
+		}
+		return pg, nil
+	}
+
+	lookPath := func(name string) string {
+		if dir := os.Getenv("FRAGRANCES_TEST_PG_BIN"); dir != "" {
+			return filepath.Join(dir, name)
+		}
+		path, _ := exec.LookPath(name)
+		return path
+	}
+	initDb, pgCtl := lookPath("initdb"), lookPath("pg_ctl")
+	if initDb == "" || pgCtl == "" {
+		return nil, nil
+	}
+
+	dir, err := ioutil.TempDir("", "fragrances-pg")
+	if err != nil {
+		return nil, err
+	}
+	pg := &testPostgres{dataDir: dir}
+	out, err := exec.Command(initDb, "-D", filepath.Join(dir, "data"), "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		pg.stop()
+		return nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		pg.stop()
+		return nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -F", port, dir)
+	out, err = exec.Command(pgCtl, "-D", filepath.Join(dir, "data"), "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start").CombinedOutput()
+	if err != nil {
+		pg.stop()
+		return nil, fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+	pg.pgCtl = pgCtl
+	pg.url = fmt.Sprintf("postgres://postgres@127.0.0.1:%d", port)
+
+	if err := pg.createDb(); err != nil {
+		pg.stop()
+		return nil, err
+	}
+	return pg, nil
+}
+
+// createDb creates an empty test database in the cluster of pg.url
+func (pg *testPostgres) createDb() error {
+	c := DefaultConfig().Database
+	c.URL, c.Name = pg.url, "postgres"
+	c.StartupTimeout = 30 * time.Second
+	db, err := OpenDb(context.Background(), c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	db.Exec("DROP DATABASE IF EXISTS " + TEST_DB_NAME)
+	if _, err := db.Exec("CREATE DATABASE " + TEST_DB_NAME); err != nil {
+		return err
+	}
+	pg.dropDb = func() {
+		if dbmap != nil {
+			dbmap.Db.Close()
+		}
+		if db, err := OpenDb(context.Background(), c); err == nil {
+			db.Exec("DROP DATABASE IF EXISTS " + TEST_DB_NAME)
+			db.Close()
+		}
+	}
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	pg, err := startTestPostgres()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "postgres is not started:", err)
+		os.Exit(1)
+	} else if pg == nil {
+		fmt.Fprintln(os.Stderr, "integration tests are skipped: set FRAGRANCES_TEST_DB_URL or put initdb and pg_ctl in PATH")
+		os.Exit(0)
+	}
+
+	code, err := runIntegrationTests(m, pg)
+	pg.stop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func runIntegrationTests(m *testing.M, pg *testPostgres) (int, error) {
+	dataDir, err := ioutil.TempDir("", "fragrances-data")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dataDir)
+
+	config = DefaultConfig()
+	config.Database.URL = pg.url
+	config.Database.Name = TEST_DB_NAME
+	config.Paths.RepoDir = "."
+	config.Paths.DataDir = dataDir
+	baseUrl = "http://fragrances.test" + API_PATH
+	accessLogOutput = ioutil.Discard
+	oAuthCred = &OAuth2Credentials{ClientID: TEST_CLIENT_ID, ProjectID: "fragrances-test"}
+	if testIssuer, err = newFakeIssuer(oAuthCred.ProjectID); err != nil {
+		return 0, err
+	}
+	defer testIssuer.Close()
+	config.Auth.CertURL = testIssuer.CertURL()
+	config.Auth.IdTokenIssuer = testIssuer.Issuer()
+
+	ctx := context.Background()
+	db, err := OpenDb(ctx, config.Database)
+	if err != nil {
+		return 0, err
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return 0, err
+	}
+	dbmap = NewDbMap(db)
+	store = NewPgStore()
+	if _, err := Seed(ctx, filepath.Join(config.Paths.RepoDir, SEED_DIR)); err != nil {
+		return 0, err
+	}
+	if err := addTestImage(ctx, dataDir); err != nil {
+		return 0, err
+	}
+	for _, pcci := range PfumsCountCache {
+		if err := CachePerfumsCount(ctx, pcci); err != nil {
+			return 0, err
+		}
+	}
+
+	testServer = httptest.NewServer(NewRouter())
+	defer testServer.Close()
+
+	return m.Run(), nil
+}
+
+// setupRouteTests does nothing, TestMain runs the server of the route tests
+// with the seeded database
+func setupRouteTests(t *testing.T) {}
+
+// addTestImage stores an image file in dataDir and links it to a perfum
+func addTestImage(ctx context.Context, dataDir string) error {
+	if err := writeTestImageFiles(dataDir); err != nil {
+		return err
+	}
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	imageId, err := saveImage(tx, sql.NullInt64{}, CatalogueImage{Small: "images/small/shalimar.txt", Large: "images/large/shalimar.txt"})
+	if err == nil {
+		_, err = tx.Exec("UPDATE parfum_info SET image_id=$1 WHERE uuid=$2", imageId, TEST_PERFUM_SHALIMAR)
+	}
+	if err == nil {
+		testImageUuid, err = tx.SelectStr("SELECT uuid FROM images WHERE id=$1", imageId)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// TestLookupPublicKey checks certificates are cached for max-age less Age
+// of the certificate response
+// TestQueryCancel checks a statement running past its context is cancelled
+// on the server
+func TestQueryCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := dbWithContext(ctx).SelectInt("SELECT 1 FROM pg_sleep(5)")
+	if err == nil {
+		t.Fatal("query is not cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query is cancelled after %v", elapsed)
+	}
+	if status := queryErrorStatus(ctx, err); status != http.StatusGatewayTimeout {
+		t.Errorf("status of %v is %d, expected %d", err, status, http.StatusGatewayTimeout)
+	}
+}
+
+// TestRouteQueryTimeout checks a request out of the query timeout of its
+// route is answered with 504
+func TestRouteQueryTimeout(t *testing.T) {
+	token, _ := mintTokens(t, TEST_USER_ID)
+	prev := config.Database.QueryTimeouts
+	t.Cleanup(func() { config.Database.QueryTimeouts = prev })
+	config.Database.QueryTimeouts = map[string]time.Duration{"GetPerfumsFind": time.Nanosecond}
+
+	status, body := doRequest(t, routeCase{Method: "GET", Path: "/perfums/find?name=Shalimar", Token: token})
+	if status != http.StatusGatewayTimeout {
+		t.Fatalf("status is %d, expected %d: %s", status, http.StatusGatewayTimeout, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(body, &resp); err != nil || resp["status"] != "gateway timeout" {
+		t.Errorf("body is %s: %v", body, err)
+	}
+}
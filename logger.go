@@ -1,36 +1,100 @@
 package main
 
 import (
-	// "bytes"
-
+	"bytes"
+	"encoding/json"
+	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
-	"text/template"
+	"strconv"
 	"time"
 
-	// "github.com/urfave/negroni"
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+const (
+	LOG_FORMAT_JSON   = "json"
+	LOG_FORMAT_LOGFMT = "logfmt"
+
+	REQUEST_ID_HEADER = "X-Request-Id"
 )
 
-// LoggerEntry is the structure
-// passed to the template.
+var (
+	accessLogFormat               = LOG_FORMAT_LOGFMT
+	accessLogOutput     io.Writer = os.Stdout
+	accessLogSampleRate           = 1.0
+)
+
+// InitAccessLog sets up access logs from the log config
+func InitAccessLog(c LogConfig) error {
+	accessLogFormat = c.AccessLogFormat
+	// share of successful requests to log, failed ones are always logged
+	accessLogSampleRate = c.AccessLogSampleRate
+
+	// stdout, stderr or path of the file to append logs to
+	switch c.AccessLogOutput {
+	case "stdout":
+		accessLogOutput = os.Stdout
+	case "stderr":
+		accessLogOutput = os.Stderr
+	default:
+		file, err := os.OpenFile(c.AccessLogOutput, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		accessLogOutput = file
+	}
+	return nil
+}
+
+// LoggerEntry is the access log record of a request
 type LoggerEntry struct {
-	StartTime string
-	Status    int
-	Duration  string
-	Hostname  string
-	Method    string
-	Path      string
+	Time       string  `json:"time"`
+	RequestId  string  `json:"request_id"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Route      string  `json:"route"`
+	Status     int     `json:"status"`
+	LatencyMs  float64 `json:"latency_ms"`
+	Bytes      int     `json:"bytes"`
+	UserId     string  `json:"user_id"`
+	RemoteAddr string  `json:"remote_addr"`
 }
 
-// LoggerDefaultFormat is the format
-// logged used by the default Logger instance.
-var LoggerDefaultFormat = "{{.StartTime}} | {{.Status}} | %8dms | {{.Method}}\t| {{.Path}}\n"
+// Logfmt encodes the entry as logfmt line
+func (e *LoggerEntry) Logfmt() string {
+	buff := &bytes.Buffer{}
+	pairs := []struct {
+		key   string
+		value string
+	}{
+		{"time", e.Time},
+		{"request_id", e.RequestId},
+		{"method", e.Method},
+		{"path", e.Path},
+		{"route", e.Route},
+		{"status", strconv.Itoa(e.Status)},
+		{"latency_ms", strconv.FormatFloat(e.LatencyMs, 'f', 3, 64)},
+		{"bytes", strconv.Itoa(e.Bytes)},
+		{"user_id", e.UserId},
+		{"remote_addr", e.RemoteAddr},
+	}
+	for i, pair := range pairs {
+		if i > 0 {
+			buff.WriteByte(' ')
+		}
+		writeLogfmtPair(buff, pair.key, pair.value)
+	}
+	return buff.String()
+}
 
 // LoggerDefaultDateFormat is the
 // format used for date by the
 // default Logger instance.
-var LoggerDefaultDateFormat = time.RFC3339
+var LoggerDefaultDateFormat = time.RFC3339Nano
 
 // ALogger interface
 type ALogger interface {
@@ -42,52 +106,97 @@ type ALogger interface {
 type Logger struct {
 	// ALogger implements just enough log.Logger interface to be compatible with other implementations
 	ALogger
-	dateFormat     string
-	template       *template.Template
-	durationFormat string
+	dateFormat string
+	format     string
+	sampleRate float64
+	*RouteNamer
+}
+
+// RouteNamer resolves the name of the route matching a request, so the
+// middlewares running before routing can report it
+type RouteNamer struct {
+	routers []*mux.Router
+}
+
+// AddRouter registers router used to resolve route names of requests
+func (n *RouteNamer) AddRouter(router *mux.Router) {
+	n.routers = append(n.routers, router)
+}
+
+// RouteName returns the name of the matching route, empty if none
+func (n *RouteNamer) RouteName(r *http.Request) string {
+	var match mux.RouteMatch
+	for _, router := range n.routers {
+		if router.Match(r, &match) && match.Route != nil {
+			return match.Route.GetName()
+		}
+	}
+	return ""
 }
 
 // NewLogger returns a new Logger instance
 func NewLogger() *Logger {
-	logger := &Logger{ALogger: log.New(os.Stdout, "[api] ", 0), dateFormat: LoggerDefaultDateFormat}
-	logger.SetFormat(LoggerDefaultFormat)
-	return logger
+	return &Logger{
+		ALogger:    log.New(accessLogOutput, "", 0),
+		dateFormat: LoggerDefaultDateFormat,
+		format:     accessLogFormat,
+		sampleRate: accessLogSampleRate,
+		RouteNamer: &RouteNamer{},
+	}
 }
 
+func (l *Logger) SetDateFormat(format string) {
+	l.dateFormat = format
+}
+
+// SetFormat sets json or logfmt output format
 func (l *Logger) SetFormat(format string) {
-	l.template = template.Must(template.New("negroni_parser").Parse(format))
+	l.format = format
 }
 
-func (l *Logger) SetDateFormat(format string) {
-	l.dateFormat = format
+// SetSampleRate sets the share of successful requests to log
+func (l *Logger) SetSampleRate(rate float64) {
+	l.sampleRate = rate
 }
 
 func (l *Logger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	// start := time.Now()
-
-	next(rw, r)
-
-	// res := rw.(negroni.ResponseWriter)
-	// d := time.Since(start)
-	// log := LoggerEntry{
-	// 	StartTime: start.Format(l.dateFormat),
-	// 	Status:    res.Status(),
-	// 	Duration:  d.String(),
-	// 	Hostname:  r.Host,
-	// 	Method:    r.Method,
-	// 	Path:      r.URL.Path,
-	// }
-
-	// buff := &bytes.Buffer{}
-	// l.template.Execute(buff, log)
-	// l.Printf(buff.String(), d.Nanoseconds()/int64(time.Millisecond))
-	// l.Printf(
-	// 	" %23s | %3d | %8dms | %6s | %s", 
-	// 	log.StartTime,
-	// 	log.Status, 
-	// 	d.Nanoseconds()/int64(time.Millisecond),
-	// 	log.Method,
-	// 	log.Path)
-	// l.Println(log.Status)
-	// l.Printf(buff.String(), d.String())
+	start := time.Now()
+
+	requestId := r.Header.Get(REQUEST_ID_HEADER)
+	if requestId == "" {
+		requestId, _ = NewUuid()
+	}
+	rw.Header().Set(REQUEST_ID_HEADER, requestId)
+	info := &requestInfo{RequestId: requestId}
+
+	next(rw, withRequestInfo(r, info))
+
+	res := rw.(negroni.ResponseWriter)
+	if res.Status() < http.StatusBadRequest && l.sampleRate < 1 && rand.Float64() >= l.sampleRate {
+		return
+	}
+
+	entry := LoggerEntry{
+		Time:       start.Format(l.dateFormat),
+		RequestId:  requestId,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Route:      l.RouteName(r),
+		Status:     res.Status(),
+		LatencyMs:  float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+		Bytes:      res.Size(),
+		RemoteAddr: r.RemoteAddr,
+	}
+	entry.UserId = info.UserId
+
+	if l.format == LOG_FORMAT_JSON {
+		line, err := json.Marshal(&entry)
+		if err != nil {
+			TracePrintError(err)
+			return
+		}
+		l.Println(string(line))
+	} else {
+		l.Println(entry.Logfmt())
+	}
 }
@@ -0,0 +1,1691 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the catalogue, users, reviews, favorites and offers in
+// memory. Its repositories answer the way the PostgreSQL queries do, so the
+// whole API runs without a database, in tests for instance. Ids are unique
+// across tables and 0 stands for NULL references.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	lastId int64
+
+	users       map[string]UserDB
+	images      map[int64]ImageDB
+	entities    map[string][]*memEntityItem // by table, in id order
+	perfums     []*memPerfum
+	composition []memCompositionRecord
+	reviews     []ReviewDB
+	favorites   []FavoriteDB
+	offers      []OfferDB
+}
+
+// memEntityItem is a row of a reference table
+type memEntityItem struct {
+	Id      int64
+	Uuid    string
+	Names   map[string]string // by name column
+	ImageId int64
+}
+
+// memPerfum is a row of parfum_info with its description
+type memPerfum struct {
+	Id              int64
+	Uuid            string
+	Name            string
+	Year            int64
+	DescriptionUuid string
+	Descriptions    map[string]string // by description column
+	ImageId         int64
+	Refs            map[string]int64 // reference ids by foreign key
+}
+
+// memCompositionRecord is a row of parfums, Refs holds note_id and
+// component_id
+type memCompositionRecord struct {
+	Id       int64
+	Uuid     string
+	PerfumId int64
+	Refs     map[string]int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:    make(map[string]UserDB),
+		images:   make(map[int64]ImageDB),
+		entities: make(map[string][]*memEntityItem),
+	}
+}
+
+// Store returns the repositories of m
+func (m *MemoryStore) Store() *Store {
+	return &Store{
+		Users:     memUserRepository{m},
+		Images:    memImageRepository{m},
+		Perfums:   memPerfumRepository{m},
+		Entities:  memEntityRepository{m},
+		Reviews:   memReviewRepository{m},
+		Favorites: memFavoriteRepository{m},
+		Offers:    memOfferRepository{m},
+		Catalogue: memCatalogueRepository{m},
+	}
+}
+
+// The helpers below expect the caller to hold the lock.
+
+func (m *MemoryStore) nextId() int64 {
+	m.lastId++
+	return m.lastId
+}
+
+func (m *MemoryStore) item(e *EntityDesc, id int64) *memEntityItem {
+	for _, item := range m.entities[e.Table] {
+		if item.Id == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) itemByUuid(e *EntityDesc, uuid string) *memEntityItem {
+	for _, item := range m.entities[e.Table] {
+		if item.Uuid == uuid {
+			return item
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) perfumById(id int64) *memPerfum {
+	for _, p := range m.perfums {
+		if p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) perfumByUuid(uuid string) *memPerfum {
+	for _, p := range m.perfums {
+		if p.Uuid == uuid {
+			return p
+		}
+	}
+	return nil
+}
+
+// perfumRef returns the item of e referenced by p, nil if none
+func (m *MemoryStore) perfumRef(p *memPerfum, e *EntityDesc) *memEntityItem {
+	if id := p.Refs[e.ForeignKey]; id != 0 {
+		return m.item(e, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) imageUuid(id int64) sql.NullString {
+	if image, ok := m.images[id]; ok {
+		return image.ImgUuid
+	}
+	return sql.NullString{}
+}
+
+func (m *MemoryStore) perfumComposition(p *memPerfum) []memCompositionRecord {
+	records := []memCompositionRecord{}
+	for _, c := range m.composition {
+		if c.PerfumId == p.Id {
+			records = append(records, c)
+		}
+	}
+	return records
+}
+
+// perfumInfo joins p with its references and review stats the way
+// perfum_info_base does
+func (m *MemoryStore) perfumInfo(p *memPerfum, lf LangField) PerfumInfoV1 {
+	info := PerfumInfoV1{
+		Id:              strconv.FormatInt(p.Id, 10),
+		Uuid:            p.Uuid,
+		Name:            p.Name,
+		Year:            p.Year,
+		DescriptionUuid: p.DescriptionUuid,
+		Description:     p.Descriptions[lf.PerfumsDescription],
+		ImgUuid:         m.imageUuid(p.ImageId),
+	}
+	ref := func(e *EntityDesc) (string, string) {
+		if item := m.perfumRef(p, e); item != nil {
+			return item.Uuid, item.Names[e.NameField(lf)]
+		}
+		return "", ""
+	}
+	info.BrandUuid, info.BrandName = ref(brandEntity)
+	info.GenderUuid, info.GenderName = ref(genderEntity)
+	info.GroupUuid, info.GroupName = ref(groupEntity)
+	info.CountryUuid, info.CountryName = ref(countryEntity)
+	info.SeasonUuid, info.SeasonName = ref(seasonEntity)
+	info.TsodUuid, info.TsodName = ref(timeOfDayEntity)
+	info.TypeUuid, info.TypeName = ref(typeEntity)
+	if shop := m.perfumRef(p, shopEntity); shop != nil {
+		info.ShopUuid = sql.NullString{String: shop.Uuid, Valid: true}
+	}
+
+	var score, longevity, sillage, longevityCount, sillageCount int64
+	for _, review := range m.reviews {
+		if review.PerfumInfoId != p.Id {
+			continue
+		}
+		info.ReviewsCount++
+		score += review.Score
+		if review.Longevity.Valid {
+			longevity += review.Longevity.Int64
+			longevityCount++
+		}
+		if review.Sillage.Valid {
+			sillage += review.Sillage.Int64
+			sillageCount++
+		}
+	}
+	if info.ReviewsCount > 0 {
+		info.ScoreAvg = float64(score) / float64(info.ReviewsCount)
+	}
+	if longevityCount > 0 {
+		info.LongevityAvg = float64(longevity) / float64(longevityCount)
+	}
+	if sillageCount > 0 {
+		info.SillageAvg = float64(sillage) / float64(sillageCount)
+	}
+	return info
+}
+
+// compositionRecord joins c with its note and component, false if one of
+// them is missing
+func (m *MemoryStore) compositionRecord(c memCompositionRecord, p *memPerfum, lf LangField) (PerfumCompositionDBRecordV1, bool) {
+	note, component := m.item(noteEntity, c.Refs[noteEntity.ForeignKey]), m.item(componentEntity, c.Refs[componentEntity.ForeignKey])
+	if note == nil || component == nil {
+		return PerfumCompositionDBRecordV1{}, false
+	}
+	return PerfumCompositionDBRecordV1{
+		PerfumId:       strconv.FormatInt(c.Id, 10),
+		PerfumUuid:     c.Uuid,
+		NoteUuid:       note.Uuid,
+		NoteName:       note.Names[noteEntity.NameField(lf)],
+		ComponentUuid:  component.Uuid,
+		ComponentName:  component.Names[componentEntity.NameField(lf)],
+		PerfumInfoUuid: p.Uuid,
+	}, true
+}
+
+// sortCompositionRecords orders records by perfum, note and component name
+func sortCompositionRecords(records []PerfumCompositionDBRecordV1) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.PerfumInfoUuid != b.PerfumInfoUuid {
+			return a.PerfumInfoUuid < b.PerfumInfoUuid
+		}
+		if a.NoteName != b.NoteName {
+			return a.NoteName < b.NoteName
+		}
+		return a.ComponentName < b.ComponentName
+	})
+}
+
+// memPage returns the bounds of the page of n rows
+func memPage(n int, offset, limit int64) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= int64(n) {
+		return n, n
+	}
+	end := int64(n)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return int(offset), int(end)
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// memUidMatcher matches a field the way uidCondition does, nil if
+// values don't filter
+func memUidMatcher(values NullSliceString) func(string) bool {
+	normalized := memNormalize(values)
+	if len(normalized) == 0 {
+		return nil
+	}
+	return func(field string) bool {
+		return containsString(normalized, field)
+	}
+}
+
+// memSubstringMatcher matches a field the way substringCondition does,
+// nil if values don't filter
+func memSubstringMatcher(values NullSliceString, cm, cs NullString) func(string) bool {
+	normalized := memNormalize(values)
+	if len(normalized) == 0 {
+		return nil
+	}
+	fold := !cs.Valid || cs.String != "y"
+	if fold {
+		for i := range normalized {
+			normalized[i] = strings.ToLower(normalized[i])
+		}
+	}
+	return func(field string) bool {
+		if fold {
+			field = strings.ToLower(field)
+		}
+		for _, value := range normalized {
+			var ok bool
+			switch cm.String {
+			case "st":
+				ok = field == value
+			case "bw":
+				ok = strings.HasPrefix(field, value)
+			case "ew":
+				ok = strings.HasSuffix(field, value)
+			default:
+				ok = strings.Contains(field, value)
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func memNormalize(values NullSliceString) []string {
+	if !values.Valid {
+		return nil
+	}
+	normalized := []string{}
+	for _, value := range values.String {
+		if n := regex.FindString(value); n != "" {
+			normalized = append(normalized, n)
+		}
+	}
+	return normalized
+}
+
+type memUserRepository struct{ *MemoryStore }
+
+func (r memUserRepository) Get(ctx context.Context, userId string) (*UserDB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if user, ok := r.users[userId]; ok {
+		return &user, nil
+	}
+	return nil, nil
+}
+
+func (r memUserRepository) getBy(match func(*UserDB) bool) (*UserDB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if match(&user) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r memUserRepository) GetByAccessToken(ctx context.Context, token string) (*UserDB, error) {
+	return r.getBy(func(user *UserDB) bool { return user.AccessToken == token })
+}
+
+func (r memUserRepository) GetByRefreshToken(ctx context.Context, token string) (*UserDB, error) {
+	return r.getBy(func(user *UserDB) bool { return user.RefreshToken == token })
+}
+
+func (r memUserRepository) Insert(ctx context.Context, user *UserDB) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.UserId]; ok {
+		return fmt.Errorf("user %q already exists", user.UserId)
+	}
+	r.users[user.UserId] = *user
+	return nil
+}
+
+func (r memUserRepository) Update(ctx context.Context, user *UserDB) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.UserId]; !ok {
+		return false, nil
+	}
+	r.users[user.UserId] = *user
+	return true, nil
+}
+
+// Delete removes reviews and favorites of user as well
+func (r memUserRepository) Delete(ctx context.Context, user *UserDB) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.UserId]; !ok {
+		return false, nil
+	}
+	delete(r.users, user.UserId)
+
+	reviews := r.reviews[:0]
+	for _, review := range r.reviews {
+		if review.UserId != user.UserId {
+			reviews = append(reviews, review)
+		}
+	}
+	r.reviews = reviews
+	favorites := r.favorites[:0]
+	for _, favorite := range r.favorites {
+		if favorite.UserId != user.UserId {
+			favorites = append(favorites, favorite)
+		}
+	}
+	r.favorites = favorites
+	return true, nil
+}
+
+type memImageRepository struct{ *MemoryStore }
+
+func (r memImageRepository) GetById(ctx context.Context, id int64) (*ImageDB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if image, ok := r.images[id]; ok {
+		return &image, nil
+	}
+	return nil, nil
+}
+
+func (r memImageRepository) GetByUuid(ctx context.Context, uuid string) (*ImageDB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, image := range r.images {
+		if image.ImgUuid.Valid && image.ImgUuid.String == uuid {
+			return &image, nil
+		}
+	}
+	return nil, nil
+}
+
+type memPerfumRepository struct{ *MemoryStore }
+
+func (r memPerfumRepository) IdByUuid(ctx context.Context, uuid string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p := r.perfumByUuid(uuid); p != nil {
+		return p.Id, nil
+	}
+	return 0, nil
+}
+
+// filter returns the perfums selected by filter in id order
+func (r memPerfumRepository) filter(filter PerfumFilter) []*memPerfum {
+	var item *memEntityItem
+	if filter.Entity != nil {
+		if item = r.itemByUuid(filter.Entity, filter.EntityUuid); item == nil {
+			return nil
+		}
+	}
+
+	perfums := []*memPerfum{}
+	for _, p := range r.perfums {
+		if len(filter.Uuids) > 0 && !containsString(filter.Uuids, p.Uuid) {
+			continue
+		}
+		if e := filter.Entity; e != nil {
+			found := false
+			if e.ForeignTable == "parfums" {
+				for _, c := range r.composition {
+					if c.PerfumId == p.Id && c.Refs[e.ForeignKey] == item.Id {
+						found = true
+						break
+					}
+				}
+			} else {
+				found = p.Refs[e.ForeignKey] == item.Id
+			}
+			if !found {
+				continue
+			}
+		}
+		perfums = append(perfums, p)
+	}
+	return perfums
+}
+
+func (r memPerfumRepository) Count(ctx context.Context, filter PerfumFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r memPerfumRepository) List(ctx context.Context, q ListQuery, filter PerfumFilter) ([]PerfumInfoV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []PerfumInfoV1{}
+	for _, p := range r.filter(filter) {
+		list = append(list, r.perfumInfo(p, q.Lang))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Uuid < list[j].Uuid
+	})
+	from, to := memPage(len(list), q.Offset, q.Limit)
+	return list[from:to], nil
+}
+
+func (r memPerfumRepository) CountComposition(ctx context.Context, uuids []string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, p := range r.filter(PerfumFilter{Uuids: uuids}) {
+		count += int64(len(r.perfumComposition(p)))
+	}
+	return count, nil
+}
+
+func (r memPerfumRepository) Composition(ctx context.Context, lf LangField, uuids []string) ([]PerfumCompositionDBRecordV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := []PerfumCompositionDBRecordV1{}
+	for _, p := range r.filter(PerfumFilter{Uuids: uuids}) {
+		for _, c := range r.perfumComposition(p) {
+			if record, ok := r.compositionRecord(c, p, lf); ok {
+				records = append(records, record)
+			}
+		}
+	}
+	sortCompositionRecords(records)
+	return records, nil
+}
+
+// search returns uuids of the perfums found by params in uuid order. As
+// perfum_search does, conditions on notes, components and composition uuids
+// must hold for the same composition record.
+func (r memPerfumRepository) search(params *SearchParams) []string {
+	lf := getNameFields(params.Base.Lang.String)
+	cm, cs := params.CompareMode, params.CaseSensitive
+
+	var perfumConditions []func(*memPerfum) bool
+	addPerfum := func(match func(string) bool, field func(*memPerfum) (string, bool)) {
+		if match != nil {
+			perfumConditions = append(perfumConditions, func(p *memPerfum) bool {
+				value, ok := field(p)
+				return ok && match(value)
+			})
+		}
+	}
+	addPerfum(memUidMatcher(params.InfoUid), func(p *memPerfum) (string, bool) { return p.Uuid, true })
+	addPerfum(memSubstringMatcher(params.Name, cm, cs), func(p *memPerfum) (string, bool) { return p.Name, true })
+	addPerfum(memUidMatcher(params.DescUid), func(p *memPerfum) (string, bool) { return p.DescriptionUuid, p.DescriptionUuid != "" })
+	addPerfum(memSubstringMatcher(params.Desc, cm, cs), func(p *memPerfum) (string, bool) {
+		return p.Descriptions[lf.PerfumsDescription], p.DescriptionUuid != ""
+	})
+	for _, ref := range []struct {
+		Entity      *EntityDesc
+		Uids, Names NullSliceString
+	}{
+		{brandEntity, params.BrandUid, params.Brand},
+		{genderEntity, params.GenderUid, params.Gender},
+		{groupEntity, params.GroupUid, params.Group},
+		{countryEntity, params.CountryUid, params.Country},
+		{seasonEntity, params.SeasonUid, params.Season},
+		{timeOfDayEntity, params.TsodUid, params.Tsod},
+		{typeEntity, params.TypeUid, params.Type},
+	} {
+		e := ref.Entity
+		addPerfum(memUidMatcher(ref.Uids), func(p *memPerfum) (string, bool) {
+			if item := r.perfumRef(p, e); item != nil {
+				return item.Uuid, true
+			}
+			return "", false
+		})
+		addPerfum(memSubstringMatcher(ref.Names, cm, cs), func(p *memPerfum) (string, bool) {
+			if item := r.perfumRef(p, e); item != nil {
+				return item.Names[e.NameField(lf)], true
+			}
+			return "", false
+		})
+	}
+	years := func(values NullSliceInt64, match func(year, value int64) bool) {
+		if values.Valid && len(values.Int64) > 0 {
+			perfumConditions = append(perfumConditions, func(p *memPerfum) bool {
+				for _, value := range values.Int64 {
+					if match(p.Year, value) {
+						return true
+					}
+				}
+				return false
+			})
+		}
+	}
+	years(params.YearFrom, func(year, value int64) bool { return year >= value })
+	years(params.YearTo, func(year, value int64) bool { return year <= value })
+
+	var recordConditions []func(memCompositionRecord) bool
+	addRecord := func(match func(string) bool, field func(memCompositionRecord) string) {
+		if match != nil {
+			recordConditions = append(recordConditions, func(c memCompositionRecord) bool {
+				return match(field(c))
+			})
+		}
+	}
+	addRecord(memUidMatcher(params.PerfumUid), func(c memCompositionRecord) string { return c.Uuid })
+	for _, ref := range []struct {
+		Entity      *EntityDesc
+		Uids, Names NullSliceString
+	}{
+		{noteEntity, params.NoteUid, params.Note},
+		{componentEntity, params.ComponentUid, params.Component},
+	} {
+		e := ref.Entity
+		addRecord(memUidMatcher(ref.Uids), func(c memCompositionRecord) string {
+			if item := r.item(e, c.Refs[e.ForeignKey]); item != nil {
+				return item.Uuid
+			}
+			return ""
+		})
+		addRecord(memSubstringMatcher(ref.Names, cm, cs), func(c memCompositionRecord) string {
+			if item := r.item(e, c.Refs[e.ForeignKey]); item != nil {
+				return item.Names[e.NameField(lf)]
+			}
+			return ""
+		})
+	}
+
+	uuids := []string{}
+perfums:
+	for _, p := range r.perfums {
+		for _, condition := range perfumConditions {
+			if !condition(p) {
+				continue perfums
+			}
+		}
+	records:
+		for _, c := range r.perfumComposition(p) {
+			for _, condition := range recordConditions {
+				if !condition(c) {
+					continue records
+				}
+			}
+			uuids = append(uuids, p.Uuid)
+			break
+		}
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+func (r memPerfumRepository) Search(ctx context.Context, params *SearchParams) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	q := NewListQuery(&params.Base)
+	uuids := r.search(params)
+	from, to := memPage(len(uuids), q.Offset, q.Limit)
+	return uuids[from:to], nil
+}
+
+func (r memPerfumRepository) SearchCount(ctx context.Context, params *SearchParams) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.search(params))), nil
+}
+
+// memNoteComponent is a note and component pair of a composition
+type memNoteComponent struct {
+	Note, Component int64
+}
+
+func (c memCompositionRecord) noteComponent() memNoteComponent {
+	return memNoteComponent{c.Refs[noteEntity.ForeignKey], c.Refs[componentEntity.ForeignKey]}
+}
+
+// similar returns the scores of perfums similar to the perfum uuid, best
+// first
+func (r memPerfumRepository) similar(uuid string) []similarityScoreRecord {
+	scores := []similarityScoreRecord{}
+	target := r.perfumByUuid(uuid)
+	if target == nil {
+		return scores
+	}
+	targetPairs := make(map[memNoteComponent]bool)
+	targetComponents := make(map[int64]bool)
+	for _, c := range r.perfumComposition(target) {
+		targetPairs[c.noteComponent()] = true
+		targetComponents[c.noteComponent().Component] = true
+	}
+	same := func(p *memPerfum, e *EntityDesc) int64 {
+		if id := p.Refs[e.ForeignKey]; id != 0 && id == target.Refs[e.ForeignKey] {
+			return 1
+		}
+		return 0
+	}
+
+	for _, p := range r.perfums {
+		if p.Id == target.Id {
+			continue
+		}
+		pairs := make(map[memNoteComponent]bool)
+		components := make(map[int64]bool)
+		for _, c := range r.perfumComposition(p) {
+			pair := c.noteComponent()
+			if !targetComponents[pair.Component] {
+				continue
+			}
+			components[pair.Component] = true
+			if targetPairs[pair] {
+				pairs[pair] = true
+			}
+		}
+		if len(components) == 0 {
+			continue
+		}
+		record := similarityScoreRecord{
+			Uuid:           p.Uuid,
+			NoteComponents: int64(len(pairs)),
+			Components:     int64(len(components)),
+			SameGroup:      same(p, groupEntity),
+			SameType:       same(p, typeEntity),
+			SameSeason:     same(p, seasonEntity),
+		}
+		record.Score = similarityScore(&record)
+		scores = append(scores, record)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Uuid < scores[j].Uuid
+	})
+	return scores
+}
+
+func (r memPerfumRepository) Similar(ctx context.Context, uuid string, offset, limit int64) ([]similarityScoreRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := r.similar(uuid)
+	from, to := memPage(len(scores), offset, limit)
+	return scores[from:to], nil
+}
+
+func (r memPerfumRepository) SimilarCount(ctx context.Context, uuid string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.similar(uuid))), nil
+}
+
+func (r memPerfumRepository) SharedComposition(ctx context.Context, lf LangField, uuid string, uuids []string) ([]PerfumCompositionDBRecordV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := []PerfumCompositionDBRecordV1{}
+	target := r.perfumByUuid(uuid)
+	if target == nil {
+		return records, nil
+	}
+	targetPairs := make(map[memNoteComponent]bool)
+	for _, c := range r.perfumComposition(target) {
+		targetPairs[c.noteComponent()] = true
+	}
+
+	for _, p := range r.filter(PerfumFilter{Uuids: uuids}) {
+		for _, c := range r.perfumComposition(p) {
+			if !targetPairs[c.noteComponent()] {
+				continue
+			}
+			if record, ok := r.compositionRecord(c, p, lf); ok {
+				records = append(records, record)
+			}
+		}
+	}
+	sortCompositionRecords(records)
+	return records, nil
+}
+
+// recommend scores the perfums recommended by params the way recommendQuery
+// does, best first
+func (r memPerfumRepository) recommend(params *RecommendParams) []recommendScoreRecord {
+	liked := make(map[int64]bool)
+	for _, favorite := range r.favorites {
+		if favorite.UserId == params.UserId {
+			liked[favorite.PerfumInfoId] = true
+		}
+	}
+	reviewed := make(map[int64]bool)
+	for _, review := range r.reviews {
+		if review.UserId == params.UserId {
+			reviewed[review.PerfumInfoId] = true
+		}
+	}
+
+	componentLikes := make(map[int64]int64)
+	noteLikes := make(map[memNoteComponent]int64)
+	for _, p := range r.perfums {
+		if !liked[p.Id] {
+			continue
+		}
+		components := make(map[int64]bool)
+		pairs := make(map[memNoteComponent]bool)
+		for _, c := range r.perfumComposition(p) {
+			components[c.noteComponent().Component] = true
+			pairs[c.noteComponent()] = true
+		}
+		for component := range components {
+			componentLikes[component]++
+		}
+		for pair := range pairs {
+			noteLikes[pair]++
+		}
+	}
+
+	ids := func(e *EntityDesc, uuids []string) map[int64]bool {
+		set := make(map[int64]bool)
+		for _, item := range r.entities[e.Table] {
+			if containsString(uuids, item.Uuid) {
+				set[item.Id] = true
+			}
+		}
+		return set
+	}
+	var filters []func(*memPerfum) bool
+	if params.GenderUid.Valid {
+		genders := ids(genderEntity, params.GenderUid.String)
+		filters = append(filters, func(p *memPerfum) bool { return genders[p.Refs[genderEntity.ForeignKey]] })
+	}
+	if params.SeasonUid.Valid {
+		seasons := ids(seasonEntity, params.SeasonUid.String)
+		filters = append(filters, func(p *memPerfum) bool { return seasons[p.Refs[seasonEntity.ForeignKey]] })
+	}
+	if params.ExcludeBrandUid.Valid {
+		brands := ids(brandEntity, params.ExcludeBrandUid.String)
+		filters = append(filters, func(p *memPerfum) bool {
+			// NULL NOT IN a non empty list is not true
+			brand := p.Refs[brandEntity.ForeignKey]
+			return len(brands) == 0 || (brand != 0 && !brands[brand])
+		})
+	}
+
+	scores := []recommendScoreRecord{}
+candidates:
+	for _, p := range r.perfums {
+		if liked[p.Id] || reviewed[p.Id] {
+			continue
+		}
+		for _, filter := range filters {
+			if !filter(p) {
+				continue candidates
+			}
+		}
+		record := recommendScoreRecord{Uuid: p.Uuid}
+		matched := make(map[int64]bool)
+		for _, c := range r.perfumComposition(p) {
+			pair := c.noteComponent()
+			likes, ok := componentLikes[pair.Component]
+			if !ok {
+				continue
+			}
+			record.Score += RECOMMEND_NOTE_COMPONENT_WEIGHT*noteLikes[pair] + RECOMMEND_COMPONENT_WEIGHT*likes
+			matched[pair.Component] = true
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		record.MatchedComponents = int64(len(matched))
+		scores = append(scores, record)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Uuid < scores[j].Uuid
+	})
+	return scores
+}
+
+func (r memPerfumRepository) Recommend(ctx context.Context, params *RecommendParams, offset, limit int64) ([]recommendScoreRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := r.recommend(params)
+	from, to := memPage(len(scores), offset, limit)
+	return scores[from:to], nil
+}
+
+func (r memPerfumRepository) RecommendCount(ctx context.Context, params *RecommendParams) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.recommend(params))), nil
+}
+
+type memEntityRepository struct{ *MemoryStore }
+
+// filter returns the items of e selected by filter in id order
+func (r memEntityRepository) filter(e *EntityDesc, lf LangField, filter EntityFilter) []*memEntityItem {
+	var uidMatch, nameMatch func(string) bool
+	if params := filter.Search; params != nil {
+		if params.EntityUid.Valid && len(params.EntityUid.String) > 0 {
+			uidMatch = memUidMatcher(params.EntityUid)
+		}
+		if params.Entity.Valid && len(params.Entity.String) > 0 {
+			nameMatch = memSubstringMatcher(params.Entity, params.CompareMode, params.CaseSensitive)
+		}
+		if uidMatch == nil && nameMatch == nil {
+			return nil
+		}
+	}
+
+	items := []*memEntityItem{}
+	for _, item := range r.entities[e.Table] {
+		if len(filter.Uuids) > 0 && !containsString(filter.Uuids, item.Uuid) {
+			continue
+		}
+		if uidMatch != nil && !uidMatch(item.Uuid) {
+			continue
+		}
+		if nameMatch != nil && !nameMatch(item.Names[e.NameField(lf)]) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (r memEntityRepository) Count(ctx context.Context, e *EntityDesc, filter EntityFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lf := NameFields["default"]
+	if filter.Search != nil {
+		lf = getNameFields(filter.Search.Base.Lang.String)
+	}
+	return int64(len(r.filter(e, lf, filter))), nil
+}
+
+func (r memEntityRepository) List(ctx context.Context, e *EntityDesc, q ListQuery, filter EntityFilter) ([]EntityV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []EntityV1{}
+	for _, item := range r.filter(e, q.Lang, filter) {
+		entity := EntityV1{Id: item.Id, Uuid: item.Uuid, Name: item.Names[e.NameField(q.Lang)]}
+		if e.HasImage {
+			entity.ImageId = r.imageUuid(item.ImageId)
+		}
+		list = append(list, entity)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Uuid < list[j].Uuid
+	})
+	from, to := memPage(len(list), q.Offset, q.Limit)
+	return list[from:to], nil
+}
+
+func (r memEntityRepository) PerfumsCount(ctx context.Context, e *EntityDesc) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, item := range r.entities[e.Table] {
+		count := 0
+		for _, p := range r.perfums {
+			if e.ForeignTable != "parfums" {
+				if p.Refs[e.ForeignKey] == item.Id {
+					count++
+				}
+				continue
+			}
+			for _, c := range r.perfumComposition(p) {
+				if c.Refs[e.ForeignKey] == item.Id {
+					count++
+					break
+				}
+			}
+		}
+		counts[item.Uuid] = int64(count)
+	}
+	return counts, nil
+}
+
+type memReviewRepository struct{ *MemoryStore }
+
+func (r memReviewRepository) GetByUser(ctx context.Context, perfumInfoId int64, userId string) (*ReviewDB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, review := range r.reviews {
+		if review.PerfumInfoId == perfumInfoId && review.UserId == userId {
+			return &review, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r memReviewRepository) Insert(ctx context.Context, review *ReviewDB) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reviews {
+		if existing.PerfumInfoId == review.PerfumInfoId && existing.UserId == review.UserId {
+			return ErrReviewExists
+		}
+	}
+	review.Id = r.nextId()
+	r.reviews = append(r.reviews, *review)
+	return nil
+}
+
+func (r memReviewRepository) Update(ctx context.Context, review *ReviewDB) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.reviews {
+		if r.reviews[i].Id == review.Id {
+			r.reviews[i] = *review
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memReviewRepository) Delete(ctx context.Context, review *ReviewDB) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.reviews {
+		if r.reviews[i].Id == review.Id {
+			r.reviews = append(r.reviews[:i], r.reviews[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memReviewRepository) Count(ctx context.Context, uuids []string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, review := range r.reviews {
+		if p := r.perfumById(review.PerfumInfoId); p != nil && (len(uuids) == 0 || containsString(uuids, p.Uuid)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memReviewRepository) List(ctx context.Context, uuid string, offset, limit int64) ([]ReviewV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []ReviewV1{}
+	p := r.perfumByUuid(uuid)
+	if p == nil {
+		return list, nil
+	}
+	reviews := []ReviewDB{}
+	for _, review := range r.reviews {
+		if review.PerfumInfoId == p.Id {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		if reviews[i].CreatedAt != reviews[j].CreatedAt {
+			return reviews[i].CreatedAt > reviews[j].CreatedAt
+		}
+		return reviews[i].Id > reviews[j].Id
+	})
+	from, to := memPage(len(reviews), offset, limit)
+	for _, review := range reviews[from:to] {
+		list = append(list, ReviewV1{
+			Uuid:       review.Uuid,
+			PerfumUuid: p.Uuid,
+			UserId:     review.UserId,
+			Score:      review.Score,
+			Longevity:  review.Longevity.Int64,
+			Sillage:    review.Sillage.Int64,
+			Text:       review.Text.String,
+			CreatedAt:  review.CreatedAt,
+			UpdatedAt:  review.UpdatedAt,
+		})
+	}
+	return list, nil
+}
+
+type memFavoriteRepository struct{ *MemoryStore }
+
+// add marks the perfums uuids as liked by user, skips the liked ones
+func (r memFavoriteRepository) add(userId string, uuids []string, createdAt int64) int64 {
+	var added int64
+	for _, p := range r.perfums {
+		if !containsString(uuids, p.Uuid) {
+			continue
+		}
+		liked := false
+		for _, favorite := range r.favorites {
+			if favorite.UserId == userId && favorite.PerfumInfoId == p.Id {
+				liked = true
+				break
+			}
+		}
+		if !liked {
+			r.favorites = append(r.favorites, FavoriteDB{Id: r.nextId(), UserId: userId, PerfumInfoId: p.Id, CreatedAt: createdAt})
+			added++
+		}
+	}
+	return added
+}
+
+// remove removes perfums of user matching keep false
+func (r memFavoriteRepository) remove(userId string, keep func(*memPerfum) bool) int64 {
+	var removed int64
+	favorites := r.favorites[:0]
+	for _, favorite := range r.favorites {
+		if favorite.UserId == userId {
+			if p := r.perfumById(favorite.PerfumInfoId); p == nil || !keep(p) {
+				removed++
+				continue
+			}
+		}
+		favorites = append(favorites, favorite)
+	}
+	r.favorites = favorites
+	return removed
+}
+
+func (r memFavoriteRepository) Add(ctx context.Context, userId string, uuids []string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(userId, uuids, time.Now().Unix()), nil
+}
+
+func (r memFavoriteRepository) Replace(ctx context.Context, userId string, uuids []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(userId, func(p *memPerfum) bool { return containsString(uuids, p.Uuid) })
+	r.add(userId, uuids, time.Now().Unix())
+	return nil
+}
+
+func (r memFavoriteRepository) Remove(ctx context.Context, userId string, uuids []string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remove(userId, func(p *memPerfum) bool { return len(uuids) > 0 && !containsString(uuids, p.Uuid) }), nil
+}
+
+func (r memFavoriteRepository) Count(ctx context.Context, userIds []string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, favorite := range r.favorites {
+		if len(userIds) == 0 || containsString(userIds, favorite.UserId) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memFavoriteRepository) List(ctx context.Context, userId string, offset, limit int64) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type liked struct {
+		Uuid      string
+		CreatedAt int64
+	}
+	perfums := []liked{}
+	for _, favorite := range r.favorites {
+		if favorite.UserId != userId {
+			continue
+		}
+		if p := r.perfumById(favorite.PerfumInfoId); p != nil {
+			perfums = append(perfums, liked{p.Uuid, favorite.CreatedAt})
+		}
+	}
+	sort.Slice(perfums, func(i, j int) bool {
+		if perfums[i].CreatedAt != perfums[j].CreatedAt {
+			return perfums[i].CreatedAt > perfums[j].CreatedAt
+		}
+		return perfums[i].Uuid < perfums[j].Uuid
+	})
+
+	from, to := memPage(len(perfums), offset, limit)
+	uuids := []string{}
+	for _, p := range perfums[from:to] {
+		uuids = append(uuids, p.Uuid)
+	}
+	return uuids, nil
+}
+
+type memOfferRepository struct{ *MemoryStore }
+
+func (r memOfferRepository) Count(ctx context.Context, uuids []string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, offer := range r.offers {
+		if p := r.perfumById(offer.PerfumInfoId); p != nil && (len(uuids) == 0 || containsString(uuids, p.Uuid)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memOfferRepository) List(ctx context.Context, lf LangField, uuid string, offset, limit int64) ([]OfferV1, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []OfferV1{}
+	p := r.perfumByUuid(uuid)
+	if p == nil {
+		return list, nil
+	}
+	for _, offer := range r.offers {
+		shop := r.item(shopEntity, offer.ShopId)
+		if offer.PerfumInfoId != p.Id || shop == nil {
+			continue
+		}
+		list = append(list, OfferV1{
+			Uuid:       offer.Uuid,
+			ShopUuid:   shop.Uuid,
+			ShopName:   shop.Names[lf.ShopsName],
+			Price:      offer.Price,
+			Currency:   offer.Currency,
+			Volume:     offer.Volume,
+			Url:        offer.Url.String,
+			LastSeenAt: offer.LastSeenAt,
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Price != list[j].Price {
+			return list[i].Price < list[j].Price
+		}
+		return list[i].LastSeenAt > list[j].LastSeenAt
+	})
+	from, to := memPage(len(list), offset, limit)
+	return list[from:to], nil
+}
+
+// idByUuid and upsertOffer make the store an offerTable
+func (m *MemoryStore) idByUuid(table, uuid string) (int64, error) {
+	if table == "parfum_info" {
+		if p := m.perfumByUuid(uuid); p != nil {
+			return p.Id, nil
+		}
+		return 0, nil
+	}
+	for _, item := range m.entities[table] {
+		if item.Uuid == uuid {
+			return item.Id, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *MemoryStore) upsertOffer(offer *OfferDB) (bool, error) {
+	for i, existing := range m.offers {
+		if existing.PerfumInfoId == offer.PerfumInfoId && existing.ShopId == offer.ShopId && existing.Volume == offer.Volume {
+			offer.Id = existing.Id
+			offer.Uuid = existing.Uuid
+			m.offers[i] = *offer
+			return false, nil
+		}
+	}
+	uuid, err := NewUuid()
+	if err != nil {
+		return false, err
+	}
+	offer.Id = m.nextId()
+	offer.Uuid = uuid
+	m.offers = append(m.offers, *offer)
+	return true, nil
+}
+
+type memCatalogueRepository struct{ *MemoryStore }
+
+func (r memCatalogueRepository) Import(ctx context.Context, items []CatalogueItem) (*CatalogueImportResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.importCatalogue(items)
+}
+
+func (r memCatalogueRepository) Export(ctx context.Context) ([]CatalogueItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lf := catalogueLang
+	name := func(p *memPerfum, e *EntityDesc) string {
+		if item := r.perfumRef(p, e); item != nil {
+			return item.Names[e.NameField(lf)]
+		}
+		return ""
+	}
+	imagePath := func(dir, name sql.NullString) string {
+		if !name.Valid {
+			return ""
+		}
+		return filepath.Join(dir.String, name.String)
+	}
+
+	items := make([]CatalogueItem, 0, len(r.perfums))
+	for _, p := range r.perfums {
+		item := CatalogueItem{
+			Id:            p.Uuid,
+			Name:          p.Name,
+			Year:          p.Year,
+			DescriptionRu: p.Descriptions["description_ru"],
+			DescriptionEn: p.Descriptions["description_en"],
+			Brand:         name(p, brandEntity),
+			Gender:        name(p, genderEntity),
+			Group:         name(p, groupEntity),
+			Country:       name(p, countryEntity),
+			Season:        name(p, seasonEntity),
+			Tsod:          name(p, timeOfDayEntity),
+			Type:          name(p, typeEntity),
+			Shop:          name(p, shopEntity),
+			Composition:   []CatalogueComposition{},
+		}
+		for _, c := range r.perfumComposition(p) {
+			note, component := r.item(noteEntity, c.Refs[noteEntity.ForeignKey]), r.item(componentEntity, c.Refs[componentEntity.ForeignKey])
+			if note != nil && component != nil {
+				item.Composition = append(item.Composition, CatalogueComposition{
+					Note:      note.Names[noteEntity.NameField(lf)],
+					Component: component.Names[componentEntity.NameField(lf)],
+				})
+			}
+		}
+		if image, ok := r.images[p.ImageId]; ok {
+			if small, large := imagePath(image.SmallImgPath, image.SmallImgFname), imagePath(image.LargeImgPath, image.LargeImgFname); small != "" || large != "" {
+				item.Image = &CatalogueImage{Small: small, Large: large}
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// importCatalogue upserts items the way importCatalogue does in a
+// transaction, a rejected item leaves the store untouched
+func (m *MemoryStore) importCatalogue(items []CatalogueItem) (*CatalogueImportResult, error) {
+	result := &CatalogueImportResult{Errors: []CatalogueImportError{}}
+	for i := range items {
+		err := items[i].Validate()
+		inserted := false
+		if err == nil {
+			inserted, err = m.upsertCatalogueItem(&items[i])
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, CatalogueImportError{Item: i + 1, Error: err.Error()})
+			continue
+		}
+
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// entityRefId returns the id of the item of e with the uuid or name ref, 0
+// if ref is empty
+func (m *MemoryStore) entityRefId(e *EntityDesc, ref string) (int64, error) {
+	if ref == "" {
+		return 0, nil
+	}
+	columns := nameColumns(e)
+	for _, item := range m.entities[e.Table] {
+		if uuidRegex.MatchString(ref) {
+			if item.Uuid == ref {
+				return item.Id, nil
+			}
+			continue
+		}
+		for _, column := range columns {
+			if item.Names[column] == ref {
+				return item.Id, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s %q is not found", strings.ToLower(e.Name), ref)
+}
+
+// saveImage updates the image id or adds a new one if id is 0 and returns
+// its id
+func (m *MemoryStore) saveImage(id int64, image CatalogueImage) (int64, error) {
+	split := func(path string) (dir, name sql.NullString) {
+		if path == "" {
+			return
+		}
+		d, n := filepath.Split(filepath.Clean(path))
+		return sql.NullString{String: d, Valid: true}, sql.NullString{String: n, Valid: true}
+	}
+
+	stored, ok := m.images[id]
+	if !ok {
+		uuid, err := NewUuid()
+		if err != nil {
+			return 0, err
+		}
+		stored = ImageDB{Id: m.nextId(), ImgUuid: sql.NullString{String: uuid, Valid: true}}
+	}
+	stored.SmallImgPath, stored.SmallImgFname = split(image.Small)
+	stored.LargeImgPath, stored.LargeImgFname = split(image.Large)
+	m.images[stored.Id] = stored
+	return stored.Id, nil
+}
+
+// upsertCatalogueItem adds or updates the perfum of item and replaces its
+// composition, returns true if the perfum is new. References are resolved
+// before anything is changed.
+func (m *MemoryStore) upsertCatalogueItem(item *CatalogueItem) (bool, error) {
+	refs := make(map[string]int64)
+	for _, ref := range item.refs() {
+		id, err := m.entityRefId(ref.Entity, ref.Ref)
+		if err != nil {
+			return false, err
+		}
+		refs[ref.Entity.ForeignKey] = id
+	}
+	pairs := make([]memNoteComponent, len(item.Composition))
+	for i, c := range item.Composition {
+		var err error
+		if pairs[i].Note, err = m.entityRefId(noteEntity, c.Note); err != nil {
+			return false, err
+		}
+		if pairs[i].Component, err = m.entityRefId(componentEntity, c.Component); err != nil {
+			return false, err
+		}
+	}
+
+	var p *memPerfum
+	for _, existing := range m.perfums {
+		if (item.Id != "" && existing.Uuid == item.Id) ||
+			(item.Id == "" && existing.Name == item.Name && existing.Refs[brandEntity.ForeignKey] == refs[brandEntity.ForeignKey]) {
+			p = existing
+			break
+		}
+	}
+	inserted := p == nil
+	if inserted {
+		uuid := item.Id
+		if uuid == "" {
+			var err error
+			if uuid, err = NewUuid(); err != nil {
+				return false, err
+			}
+		}
+		p = &memPerfum{Uuid: uuid}
+	}
+	if p.DescriptionUuid == "" {
+		uuid, err := NewUuid()
+		if err != nil {
+			return false, err
+		}
+		p.DescriptionUuid = uuid
+	}
+
+	// an item without image keeps the stored one
+	imageId := p.ImageId
+	if item.Image != nil && (item.Image.Small != "" || item.Image.Large != "") {
+		var err error
+		if imageId, err = m.saveImage(p.ImageId, *item.Image); err != nil {
+			return false, err
+		}
+	}
+
+	p.Name = item.Name
+	p.Year = item.Year
+	p.Descriptions = map[string]string{"description_ru": item.DescriptionRu, "description_en": item.DescriptionEn}
+	p.ImageId = imageId
+	p.Refs = refs
+	if inserted {
+		p.Id = m.nextId()
+		m.perfums = append(m.perfums, p)
+	}
+	item.Id = p.Uuid
+
+	// the composition of the item replaces the stored one
+	composition := m.composition[:0]
+	for _, c := range m.composition {
+		if c.PerfumId != p.Id {
+			composition = append(composition, c)
+		}
+	}
+	m.composition = composition
+	for _, pair := range pairs {
+		uuid, err := NewUuid()
+		if err != nil {
+			return false, err
+		}
+		m.composition = append(m.composition, memCompositionRecord{
+			Id:       m.nextId(),
+			Uuid:     uuid,
+			PerfumId: p.Id,
+			Refs:     map[string]int64{noteEntity.ForeignKey: pair.Note, componentEntity.ForeignKey: pair.Component},
+		})
+	}
+
+	return inserted, nil
+}
+
+// LoadFixtures loads the fixtures of dir the way Seed does
+func (m *MemoryStore) LoadFixtures(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	steps := []func(string) error{m.loadUsers}
+	for _, e := range entityRegistry {
+		steps = append(steps, m.loadEntity(e))
+	}
+	steps = append(steps, m.loadPerfums, m.loadOffers, m.loadReviews, m.loadFavorites)
+	for _, step := range steps {
+		if err := step(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) loadUsers(dir string) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "users.csv"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i, row := range rows {
+		if row["user_id"] == "" {
+			return fmt.Errorf("users.csv line %d: user_id is empty", i+2)
+		}
+		if _, ok := m.users[row["user_id"]]; !ok {
+			m.users[row["user_id"]] = UserDB{UserId: row["user_id"], CreatedAt: now, UpdatedAt: now}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) loadEntity(e *EntityDesc) func(string) error {
+	return func(dir string) error {
+		file := e.Table + ".csv"
+		rows, err := readSeedCsv(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+
+		columns := nameColumns(e)
+		for i, row := range rows {
+			names := make(map[string]string)
+			for _, column := range columns {
+				if row[column] == "" {
+					return fmt.Errorf("%s line %d: %s is empty", file, i+2, column)
+				}
+				names[column] = row[column]
+			}
+
+			uuid := row["id"]
+			if uuid == "" {
+				uuid = seedUuid(e.Table, row[columns[len(columns)-1]])
+			} else if !uuidRegex.MatchString(uuid) {
+				return fmt.Errorf("%s line %d: invalid id %q", file, i+2, uuid)
+			}
+
+			item := m.itemByUuid(e, uuid)
+			if item == nil {
+				item = &memEntityItem{Id: m.nextId(), Uuid: uuid}
+				m.entities[e.Table] = append(m.entities[e.Table], item)
+			}
+			item.Names = names
+			if e.HasImage && (row["small_image"] != "" || row["large_image"] != "") {
+				if item.ImageId, err = m.saveImage(item.ImageId, CatalogueImage{Small: row["small_image"], Large: row["large_image"]}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (m *MemoryStore) loadPerfums(dir string) error {
+	file, err := os.Open(filepath.Join(dir, "perfums.json"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	items, err := ReadCatalogue(file, CATALOGUE_FORMAT_JSON)
+	if err != nil {
+		return fmt.Errorf("perfums.json: %v", err)
+	}
+	perfums, err := m.importCatalogue(items)
+	if err != nil {
+		return fmt.Errorf("perfums.json: %v", err)
+	}
+	if len(perfums.Errors) > 0 {
+		return fmt.Errorf("perfums.json item %d: %s", perfums.Errors[0].Item, perfums.Errors[0].Error)
+	}
+	return nil
+}
+
+func (m *MemoryStore) loadOffers(dir string) error {
+	file, err := os.Open(filepath.Join(dir, "offers.csv"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offers, err := importOffersCSV(m, file)
+	if err != nil {
+		return fmt.Errorf("offers.csv: %v", err)
+	}
+	if len(offers.Errors) > 0 {
+		return fmt.Errorf("offers.csv: %v", offers.Errors[0])
+	}
+	return nil
+}
+
+// fixturePerfumId resolves the perfum_id column of a fixture row
+func (m *MemoryStore) fixturePerfumId(ref string) (int64, error) {
+	if ref == "" {
+		return 0, errors.New("perfum_id is empty")
+	}
+	for _, p := range m.perfums {
+		if p.Uuid == ref || p.Name == ref {
+			return p.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("perfum %q is not found", ref)
+}
+
+func (m *MemoryStore) loadReviews(dir string) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "reviews.csv"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i, row := range rows {
+		review := ReviewDB{UserId: row["user_id"], CreatedAt: now, UpdatedAt: now}
+		if review.PerfumInfoId, err = m.fixturePerfumId(row["perfum_id"]); err != nil {
+			return fmt.Errorf("reviews.csv line %d: %v", i+2, err)
+		}
+		if review.Score, err = strconv.ParseInt(row["score"], 10, 64); err != nil {
+			return fmt.Errorf("reviews.csv line %d: invalid score %q", i+2, row["score"])
+		}
+		for column, field := range map[string]*sql.NullInt64{"longevity": &review.Longevity, "sillage": &review.Sillage} {
+			if row[column] == "" {
+				continue
+			}
+			if field.Int64, err = strconv.ParseInt(row[column], 10, 64); err != nil {
+				return fmt.Errorf("reviews.csv line %d: invalid %s %q", i+2, column, row[column])
+			}
+			field.Valid = true
+		}
+		if row["text"] != "" {
+			review.Text = sql.NullString{String: row["text"], Valid: true}
+		}
+
+		review.Uuid = seedUuid("reviews", row["perfum_id"], review.UserId)
+		replaced := false
+		for j := range m.reviews {
+			if m.reviews[j].PerfumInfoId == review.PerfumInfoId && m.reviews[j].UserId == review.UserId {
+				review.Id, review.Uuid, review.CreatedAt = m.reviews[j].Id, m.reviews[j].Uuid, m.reviews[j].CreatedAt
+				m.reviews[j] = review
+				replaced = true
+			}
+		}
+		if !replaced {
+			review.Id = m.nextId()
+			m.reviews = append(m.reviews, review)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) loadFavorites(dir string) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "favorites.csv"))
+	if err != nil {
+		return err
+	}
+
+	favorites := memFavoriteRepository{m}
+	// favorited a second apart in file order, as seedFavorites does
+	now := time.Now().Unix()
+	for i, row := range rows {
+		perfumId, err := m.fixturePerfumId(row["perfum_id"])
+		if err != nil {
+			return fmt.Errorf("favorites.csv line %d: %v", i+2, err)
+		}
+		favorites.add(row["user_id"], []string{m.perfumById(perfumId).Uuid}, now-int64(len(rows)-i))
+	}
+	return nil
+}
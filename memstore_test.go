@@ -0,0 +1,403 @@
+//go:build !integration
+// +build !integration
+
+package main
+
+// The API runs here against a MemoryStore loaded with the repo fixtures, the
+// integration build runs the same routes against PostgreSQL.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// memTestServer serves the router with a MemoryStore of the fixtures and
+// returns the store, the global state is restored on cleanup
+func memTestServer(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	prevStore, prevConfig, prevBaseUrl, prevCred := store, config, baseUrl, oAuthCred
+	prevCache, prevServer, prevLimits := PfumsCountCache, testServer, rateLimitStore
+	t.Cleanup(func() {
+		store, config, baseUrl, oAuthCred = prevStore, prevConfig, prevBaseUrl, prevCred
+		PfumsCountCache, testServer, rateLimitStore = prevCache, prevServer, prevLimits
+	})
+
+	config = DefaultConfig()
+	baseUrl = "http://fragrances.test" + API_PATH
+	accessLogOutput = ioutil.Discard
+	rateLimitStore = NewMemoryRateLimitStore()
+	oAuthCred = &OAuth2Credentials{ClientID: TEST_CLIENT_ID, ProjectID: "fragrances-test"}
+
+	m := NewMemoryStore()
+	if err := m.LoadFixtures(SEED_DIR); err != nil {
+		t.Fatal(err)
+	}
+	store = m.Store()
+	PfumsCountCache = newPfumsCountCache()
+	for _, pcci := range PfumsCountCache {
+		if err := CachePerfumsCount(context.Background(), pcci); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testServer = httptest.NewServer(NewRouter())
+	t.Cleanup(testServer.Close)
+	return m
+}
+
+// setupRouteTests serves the route tests with a MemoryStore, a fake issuer
+// of id tokens and an image of Shalimar in a temporary data dir
+func setupRouteTests(t *testing.T) {
+	t.Helper()
+
+	m := memTestServer(t)
+	prevIssuer, prevImageUuid := testIssuer, testImageUuid
+	t.Cleanup(func() { testIssuer, testImageUuid = prevIssuer, prevImageUuid })
+
+	issuer, err := newFakeIssuer(oAuthCred.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	// certificates of the issuers of previous runs have the same key id
+	certCache.Flush()
+	testIssuer = issuer
+	config.Auth.CertURL = issuer.CertURL()
+	config.Auth.IdTokenIssuer = issuer.Issuer()
+
+	config.Paths.DataDir = t.TempDir()
+	if err := writeTestImageFiles(config.Paths.DataDir); err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	imageId, err := m.saveImage(0, CatalogueImage{Small: "images/small/shalimar.txt", Large: "images/large/shalimar.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range m.perfums {
+		if p.Uuid == TEST_PERFUM_SHALIMAR {
+			p.ImageId = imageId
+		}
+	}
+	testImageUuid = m.images[imageId].ImgUuid.String
+}
+
+// TestMemoryStoreUserRoutes runs the requests of a user in order, each one
+// sees the changes of the previous ones
+func TestMemoryStoreUserRoutes(t *testing.T) {
+	memTestServer(t)
+	token, _ := mintTokens(t, TEST_USER_ID)
+
+	user := "/user/" + TEST_USER_ID
+	dior := "/perfum/" + TEST_PERFUM_DIOR
+	cases := []routeCase{
+		{Name: "User", Method: "GET", Path: user, Token: token, Status: http.StatusOK},
+		{Name: "OtherUser", Method: "GET", Path: "/user/" + TEST_OTHER_USER_ID, Token: token, Status: http.StatusForbidden},
+
+		{Name: "Favorites", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+		{Name: "FavoritesAdd", Method: "POST", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {TEST_PERFUM_DIOR}}, Status: http.StatusCreated},
+		{Name: "FavoritesAdded", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+		{Name: "FavoritesRemove", Method: "DELETE", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {TEST_PERFUM_DIOR}}, Status: http.StatusOK},
+		{Name: "FavoritesReplace", Method: "PUT", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {TEST_PERFUM_CHANEL}}, Status: http.StatusOK},
+		{Name: "FavoritesReplaced", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+		{Name: "Recommendations", Method: "GET", Path: user + "/recommendations", Token: token, Status: http.StatusOK},
+
+		{Name: "ReviewExists", Method: "POST", Path: "/perfum/" + TEST_PERFUM_CHANEL + "/reviews", Token: token, Form: url.Values{"score": {"4"}}, Status: http.StatusConflict},
+		{Name: "ReviewCreate", Method: "POST", Path: dior + "/reviews", Token: token, Form: url.Values{"score": {"4"}, "text": {"Fresh"}}, Status: http.StatusCreated},
+		{Name: "ReviewInvalid", Method: "PUT", Path: dior + "/reviews", Token: token, Form: url.Values{"score": {"11"}}, Status: http.StatusBadRequest},
+		{Name: "ReviewUpdate", Method: "PUT", Path: dior + "/reviews", Token: token, Form: url.Values{"score": {"5"}}, Status: http.StatusOK},
+		{Name: "Reviews", Method: "GET", Path: dior + "/reviews", Token: token, Status: http.StatusOK},
+		{Name: "ReviewDelete", Method: "DELETE", Path: dior + "/reviews", Token: token, Status: http.StatusOK},
+		{Name: "ReviewDeleted", Method: "DELETE", Path: dior + "/reviews", Token: token, Status: http.StatusNotFound},
+
+		{Name: "Logout", Method: "PUT", Path: user + "/logout", Token: token, Status: http.StatusOK},
+	}
+	totals := map[string]int64{"Favorites": 2, "FavoritesAdded": 3, "FavoritesReplaced": 1, "Reviews": 1}
+
+	for _, c := range cases {
+		status, body := doRequest(t, c)
+		if status != c.Status {
+			t.Fatalf("%s: status is %d, expected %d: %s", c.Name, status, c.Status, body)
+		}
+		if total, ok := totals[c.Name]; ok {
+			checkListTotal(t, c.Name, body, total)
+		}
+	}
+}
+
+// TestHandlersWithoutIdentity checks the handlers of the user routes answer
+// 401 when they are reached without the access token validation
+func TestHandlersWithoutIdentity(t *testing.T) {
+	memTestServer(t)
+
+	handlers := map[string]http.HandlerFunc{
+		"GetUser":         GetUserEndpoint,
+		"DeleteUser":      DeleteUserEndpoint,
+		"Logout":          LogoutEndpoint,
+		"Favorites":       GetUserFavoritesEndpoint,
+		"FavoritesAdd":    CreateUserFavoritesEndpoint,
+		"Recommendations": GetUserRecommendationsEndpoint,
+		"ReviewCreate":    CreatePerfumReviewEndpoint,
+		"ReviewDelete":    DeletePerfumReviewEndpoint,
+	}
+	for name, handler := range handlers {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", API_PATH+"/user/"+TEST_USER_ID, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status is %d, expected %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// failingUserRepository fails the lookups of tokens with err
+type failingUserRepository struct {
+	UserRepository
+	err error
+}
+
+func (r failingUserRepository) GetByAccessToken(ctx context.Context, token string) (*UserDB, error) {
+	return nil, r.err
+}
+
+func (r failingUserRepository) GetByRefreshToken(ctx context.Context, token string) (*UserDB, error) {
+	return nil, r.err
+}
+
+// TestValidateAccessTokenErrors checks failed user lookups are answered as
+// server errors and only unknown tokens as 401
+func TestValidateAccessTokenErrors(t *testing.T) {
+	memTestServer(t)
+	token, _ := mintTokens(t, TEST_USER_ID)
+	unknown, err := NewAccessToken(TEST_CLIENT_ID, "unknown-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := store.Users
+
+	cases := []struct {
+		Name   string
+		Token  string
+		Err    error
+		Status int
+	}{
+		{"Valid", token, nil, http.StatusOK},
+		{"UnknownToken", unknown.tokenString, nil, http.StatusUnauthorized},
+		{"DbError", token, errors.New("connection refused"), http.StatusInternalServerError},
+		{"DeadlineExceeded", token, context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"Canceled", token, context.Canceled, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		store.Users = users
+		if c.Err != nil {
+			store.Users = failingUserRepository{users, c.Err}
+		}
+		r := httptest.NewRequest("GET", API_PATH+"/user/"+TEST_USER_ID, nil)
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+		w := httptest.NewRecorder()
+		ValidateAccessToken(w, r, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		if w.Code != c.Status {
+			t.Errorf("%s: status is %d, expected %d: %s", c.Name, w.Code, c.Status, w.Body)
+		}
+	}
+}
+
+// TestTokenEndpointErrors checks failed refresh token lookups are answered
+// as server errors, so clients keep their refresh tokens, and only unknown
+// tokens as 401
+func TestTokenEndpointErrors(t *testing.T) {
+	memTestServer(t)
+	_, token := mintTokens(t, TEST_USER_ID)
+	unknown, err := NewRefreshToken(TEST_CLIENT_ID, "unknown-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := store.Users
+
+	cases := []struct {
+		Name   string
+		Token  string
+		Err    error
+		Status int
+	}{
+		{"UnknownToken", unknown.tokenString, nil, http.StatusUnauthorized},
+		{"DbError", token, errors.New("connection refused"), http.StatusInternalServerError},
+		{"DeadlineExceeded", token, context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"Canceled", token, context.Canceled, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		store.Users = users
+		if c.Err != nil {
+			store.Users = failingUserRepository{users, c.Err}
+		}
+		form := url.Values{"grant_type": {"refresh_token"}, "client_id": {TEST_CLIENT_ID}, "refresh_token": {c.Token}}
+		r := httptest.NewRequest("POST", API_PATH+"/token", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		TokenEndpoint(w, r)
+		if w.Code != c.Status {
+			t.Errorf("%s: status is %d, expected %d: %s", c.Name, w.Code, c.Status, w.Body)
+		}
+	}
+}
+
+// timedOutImageRepository fails the lookups of images like queries past
+// their deadline
+type timedOutImageRepository struct{ ImageRepository }
+
+func (timedOutImageRepository) GetByUuid(ctx context.Context, uuid string) (*ImageDB, error) {
+	return nil, context.DeadlineExceeded
+}
+
+// timedOutReviewRepository fails the lookups of reviews like queries past
+// their deadline
+type timedOutReviewRepository struct{ ReviewRepository }
+
+func (timedOutReviewRepository) GetByUser(ctx context.Context, perfumInfoId int64, userId string) (*ReviewDB, error) {
+	return nil, context.DeadlineExceeded
+}
+
+// TestLookupTimeouts checks failed lookups of images and reviews are not
+// answered as missing ones
+func TestLookupTimeouts(t *testing.T) {
+	memTestServer(t)
+	token, _ := mintTokens(t, TEST_USER_ID)
+	store.Images = timedOutImageRepository{store.Images}
+	store.Reviews = timedOutReviewRepository{store.Reviews}
+
+	reviews := "/perfum/" + TEST_PERFUM_CHANEL + "/reviews"
+	cases := []routeCase{
+		{Name: "ImageSmall", Method: "GET", Path: "/image/" + TEST_UNKNOWN_UUID + "/small", Token: token},
+		{Name: "ImageLarge", Method: "GET", Path: "/image/" + TEST_UNKNOWN_UUID + "/large", Token: token},
+		{Name: "ReviewUpdate", Method: "PUT", Path: reviews, Token: token, Form: url.Values{"score": {"3"}}},
+		{Name: "ReviewDelete", Method: "DELETE", Path: reviews, Token: token},
+	}
+	for _, c := range cases {
+		if status, body := doRequest(t, c); status != http.StatusGatewayTimeout {
+			t.Errorf("%s: status is %d, expected %d: %s", c.Name, status, http.StatusGatewayTimeout, body)
+		}
+	}
+}
+
+// TestFavoritesOrder checks favorites are listed latest first, not by name
+func TestFavoritesOrder(t *testing.T) {
+	m := memTestServer(t)
+	token, _ := mintTokens(t, TEST_USER_ID)
+
+	// Shalimar is liked after Chanel No 5
+	m.mu.Lock()
+	for i, favorite := range m.favorites {
+		if favorite.UserId == TEST_USER_ID {
+			m.favorites[i].CreatedAt = int64(100 + i)
+		}
+	}
+	m.mu.Unlock()
+
+	status, body := doRequest(t, routeCase{Method: "GET", Path: "/user/" + TEST_USER_ID + "/favorites", Token: token})
+	var list struct {
+		Perfums []struct {
+			Uuid string `json:"id"`
+		} `json:"perfums_info_list"`
+	}
+	if err := json.Unmarshal(body, &list); status != http.StatusOK || err != nil {
+		t.Fatalf("status is %d: %v: %s", status, err, body)
+	}
+	if len(list.Perfums) != 2 || list.Perfums[0].Uuid != TEST_PERFUM_SHALIMAR || list.Perfums[1].Uuid != TEST_PERFUM_CHANEL {
+		t.Errorf("favorites are %+v, expected Shalimar then Chanel No 5", list.Perfums)
+	}
+}
+
+// TestMemoryStoreSimilar checks perfums sharing components of the target
+// are scored and the target is left out
+func TestMemoryStoreSimilar(t *testing.T) {
+	m := NewMemoryStore()
+	if err := m.LoadFixtures(SEED_DIR); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	scores, err := m.Store().Perfums.Similar(ctx, TEST_PERFUM_CHANEL, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) == 0 {
+		t.Fatal("no similar perfums")
+	}
+	for i, score := range scores {
+		if score.Uuid == TEST_PERFUM_CHANEL {
+			t.Errorf("target is similar to itself")
+		}
+		if score.Components == 0 || score.NoteComponents > score.Components || score.Score != similarityScore(&score) {
+			t.Errorf("invalid score %+v", score)
+		}
+		if i > 0 && score.Score > scores[i-1].Score {
+			t.Errorf("score %+v is ranked below %+v", score, scores[i-1])
+		}
+	}
+	if count, err := m.Store().Perfums.SimilarCount(ctx, TEST_PERFUM_CHANEL); err != nil || count != int64(len(scores)) {
+		t.Errorf("count is %d, expected %d: %v", count, len(scores), err)
+	}
+	page, err := m.Store().Perfums.Similar(ctx, TEST_PERFUM_CHANEL, 1, 1)
+	if err != nil || len(scores) > 1 && (len(page) != 1 || page[0] != scores[1]) {
+		t.Errorf("page is %+v, expected the second score: %v", page, err)
+	}
+}
+
+// TestMemoryStoreUserData checks reviews and favorites of a user and their
+// removal with the user
+func TestMemoryStoreUserData(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	if err := m.LoadFixtures(SEED_DIR); err != nil {
+		t.Fatal(err)
+	}
+	s := m.Store()
+
+	diorId, err := s.Perfums.IdByUuid(ctx, TEST_PERFUM_DIOR)
+	if err != nil || diorId == 0 {
+		t.Fatalf("perfum id is %d: %v", diorId, err)
+	}
+	review := &ReviewDB{PerfumInfoId: diorId, UserId: TEST_OTHER_USER_ID, Score: 4}
+	if err := s.Reviews.Insert(ctx, review); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reviews.Insert(ctx, &ReviewDB{PerfumInfoId: diorId, UserId: TEST_OTHER_USER_ID, Score: 3}); err != ErrReviewExists {
+		t.Errorf("second review error is %v, expected %v", err, ErrReviewExists)
+	}
+	if added, err := s.Favorites.Add(ctx, TEST_OTHER_USER_ID, []string{TEST_PERFUM_DIOR, TEST_PERFUM_SHALIMAR}); err != nil || added != 1 {
+		t.Errorf("added %d favorites: %v", added, err)
+	}
+
+	recommended, err := s.Perfums.Recommend(ctx, &RecommendParams{UserId: TEST_USER_ID}, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range recommended {
+		if r.Uuid == TEST_PERFUM_CHANEL {
+			t.Errorf("liked perfum %s is recommended", r.Uuid)
+		}
+	}
+
+	user, err := s.Users.Get(ctx, TEST_OTHER_USER_ID)
+	if err != nil || user == nil {
+		t.Fatalf("user is %v: %v", user, err)
+	}
+	if deleted, err := s.Users.Delete(ctx, user); err != nil || !deleted {
+		t.Fatalf("user is not deleted: %v", err)
+	}
+	if found, err := s.Reviews.GetByUser(ctx, diorId, TEST_OTHER_USER_ID); err != nil || found != nil {
+		t.Errorf("review of the deleted user is %v: %v", found, err)
+	}
+	if count, err := s.Favorites.Count(ctx, []string{TEST_OTHER_USER_ID}); err != nil || count != 0 {
+		t.Errorf("deleted user has %d favorites: %v", count, err)
+	}
+}
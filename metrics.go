@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/negroni"
+)
+
+const (
+	METRICS_PATH      = "/metrics"
+	METRICS_NAMESPACE = "fragrancesapi"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by route name, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by route name and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	rateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests refused by rate limit policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		rateLimitedTotal,
+		&appCollector{},
+	)
+}
+
+// RegisterDbMetrics exposes connection pool stats of db
+func RegisterDbMetrics(db *sql.DB) {
+	metricsRegistry.MustRegister(collectors.NewDBStatsCollector(db, "fragrances"))
+}
+
+// MetricsHandler serves metrics of metricsRegistry
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// Metrics is a middleware counting requests and observing their latency
+// by the name of the route from routes.go
+type Metrics struct {
+	*RouteNamer
+}
+
+// NewMetrics returns the middleware resolving route names with namer
+func NewMetrics(namer *RouteNamer) *Metrics {
+	return &Metrics{RouteNamer: namer}
+}
+
+func (m *Metrics) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+
+	next(rw, r)
+
+	route := m.RouteName(r)
+	if route == "" {
+		// keep unknown paths out of the labels
+		route = "unmatched"
+	}
+	res := rw.(negroni.ResponseWriter)
+	requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(res.Status())).Inc()
+	requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(METRICS_NAMESPACE+"_cache_hits_total",
+		"Number of cache lookups found the value.", []string{"cache"}, nil)
+	cacheMissesDesc = prometheus.NewDesc(METRICS_NAMESPACE+"_cache_misses_total",
+		"Number of cache lookups missed the value.", []string{"cache"}, nil)
+	cacheItemsDesc = prometheus.NewDesc(METRICS_NAMESPACE+"_cache_items",
+		"Number of cached items.", []string{"cache"}, nil)
+	perfumsCountCacheAgeDesc = prometheus.NewDesc(METRICS_NAMESPACE+"_perfums_count_cache_age_seconds",
+		"Time since the last successful CachePerfumsCount run, -1 if it never succeeded.", []string{"cache"}, nil)
+)
+
+// appCollector collects stats of the caches at scrape time
+type appCollector struct{}
+
+func (c *appCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheItemsDesc
+	ch <- perfumsCountCacheAgeDesc
+}
+
+func (c *appCollector) Collect(ch chan<- prometheus.Metric) {
+	hits, misses, items := certCache.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(hits), "cert")
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(misses), "cert")
+	ch <- prometheus.MustNewConstMetric(cacheItemsDesc, prometheus.GaugeValue, float64(items), "cert")
+
+	now := time.Now().Unix()
+	for table, cacheItem := range PfumsCountCache {
+		name := "perfums_count_" + table
+		hits, misses, cachedAt := cacheItem.Stats()
+		age := float64(-1)
+		if cachedAt > 0 {
+			age = float64(now - cachedAt)
+		}
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(hits), name)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(misses), name)
+		ch <- prometheus.MustNewConstMetric(perfumsCountCacheAgeDesc, prometheus.GaugeValue, age, name)
+	}
+}
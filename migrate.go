@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MIGRATIONS_LOCK_ID is the key of the advisory lock serializing migrators
+// of several instances
+const MIGRATIONS_LOCK_ID = 7283517
+
+// migration file names are <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration with the time it was applied, zero if it is
+// pending
+type MigrationState struct {
+	Migration
+	AppliedAt int64
+}
+
+// LoadMigrations returns the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.New("migration file name " + entry.Name() + " is not valid")
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		b, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files of different names", version)
+		}
+		if match[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrator applies migrations to db keeping track of them in
+// schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns the migrator of the embedded migrations
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// withLock runs fn on a connection holding the migrations lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", MIGRATIONS_LOCK_ID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", MIGRATIONS_LOCK_ID)
+
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations "+
+		"(version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at BIGINT NOT NULL)"); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]int64, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]int64)
+	for rows.Next() {
+		var version, appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// run executes the script of migration and records it in one transaction
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up applies pending migrations and returns their number
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, found := applied[migration.Version]; found {
+				continue
+			}
+			if err := run(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, time.Now().Unix()); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+			}
+			appLog.Info("migration is applied", F("version", migration.Version), F("name", migration.Name))
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the last steps applied migrations and returns their number
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := m.migrations[i]
+			if _, found := applied[migration.Version]; !found {
+				continue
+			}
+			if err := run(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version=$1", migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+			}
+			appLog.Info("migration is reverted", F("version", migration.Version), F("name", migration.Name))
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status returns the embedded migrations with the time they were applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationState, error) {
+	var states []MigrationState
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			states = append(states, MigrationState{Migration: migration, AppliedAt: applied[migration.Version]})
+		}
+		return nil
+	})
+	return states, err
+}
+
+// runMigrate runs the migrate subcommand: up, down [steps] or status
+func runMigrate(ctx context.Context, db *sql.DB, args []string, out io.Writer) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		fmt.Fprintf(out, "%d migrations applied\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("steps of migrate down must be a positive number")
+			}
+		}
+		count, err := migrator.Down(ctx, steps)
+		fmt.Fprintf(out, "%d migrations reverted\n", count)
+		return err
+	case "status":
+		states, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt > 0 {
+				applied = "applied at " + time.Unix(state.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", state.Version, state.Name, applied)
+		}
+		return nil
+	}
+
+	return errors.New("unknown migrate command " + command + ", use up, down [steps] or status")
+}
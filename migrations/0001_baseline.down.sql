@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS parfums;
+DROP TABLE IF EXISTS parfum_info;
+DROP TABLE IF EXISTS stars;
+DROP TABLE IF EXISTS types;
+DROP TABLE IF EXISTS times_of_day;
+DROP TABLE IF EXISTS shops;
+DROP TABLE IF EXISTS seasons;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS gender;
+DROP TABLE IF EXISTS countries;
+DROP TABLE IF EXISTS components;
+DROP TABLE IF EXISTS brands;
+DROP TABLE IF EXISTS descriptions;
+DROP TABLE IF EXISTS images;
+DROP TABLE IF EXISTS users;
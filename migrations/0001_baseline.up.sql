@@ -0,0 +1,150 @@
+-- Schema the service was deployed with before migrations were introduced.
+-- Reference tables have names in russian and english, see LangField.
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id       TEXT PRIMARY KEY,
+    access_token  TEXT NOT NULL DEFAULT '',
+    refresh_token TEXT NOT NULL DEFAULT '',
+    expires_at    BIGINT NOT NULL DEFAULT 0,
+    created_at    BIGINT NOT NULL DEFAULT 0,
+    updated_at    BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS users_access_token_idx ON users (access_token);
+CREATE INDEX IF NOT EXISTS users_refresh_token_idx ON users (refresh_token);
+
+CREATE TABLE IF NOT EXISTS images (
+    id                 SERIAL PRIMARY KEY,
+    uuid               UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    small_img_filename TEXT,
+    small_img_path     TEXT,
+    small_img_link     TEXT,
+    small_img_etag     TEXT,
+    large_img_filename TEXT,
+    large_img_path     TEXT,
+    large_img_link     TEXT,
+    large_img_etag     TEXT,
+    note               TEXT
+);
+
+CREATE TABLE IF NOT EXISTS descriptions (
+    id             SERIAL PRIMARY KEY,
+    uuid           UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    description_ru TEXT NOT NULL DEFAULT '',
+    description_en TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS brands (
+    id       SERIAL PRIMARY KEY,
+    uuid     UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name     TEXT NOT NULL,
+    image_id INTEGER REFERENCES images (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS components (
+    id       SERIAL PRIMARY KEY,
+    uuid     UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru  TEXT NOT NULL,
+    name_en  TEXT NOT NULL,
+    image_id INTEGER REFERENCES images (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS countries (
+    id       SERIAL PRIMARY KEY,
+    uuid     UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru  TEXT NOT NULL,
+    name_en  TEXT NOT NULL,
+    image_id INTEGER REFERENCES images (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS gender (
+    id       SERIAL PRIMARY KEY,
+    uuid     UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru  TEXT NOT NULL,
+    name_en  TEXT NOT NULL,
+    image_id INTEGER REFERENCES images (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shops (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS times_of_day (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS types (
+    id      SERIAL PRIMARY KEY,
+    uuid    UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stars (
+    id   SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL UNIQUE DEFAULT gen_random_uuid()
+);
+
+CREATE TABLE IF NOT EXISTS parfum_info (
+    id             SERIAL PRIMARY KEY,
+    uuid           UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name           TEXT NOT NULL,
+    year           INTEGER NOT NULL DEFAULT 0,
+    description_id INTEGER REFERENCES descriptions (id),
+    brand_id       INTEGER REFERENCES brands (id),
+    gender_id      INTEGER REFERENCES gender (id),
+    group_id       INTEGER REFERENCES groups (id),
+    country_id     INTEGER REFERENCES countries (id),
+    type_id        INTEGER REFERENCES types (id),
+    season_id      INTEGER REFERENCES seasons (id),
+    tsod_id        INTEGER REFERENCES times_of_day (id),
+    shop_id        INTEGER REFERENCES shops (id),
+    image_id       INTEGER REFERENCES images (id) ON DELETE SET NULL,
+    stars_id       INTEGER REFERENCES stars (id)
+);
+CREATE INDEX IF NOT EXISTS parfum_info_brand_id_idx ON parfum_info (brand_id);
+CREATE INDEX IF NOT EXISTS parfum_info_gender_id_idx ON parfum_info (gender_id);
+CREATE INDEX IF NOT EXISTS parfum_info_group_id_idx ON parfum_info (group_id);
+CREATE INDEX IF NOT EXISTS parfum_info_country_id_idx ON parfum_info (country_id);
+CREATE INDEX IF NOT EXISTS parfum_info_type_id_idx ON parfum_info (type_id);
+CREATE INDEX IF NOT EXISTS parfum_info_season_id_idx ON parfum_info (season_id);
+CREATE INDEX IF NOT EXISTS parfum_info_tsod_id_idx ON parfum_info (tsod_id);
+CREATE INDEX IF NOT EXISTS parfum_info_shop_id_idx ON parfum_info (shop_id);
+
+-- composition of a perfum: one row per note and component
+CREATE TABLE IF NOT EXISTS parfums (
+    id             SERIAL PRIMARY KEY,
+    uuid           UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    parfum_info_id INTEGER NOT NULL REFERENCES parfum_info (id) ON DELETE CASCADE,
+    note_id        INTEGER NOT NULL REFERENCES notes (id),
+    component_id   INTEGER NOT NULL REFERENCES components (id)
+);
+CREATE INDEX IF NOT EXISTS parfums_parfum_info_id_idx ON parfums (parfum_info_id);
+CREATE INDEX IF NOT EXISTS parfums_note_id_idx ON parfums (note_id);
+CREATE INDEX IF NOT EXISTS parfums_component_id_idx ON parfums (component_id);
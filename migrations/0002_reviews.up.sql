@@ -0,0 +1,15 @@
+CREATE TABLE reviews (
+    id             BIGSERIAL PRIMARY KEY,
+    uuid           UUID NOT NULL UNIQUE,
+    parfum_info_id INTEGER NOT NULL REFERENCES parfum_info (id) ON DELETE CASCADE,
+    user_id        TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    score          SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    longevity      SMALLINT CHECK (longevity BETWEEN 1 AND 5),
+    sillage        SMALLINT CHECK (sillage BETWEEN 1 AND 5),
+    text           TEXT,
+    created_at     BIGINT NOT NULL,
+    updated_at     BIGINT NOT NULL,
+    -- a user reviews a perfum once
+    UNIQUE (parfum_info_id, user_id)
+);
+CREATE INDEX reviews_user_id_idx ON reviews (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE offers (
+    id             BIGSERIAL PRIMARY KEY,
+    uuid           UUID NOT NULL UNIQUE,
+    parfum_info_id INTEGER NOT NULL REFERENCES parfum_info (id) ON DELETE CASCADE,
+    shop_id        INTEGER NOT NULL REFERENCES shops (id) ON DELETE CASCADE,
+    price          NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    currency       CHAR(3) NOT NULL,
+    volume         NUMERIC(8, 2) NOT NULL CHECK (volume > 0),
+    url            TEXT,
+    last_seen_at   BIGINT NOT NULL,
+    -- key of the CSV importer upsert
+    UNIQUE (parfum_info_id, shop_id, volume)
+);
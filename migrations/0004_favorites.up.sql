@@ -0,0 +1,7 @@
+CREATE TABLE favorites (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    parfum_info_id INTEGER NOT NULL REFERENCES parfum_info (id) ON DELETE CASCADE,
+    created_at     BIGINT NOT NULL,
+    UNIQUE (user_id, parfum_info_id)
+);
@@ -1,11 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/unrolled/render"
-	"fmt"
 	"net/http"
 	"sort"
 )
@@ -15,14 +14,14 @@ type MakeObjParams struct {
 	Id         string
 	Total      int64
 	PerfumsNum NullInt64
-	DbQuery    QueryTemplateParams
+	Filter     PerfumFilter
 }
 
 type Objecter interface {
-	MakeObj(pParams interface{}) (Objecter, error)
-	MakeExtraObj(params *MakeObjParams, uuids []string) (Objecter, error)
-	Count(pParams interface{}) (int64, error)
-	ExtraCount(uuids []string) (int64, error)
+	MakeObj(ctx context.Context, pParams interface{}) (Objecter, error)
+	MakeExtraObj(ctx context.Context, params *MakeObjParams, uuids []string) (Objecter, error)
+	Count(ctx context.Context, pParams interface{}) (int64, error)
+	ExtraCount(ctx context.Context, uuids []string) (int64, error)
 	Json(w http.ResponseWriter, status int) error
 }
 
@@ -58,6 +57,10 @@ type PerfumInfoV1 struct {
 	ImgUuid         sql.NullString `db:"img_uuid" json:"-"`
 	StarsUuid       sql.NullString `db:"stars_uuid" json:"stars_id"`
 	ShopUuid        sql.NullString `db:"shop_uuid" json:"shop_id"`
+	ScoreAvg        float64        `db:"score_avg" json:"score_avg"`
+	LongevityAvg    float64        `db:"longevity_avg" json:"longevity_avg"`
+	SillageAvg      float64        `db:"sillage_avg" json:"sillage_avg"`
+	ReviewsCount    int64          `db:"reviews_count" json:"reviews_count"`
 	Links           []LinkV1       `db:"-" json:"links"`
 	SmallImgUrl     string         `db:"-" json:"small_img_url"`
 	LargeImgUrl     string         `db:"-" json:"large_img_url"`
@@ -79,32 +82,21 @@ func NewPerfumsInfoFactory(version string) Objecter {
 	return nil
 }
 
-func (obj *PerfumsInfoV1) MakeObj(pParams interface{}) (Objecter, error) {
+func (obj *PerfumsInfoV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
 	if pParams == nil {
 		return nil, errors.New("invalid args")
 	}
 	params := pParams.(*MakeObjParams)
 
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
 	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
+		params.Filter.Uuids = params.Base.Ids.String
 	}
 
-	fmt.Println("================================= Query begin =======================================")
-	fmt.Println(query.String())
-	fmt.Println("================================= Query end =========================================")
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
+	list, err := store.Perfums.List(ctx, NewListQuery(&params.Base), params.Filter)
+	if err != nil {
 		return nil, err
 	}
+	obj.ObjList = list
 
 	obj.Total = params.Total
 	obj.Offset = params.Base.Offset.Int64
@@ -117,6 +109,21 @@ func (obj *PerfumsInfoV1) MakeObj(pParams interface{}) (Objecter, error) {
 				Rel:    "PerfumInfo",
 				Method: "GET",
 			},
+			LinkV1{
+				Href:   baseUrl + "/perfum/" + obj.ObjList[i].Uuid + "/reviews",
+				Rel:    "PerfumReviews",
+				Method: "GET",
+			},
+			LinkV1{
+				Href:   baseUrl + "/perfum/" + obj.ObjList[i].Uuid + "/offers",
+				Rel:    "PerfumOffers",
+				Method: "GET",
+			},
+			LinkV1{
+				Href:   baseUrl + "/perfum/" + obj.ObjList[i].Uuid + "/similar",
+				Rel:    "PerfumSimilar",
+				Method: "GET",
+			},
 		}
 
 		if obj.ObjList[i].ImgUuid.Valid {
@@ -128,63 +135,32 @@ func (obj *PerfumsInfoV1) MakeObj(pParams interface{}) (Objecter, error) {
 	return obj, nil
 }
 
-func (obj *PerfumsInfoV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
+func (obj *PerfumsInfoV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
 	if params == nil || len(uids) == 0 {
 		return nil, errors.New("invalid args")
 	}
 
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if len(uids) > 0 {
-		params.DbQuery.WhereConditionString = addIdsToQuery(uids, "parfum_info.uuid")
-		params.Base.Ids.Valid = false
-	}
+	params.Filter.Uuids = uids
+	params.Base.Ids.Valid = false
 
 	composition := NewPerfumsCompositionFactory(params.Base.Version)
-	return composition.MakeObj(params)
+	return composition.MakeObj(ctx, params)
 }
 
-func (obj *PerfumsInfoV1) Count(pParams interface{}) (int64, error) {
+func (obj *PerfumsInfoV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
 	if pParams == nil {
 		return 0, errors.New("invalid args")
 	}
 
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+	return store.Perfums.Count(ctx, PerfumFilter{})
 }
 
-func (obj *PerfumsInfoV1) ExtraCount(uids []string) (int64, error) {
+func (obj *PerfumsInfoV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
 	if len(uids) == 0 {
 		return 0, errors.New("invalid args")
 	}
 
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.WhereConditionString = addIdsToQuery(uids, "parfum_info.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+	return store.Perfums.Count(ctx, PerfumFilter{Uuids: uids})
 }
 
 func (obj *PerfumsInfoV1) Json(w http.ResponseWriter, status int) error {
@@ -243,17 +219,7 @@ func (note *NoteItemV1) AddComponentItem(componentToAdd *ComponentItemV1) *NoteI
 		return note
 	}
 
-	componentToAdd.Links = append(componentToAdd.Links,
-		LinkV1{
-			Href:   baseUrl + "/component/" + componentToAdd.Id,
-			Rel:    "ComponentInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/component/" + componentToAdd.Id + "/perfums",
-			Rel:    "ComponentPerfums",
-			Method: "GET",
-		})
+	componentToAdd.Links = append(componentToAdd.Links, componentEntity.Links(componentToAdd.Id)...)
 	note.Components = append(note.Components, *componentToAdd)
 
 	return note
@@ -284,17 +250,7 @@ func (obj *PerfumCompositionV1) AddNoteItem(noteToAdd *NoteItemV1) *PerfumCompos
 		return obj
 	}
 
-	noteToAdd.Links = append(noteToAdd.Links,
-		LinkV1{
-			Href:   baseUrl + "/note/" + noteToAdd.Id,
-			Rel:    "NoteInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/note/" + noteToAdd.Id + "/perfums",
-			Rel:    "NotePerfums",
-			Method: "GET",
-		})
+	noteToAdd.Links = append(noteToAdd.Links, noteEntity.Links(noteToAdd.Id)...)
 
 	obj.Notes = append(obj.Notes, *noteToAdd)
 
@@ -307,83 +263,34 @@ func (obj *PerfumCompositionV1) AddPerfumInfoItem(info *PerfumInfoV1) *PerfumCom
 	}
 
 	obj.PerfumInfoV1 = *info
-	obj.PerfumInfoV1.Links = []LinkV1{
-		LinkV1{
-			Href:   baseUrl + "/brand/" + info.BrandUuid,
-			Rel:    "BrandInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/brand/" + info.BrandUuid + "/perfums",
-			Rel:    "BrandPerfums",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/country/" + info.CountryUuid,
-			Rel:    "CountryInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/country/" + info.CountryUuid + "/perfums",
-			Rel:    "CountryPerfums",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/gender/" + info.GenderUuid,
-			Rel:    "GenderInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/gender/" + info.GenderUuid + "/perfums",
-			Rel:    "GenderPerfums",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/group/" + info.GroupUuid,
-			Rel:    "GroupInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/group/" + info.GroupUuid + "/perfums",
-			Rel:    "GroupPerfums",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/season/" + info.SeasonUuid,
-			Rel:    "SeasonInfo",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/season/" + info.SeasonUuid + "/perfums",
-			Rel:    "SeasonPerfums",
-			Method: "GET",
-		},
-		LinkV1{
-			Href:   baseUrl + "/timeofday/" + info.TsodUuid,
-			Rel:    "TimeofdayInfo",
-			Method: "GET",
-		},
+	obj.PerfumInfoV1.Links = []LinkV1{}
+	for _, e := range entityRegistry {
+		if e.InfoUuid == nil || e.InfoUuid(info) == "" {
+			continue
+		}
+		obj.PerfumInfoV1.Links = append(obj.PerfumInfoV1.Links, e.Links(e.InfoUuid(info))...)
+	}
+	obj.PerfumInfoV1.Links = append(obj.PerfumInfoV1.Links,
 		LinkV1{
-			Href:   baseUrl + "/timeofday/" + info.TsodUuid + "/perfums",
-			Rel:    "TimeofdayPerfums",
+			Href:   baseUrl + "/perfum/" + info.Uuid,
+			Rel:    "PerfumInfo",
 			Method: "GET",
 		},
 		LinkV1{
-			Href:   baseUrl + "/type/" + info.TypeUuid,
-			Rel:    "TypeInfo",
+			Href:   baseUrl + "/perfum/" + info.Uuid + "/reviews",
+			Rel:    "PerfumReviews",
 			Method: "GET",
 		},
 		LinkV1{
-			Href:   baseUrl + "/type/" + info.TypeUuid + "/perfums",
-			Rel:    "TypePerfums",
+			Href:   baseUrl + "/perfum/" + info.Uuid + "/offers",
+			Rel:    "PerfumOffers",
 			Method: "GET",
 		},
 		LinkV1{
-			Href:   baseUrl + "/perfum/" + info.Uuid,
-			Rel:    "PerfumInfo",
+			Href:   baseUrl + "/perfum/" + info.Uuid + "/similar",
+			Rel:    "PerfumSimilar",
 			Method: "GET",
-		},
-	}
+		})
 
 	if info.ImgUuid.Valid {
 		obj.SmallImgUrl = baseUrl + "/image/" + info.ImgUuid.String + "/small"
@@ -420,38 +327,25 @@ func NewPerfumsCompositionFactory(version string) Objecter {
 	return nil
 }
 
-func (obj *PerfumsCompositionV1) MakeObj(pParams interface{}) (Objecter, error) {
+func (obj *PerfumsCompositionV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
 	if pParams == nil {
 		return nil, errors.New("invalid args")
 	}
 
 	params := pParams.(*MakeObjParams)
 
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
+	perfumInfos := PerfumsInfoV1{}
+	if _, err := perfumInfos.MakeObj(ctx, params); err != nil {
 		return nil, err
 	}
 
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-
-	perfumInfos := PerfumsInfoV1{}
-	perfumInfos.MakeObj(params)
-
 	perfumInfoMap := make(map[string]*PerfumInfoV1)
 	for i := range perfumInfos.ObjList {
 		perfumInfoMap[perfumInfos.ObjList[i].Uuid] = &perfumInfos.ObjList[i]
 	}
 
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_perfums_on_perfum_info_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	var records []PerfumCompositionDBRecordV1
-	if _, err = dbmap.Select(&records, query.String()); err != nil {
+	records, err := store.Perfums.Composition(ctx, getNameFields(params.Base.Lang.String), params.Filter.Uuids)
+	if err != nil {
 		return nil, err
 	}
 
@@ -528,56 +422,24 @@ func (obj *PerfumsCompositionV1) MakeObj(pParams interface{}) (Objecter, error)
 	return obj, nil
 }
 
-func (obj *PerfumsCompositionV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
+func (obj *PerfumsCompositionV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
 	return obj, nil
 }
 
-func (obj *PerfumsCompositionV1) Count(pParams interface{}) (int64, error) {
+func (obj *PerfumsCompositionV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
 	if pParams == nil {
 		return 0, errors.New("invalid args")
 	}
 
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfums"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+	return store.Perfums.CountComposition(ctx, nil)
 }
 
-func (obj *PerfumsCompositionV1) ExtraCount(uids []string) (int64, error) {
+func (obj *PerfumsCompositionV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
 	if len(uids) == 0 {
 		return 0, errors.New("invalid args")
 	}
 
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfums"
-	dbQuery.ConditionTableField = "parfum_info_id"
-	dbQuery.ConditionTableName = "parfum_info"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "parfum_info.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+	return store.Perfums.CountComposition(ctx, uids)
 }
 
 func (obj *PerfumsCompositionV1) Json(w http.ResponseWriter, status int) error {
@@ -585,2048 +447,104 @@ func (obj *PerfumsCompositionV1) Json(w http.ResponseWriter, status int) error {
 	return render.JSON(w, status, obj)
 }
 
-// Brand ...
-type BrandV1 struct {
-	Id           string         `db:"id" json:"-"`
-	Uuid         string         `db:"brand_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-// Brands ...
-type BrandsV1 struct {
-	ObjList []BrandV1 `db:"-" json:"brands_list"`
-	Total   int64     `db:"-" json:"total"`
-	Offset  int64     `db:"-" json:"offset"`
-	Amount  int64     `db:"-" json:"amount"`
+type PerfumsSearchResultV1 struct {
+	Links  []LinkV1 `json:"links"`
+	Total  int64    `json:"total"`
+	Offset int64    `json:"offset"`
+	Amount int64    `json:"amount"`
 }
 
-func NewBrandsFactory(version string) Objecter {
+func NewPerfumsSearchResultFactory(version string) Objecter {
 	switch version {
 	case "v1":
-		return &BrandsV1{ObjList: make([]BrandV1, 0)}
+		return &PerfumsSearchResultV1{Links: make([]LinkV1, 0)}
 	}
 
 	return nil
 }
 
-func (obj *BrandsV1) MakeObj(pParams interface{}) (Objecter, error) {
+func (obj *PerfumsSearchResultV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
 	if pParams == nil {
 		return nil, errors.New("invalid args")
 	}
 
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "brands.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_brands", &params.DbQuery); err != nil {
-		return nil, err
-	}
+	params := pParams.(*SearchParams)
 
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
+	results, err := store.Perfums.Search(ctx, params)
+	if err != nil {
 		return nil, err
 	}
 
 	obj.Total = params.Total
 	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
+	obj.Amount = int64(len(results))
 
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("brands", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/brand/" + obj.ObjList[i].Uuid,
-				Rel:    "BrandInfo",
-				Method: "GET",
-			},
+	for _, result := range results {
+		obj.Links = append(obj.Links,
 			LinkV1{
-				Href:   baseUrl + "/brand/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "BrandPerfums",
+				Href:   baseUrl + "/perfum/" + result,
+				Rel:    "PerfumInfo",
 				Method: "GET",
 			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
+		)
 	}
 
 	return obj, nil
 }
 
-func (obj *BrandsV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "brand_id"
-	params.DbQuery.ConditionTableName = "brands"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "brands.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
+func (obj *PerfumsSearchResultV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
 }
 
-func (obj *BrandsV1) Count(pParams interface{}) (int64, error) {
+func (obj *PerfumsSearchResultV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
 	if pParams == nil {
 		return 0, errors.New("invalid args")
 	}
 
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "brands"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+	params := pParams.(*SearchParams)
+	return store.Perfums.SearchCount(ctx, params)
 }
 
-func (obj *BrandsV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "brand_id"
-	dbQuery.ConditionTableName = "brands"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "brands.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
+func (obj *PerfumsSearchResultV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	return 0, nil
 }
 
-func (obj *BrandsV1) Json(w http.ResponseWriter, status int) error {
+func (obj *PerfumsSearchResultV1) Json(w http.ResponseWriter, status int) error {
 	render := render.New()
 	return render.JSON(w, status, obj)
 }
 
-// ComponentDB ...
-type ComponentV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"component_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-// Components ...
-type ComponentsV1 struct {
-	ObjList []ComponentV1 `db:"-" json:"components"`
-	Total   int64         `db:"-" json:"total"`
-	Offset  int64         `db:"-" json:"offset"`
-	Amount  int64         `db:"-" json:"amount"`
+// UserReq
+type UserReq struct {
+	UserId string `json:"user_id"`
 }
 
-func NewComponentsFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &ComponentsV1{ObjList: make([]ComponentV1, 0)}
-	}
-
-	return nil
+// LoginReq ...
+type LoginReq struct {
+	AuthcodeString string `json:"auth_code" binding:"required"`
 }
 
-func (obj *ComponentsV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "components.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_components", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("components", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/component/" + obj.ObjList[i].Uuid,
-				Rel:    "ComponentInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/component/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "ComponentPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
+// LoginResp represents an authenticated response.
+type LoginResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	UserId       string `json:"user_id"`
 }
 
-func (obj *ComponentsV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.WhereConditionString = addIdsToQuery(uids, "components.uuid")
-	query := bytes.NewBufferString("")
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-	if err := tmpl.ExecuteTemplate(query, "condition_innerjoin_component_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.AuxConditionString = query.String()
-	params.DbQuery.WhereConditionString = ""
-	params.DbQuery.AndConditionString = ""
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
+// UserResp ...
+type UserResp struct {
+	UserId    string   `json:"user_id"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	Links     []LinkV1 `json:"links"`
 }
 
-func (obj *ComponentsV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "components"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *ComponentsV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfums"
-	dbQuery.ConditionTableField = "component_id"
-	dbQuery.ConditionTableName = "components"
-	dbQuery.DistinctTableField = "parfum_info_id"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "components.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *ComponentsV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type CountryV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"country_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-// Countries ...
-type CountriesV1 struct {
-	ObjList []CountryV1 `db:"-" json:"countries_list"`
-	Total   int64       `db:"-" json:"total"`
-	Offset  int64       `db:"-" json:"offset"`
-	Amount  int64       `db:"-" json:"amount"`
-}
-
-func NewCountriesFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &CountriesV1{ObjList: make([]CountryV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *CountriesV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "countries.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_countries", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("countries", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/country/" + obj.ObjList[i].Uuid,
-				Rel:    "CountryInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/country/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "CountryPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *CountriesV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "country_id"
-	params.DbQuery.ConditionTableName = "countries"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "countries.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *CountriesV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "countries"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *CountriesV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "country_id"
-	dbQuery.ConditionTableName = "countries"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "countries.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *CountriesV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type GenderV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"gender_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-// GendersV1 ...
-type GendersV1 struct {
-	ObjList []GenderV1 `db:"-" json:"gender_list"`
-	Total   int64      `db:"-" json:"total"`
-	Offset  int64      `db:"-" json:"offset"`
-	Amount  int64      `db:"-" json:"amount"`
-}
-
-func NewGendersFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &GendersV1{ObjList: make([]GenderV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *GendersV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "gender.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_gender", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("genders", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/gender/" + obj.ObjList[i].Uuid,
-				Rel:    "GenderInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/gender/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "GenderPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *GendersV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "gender_id"
-	params.DbQuery.ConditionTableName = "gender"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "gender.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *GendersV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "gender"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *GendersV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "gender_id"
-	dbQuery.ConditionTableName = "gender"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "gender.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *GendersV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type GroupV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"group_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-// GroupsV1 ...
-type GroupsV1 struct {
-	ObjList []GroupV1 `db:"-" json:"groups_list"`
-	Total   int64     `db:"-" json:"total"`
-	Offset  int64     `db:"-" json:"offset"`
-	Amount  int64     `db:"-" json:"amount"`
-}
-
-func NewGroupsFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &GroupsV1{ObjList: make([]GroupV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *GroupsV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "groups.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_groups", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("groups", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/group/" + obj.ObjList[i].Uuid,
-				Rel:    "GroupInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/group/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "GroupPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *GroupsV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "group_id"
-	params.DbQuery.ConditionTableName = "groups"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "groups.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *GroupsV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "groups"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *GroupsV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "group_id"
-	dbQuery.ConditionTableName = "groups"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "groups.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *GroupsV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type NoteV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"note_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-type NotesV1 struct {
-	ObjList []NoteV1 `db:"-" json:"notes_list"`
-	Total   int64    `db:"-" json:"total"`
-	Offset  int64    `db:"-" json:"offset"`
-	Amount  int64    `db:"-" json:"amount"`
-}
-
-func NewNotesFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &NotesV1{ObjList: make([]NoteV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *NotesV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "notes.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_notes", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("notes", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/note/" + obj.ObjList[i].Uuid,
-				Rel:    "NoteInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/note/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "NotePerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *NotesV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.WhereConditionString = addIdsToQuery(uids, "notes.uuid")
-	query := bytes.NewBufferString("")
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "notes.uuid")
-	}
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-	if err := tmpl.ExecuteTemplate(query, "condition_innerjoin_note_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.AuxConditionString = query.String()
-	params.DbQuery.WhereConditionString = ""
-	params.DbQuery.AndConditionString = ""
-	query.Reset()
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *NotesV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "notes"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *NotesV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfums"
-	dbQuery.ConditionTableField = "note_id"
-	dbQuery.ConditionTableName = "notes"
-	dbQuery.DistinctTableField = "parfum_info_id"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "notes.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *NotesV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type SeasonV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"season_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-type SeasonsV1 struct {
-	ObjList []SeasonV1 `db:"-" json:"seasons_list"`
-	Total   int64      `db:"-" json:"total"`
-	Offset  int64      `db:"-" json:"offset"`
-	Amount  int64      `db:"-" json:"amount"`
-}
-
-func NewSeasonsFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &SeasonsV1{ObjList: make([]SeasonV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *SeasonsV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "seasons.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_seasons", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("seasons", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/season/" + obj.ObjList[i].Uuid,
-				Rel:    "SeasonInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/season/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "SeasonPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *SeasonsV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "season_id"
-	params.DbQuery.ConditionTableName = "seasons"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "seasons.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *SeasonsV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "seasons"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *SeasonsV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "season_id"
-	dbQuery.ConditionTableName = "seasons"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "seasons.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *SeasonsV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type TimeOfDayV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"tsod_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-type TimesOfDayV1 struct {
-	ObjList []TimeOfDayV1 `db:"-" json:"timeofday_list"`
-	Total   int64         `db:"-" json:"total"`
-	Offset  int64         `db:"-" json:"offset"`
-	Amount  int64         `db:"-" json:"amount"`
-}
-
-func NewTimesOfDayFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &TimesOfDayV1{ObjList: make([]TimeOfDayV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *TimesOfDayV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "times_of_day.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_timeofday", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("timesOfDay", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/timeofday/" + obj.ObjList[i].Uuid,
-				Rel:    "TimeofdayInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/timeofday/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "TimeofdayPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *TimesOfDayV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "tsod_id"
-	params.DbQuery.ConditionTableName = "times_of_day"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "times_of_day.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *TimesOfDayV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "times_of_day"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *TimesOfDayV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "tsod_id"
-	dbQuery.ConditionTableName = "times_of_day"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "times_of_day.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *TimesOfDayV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type TypeV1 struct {
-	Id           int64          `db:"id" json:"-"`
-	Uuid         string         `db:"type_uuid" json:"id"`
-	Name         string         `db:"name" json:"name"`
-	ImageId      sql.NullString `db:"img_uuid" json:"-"`
-	PerfumsCount int64          `db:"-" json:"perfums_count"`
-	Links        []LinkV1       `db:"-" json:"links"`
-	SmallImgUrl  string         `db:"-" json:"small_img_url"`
-	LargeImgUrl  string         `db:"-" json:"large_img_url"`
-}
-
-type TypesV1 struct {
-	ObjList []TypeV1 `db:"-" json:"types_list"`
-	Total   int64    `db:"-" json:"total"`
-	Offset  int64    `db:"-" json:"offset"`
-	Amount  int64    `db:"-" json:"amount"`
-}
-
-func NewTypesFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &TypesV1{ObjList: make([]TypeV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *TypesV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*MakeObjParams)
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.WhereConditionString = addIdsToQuery(params.Base.Ids.String, "types.uuid")
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_types", &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		// obj.ObjList[i].PerfumsCount = params.PerfumsNum
-		perfumsCount, _ := GetPerfumsCount("types", obj.ObjList[i].Uuid)
-		obj.ObjList[i].PerfumsCount = perfumsCount
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/type/" + obj.ObjList[i].Uuid,
-				Rel:    "TypeInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/type/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "TypePerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *TypesV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	if params == nil || len(uids) == 0 {
-		return nil, errors.New("invalid args")
-	}
-
-	params.DbQuery.ConditionTableField = "type_id"
-	params.DbQuery.ConditionTableName = "types"
-	params.DbQuery.ConditionUuid = addIdsToQuery(uids, "types.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &params.DbQuery); err != nil {
-		return nil, err
-	}
-	params.DbQuery.WhereConditionString = query.String()
-	query.Reset()
-
-	if err := setDbQueryBaseParams(&params.Base, &params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	if params.Base.Ids.Valid {
-		params.DbQuery.AndConditionString = addIdsToQuery(params.Base.Ids.String, "parfum_info.uuid")
-	}
-
-	if err := tmpl.ExecuteTemplate(query, "perfum_info_base", params.DbQuery); err != nil {
-		return nil, err
-	}
-
-	pinfos := NewPerfumsInfoFactory(params.Base.Version)
-	return pinfos.MakeObj(params)
-}
-
-func (obj *TypesV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "types"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *TypesV1) ExtraCount(uids []string) (int64, error) {
-	if len(uids) == 0 {
-		return 0, errors.New("invalid args")
-	}
-
-	dbQuery := QueryTemplateParams{}
-	dbQuery.FromTableName = "parfum_info"
-	dbQuery.ConditionTableField = "type_id"
-	dbQuery.ConditionTableName = "types"
-	dbQuery.ConditionUuid = addIdsToQuery(uids, "types.uuid")
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "condition_select_id_eq_uuid", &dbQuery); err != nil {
-		return 0, err
-	}
-	dbQuery.WhereConditionString = query.String()
-	query.Reset()
-	if err := tmpl.ExecuteTemplate(query, "select_count", &dbQuery); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *TypesV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-type PerfumsSearchResultV1 struct {
-	Links  []LinkV1 `json:"links"`
-	Total  int64    `json:"total"`
-	Offset int64    `json:"offset"`
-	Amount int64    `json:"amount"`
-}
-
-func NewPerfumsSearchResultFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &PerfumsSearchResultV1{Links: make([]LinkV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *PerfumsSearchResultV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return nil, err
-	}
-	search.Order = "perfum_info.info_uuid"
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "perfum_search", &search); err != nil {
-		return nil, err
-	}
-
-	var results []string
-	if _, err = dbmap.Select(&results, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(results))
-
-	for _, result := range results {
-		obj.Links = append(obj.Links,
-			LinkV1{
-				Href:   baseUrl + "/perfum/" + result,
-				Rel:    "PerfumInfo",
-				Method: "GET",
-			},
-		)
-	}
-
-	return obj, nil
-}
-
-func (obj *PerfumsSearchResultV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	return obj, nil
-}
-
-func (obj *PerfumsSearchResultV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return 0, err
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "perfum_search_count", &search); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *PerfumsSearchResultV1) ExtraCount(uids []string) (int64, error) {
-	return 0, nil
-}
-
-func (obj *PerfumsSearchResultV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-// UserReq
-type UserReq struct {
-	UserId string `json:"user_id"`
-}
-
-// LoginReq ...
-type LoginReq struct {
-	AuthcodeString string `json:"auth_code" binding:"required"`
-}
-
-// LoginResp represents an authenticated response.
-type LoginResp struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	UserId       string `json:"user_id"`
-}
-
-// UserResp ...
-type UserResp struct {
-	UserId    string   `json:"user_id"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
-	Links     []LinkV1 `json:"links"`
-}
-
-// IdTokenClaims ...
-type IdTokenClaims struct {
-	Iss    string  `json:"iss"`
-	Sub    string  `json:"sub"`
-	Aud    string  `json:"aud"`
-	Iat    float64 `json:"iat"`
-	Exp    float64 `json:"exp"`
-	UserId string  `json:"user_id"`
-}
-
-//BrandsSearchResultV1
-type BrandsSearchResultV1 struct {
-	ObjList []BrandV1 `db:"-" json:"brands_list"`
-	Total   int64     `json:"total"`
-	Offset  int64     `json:"offset"`
-	Amount  int64     `json:"amount"`
-}
-
-func NewBrandsSearchResultFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &BrandsSearchResultV1{ObjList: make([]BrandV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *BrandsSearchResultV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return nil, err
-	}
-	if search.BrandUid == "" && search.Brand == "" {
-		// return empty object
-		return obj, nil
-	}
-	search.Order = "brands." + search.BrandsName
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "brands_search", &search); err != nil {
-		return nil, err
-	}
-
-	fmt.Println("================================= Query begin =======================================")
-	fmt.Println(query.String())
-	fmt.Println("================================= Query end =========================================")
-
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		obj.ObjList[i].PerfumsCount, _ = GetPerfumsCount("brands", obj.ObjList[i].Uuid)
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/brand/" + obj.ObjList[i].Uuid,
-				Rel:    "BrandInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/brand/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "BrandPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *BrandsSearchResultV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	return obj, nil
-}
-
-func (obj *BrandsSearchResultV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return 0, err
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "brands_search_count", &search); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *BrandsSearchResultV1) ExtraCount(uids []string) (int64, error) {
-	return 0, nil
-}
-
-func (obj *BrandsSearchResultV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-//ComponentsSearchResultV1
-type ComponentsSearchResultV1 struct {
-	ObjList []ComponentV1 `db:"-" json:"components"`
-	Total   int64         `db:"-" json:"total"`
-	Offset  int64         `db:"-" json:"offset"`
-	Amount  int64         `db:"-" json:"amount"`
-}
-
-func NewComponentsSearchResultFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &ComponentsSearchResultV1{ObjList: make([]ComponentV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *ComponentsSearchResultV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return nil, err
-	}
-	if search.ComponentUid == "" && search.Component == "" {
-		// return empty object
-		return obj, nil
-	}
-	search.Order = "components." + search.ComponentsName
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "components_search", &search); err != nil {
-		return nil, err
-	}
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		obj.ObjList[i].PerfumsCount, _ = GetPerfumsCount("components", obj.ObjList[i].Uuid)
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/component/" + obj.ObjList[i].Uuid,
-				Rel:    "ComponentInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/component/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "ComponentPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *ComponentsSearchResultV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	return obj, nil
-}
-
-func (obj *ComponentsSearchResultV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return 0, err
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "components_search_count", &search); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *ComponentsSearchResultV1) ExtraCount(uids []string) (int64, error) {
-	return 0, nil
-}
-
-func (obj *ComponentsSearchResultV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-// CountriesSearchResultV1
-type CountriesSearchResultV1 struct {
-	ObjList []CountryV1 `db:"-" json:"countries_list"`
-	Total   int64       `db:"-" json:"total"`
-	Offset  int64       `db:"-" json:"offset"`
-	Amount  int64       `db:"-" json:"amount"`
-}
-
-func NewCountriesSearchResultFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &CountriesSearchResultV1{ObjList: make([]CountryV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *CountriesSearchResultV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return nil, err
-	}
-	if search.CountryUid == "" && search.Country == "" {
-		// return empty object
-		return obj, nil
-	}
-	search.Order = "countries." + search.CountriesName
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "countries_search", &search); err != nil {
-		return nil, err
-	}
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		obj.ObjList[i].PerfumsCount, _ = GetPerfumsCount("countries", obj.ObjList[i].Uuid)
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/country/" + obj.ObjList[i].Uuid,
-				Rel:    "CountryInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/country/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "CountryPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *CountriesSearchResultV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	return obj, nil
-}
-
-func (obj *CountriesSearchResultV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return 0, err
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "countries_search_count", &search); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *CountriesSearchResultV1) ExtraCount(uids []string) (int64, error) {
-	return 0, nil
-}
-
-func (obj *CountriesSearchResultV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
-}
-
-// GroupsSearchResultV1
-type GroupsSearchResultV1 struct {
-	ObjList []GroupV1 `db:"-" json:"groups_list"`
-	Total   int64     `db:"-" json:"total"`
-	Offset  int64     `db:"-" json:"offset"`
-	Amount  int64     `db:"-" json:"amount"`
-}
-
-func NewGroupsSearchResultFactory(version string) Objecter {
-	switch version {
-	case "v1":
-		return &GroupsSearchResultV1{ObjList: make([]GroupV1, 0)}
-	}
-
-	return nil
-}
-
-func (obj *GroupsSearchResultV1) MakeObj(pParams interface{}) (Objecter, error) {
-	if pParams == nil {
-		return nil, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return nil, err
-	}
-	if search.GroupUid == "" && search.Group == "" {
-		// return empty object
-		return obj, nil
-	}
-	search.Order = "groups." + search.GroupsName
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "groups_search", &search); err != nil {
-		return nil, err
-	}
-	if _, err = dbmap.Select(&obj.ObjList, query.String()); err != nil {
-		return nil, err
-	}
-
-	obj.Total = params.Total
-	obj.Offset = params.Base.Offset.Int64
-	obj.Amount = int64(len(obj.ObjList))
-
-	for i := 0; i < len(obj.ObjList); i++ {
-		obj.ObjList[i].PerfumsCount, _ = GetPerfumsCount("groups", obj.ObjList[i].Uuid)
-		obj.ObjList[i].Links = []LinkV1{
-			LinkV1{
-				Href:   baseUrl + "/group/" + obj.ObjList[i].Uuid,
-				Rel:    "GroupInfo",
-				Method: "GET",
-			},
-			LinkV1{
-				Href:   baseUrl + "/group/" + obj.ObjList[i].Uuid + "/perfums",
-				Rel:    "GroupPerfums",
-				Method: "GET",
-			},
-		}
-
-		if obj.ObjList[i].ImageId.Valid {
-			obj.ObjList[i].SmallImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/small"
-			obj.ObjList[i].LargeImgUrl = baseUrl + "/image/" + obj.ObjList[i].ImageId.String + "/large"
-		}
-	}
-
-	return obj, nil
-}
-
-func (obj *GroupsSearchResultV1) MakeExtraObj(params *MakeObjParams, uids []string) (Objecter, error) {
-	return obj, nil
-}
-
-func (obj *GroupsSearchResultV1) Count(pParams interface{}) (int64, error) {
-	if pParams == nil {
-		return 0, errors.New("invalid args")
-	}
-
-	params := pParams.(*SearchParams)
-
-	search := NewSearchQueryTemplateParams()
-	if err := search.ParseSearchParams(params); err != nil {
-		return 0, err
-	}
-
-	query := bytes.NewBufferString("")
-	if err := tmpl.ExecuteTemplate(query, "groups_search_count", &search); err != nil {
-		return 0, err
-	}
-
-	count, err := dbmap.SelectInt(query.String())
-	if err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-func (obj *GroupsSearchResultV1) ExtraCount(uids []string) (int64, error) {
-	return 0, nil
-}
-
-func (obj *GroupsSearchResultV1) Json(w http.ResponseWriter, status int) error {
-	render := render.New()
-	return render.JSON(w, status, obj)
+// IdTokenClaims ...
+type IdTokenClaims struct {
+	Iss    string  `json:"iss"`
+	Sub    string  `json:"sub"`
+	Aud    string  `json:"aud"`
+	Iat    float64 `json:"iat"`
+	Exp    float64 `json:"exp"`
+	UserId string  `json:"user_id"`
 }
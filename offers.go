@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp/v3"
+	"github.com/unrolled/render"
+)
+
+var (
+	currencyRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+	uuidRegex     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	// offerCsvColumns lists the columns of an offer feed, url and last_seen
+	// are optional. last_seen is unix time or RFC3339, empty means now.
+	offerCsvColumns = []string{"perfum_id", "shop_id", "price", "currency", "volume", "url", "last_seen"}
+)
+
+// OfferDB ...
+type OfferDB struct {
+	Id           int64          `db:"id"`
+	Uuid         string         `db:"uuid"`
+	PerfumInfoId int64          `db:"parfum_info_id"`
+	ShopId       int64          `db:"shop_id"`
+	Price        float64        `db:"price"`
+	Currency     string         `db:"currency"`
+	Volume       float64        `db:"volume"`
+	Url          sql.NullString `db:"url"`
+	LastSeenAt   int64          `db:"last_seen_at"`
+}
+
+// OfferV1 ...
+type OfferV1 struct {
+	Uuid       string   `db:"uuid" json:"id"`
+	ShopUuid   string   `db:"shop_uuid" json:"shop_id"`
+	ShopName   string   `db:"shop_name" json:"shop_name"`
+	Price      float64  `db:"price" json:"price"`
+	Currency   string   `db:"currency" json:"currency"`
+	Volume     float64  `db:"volume" json:"volume"`
+	Url        string   `db:"url" json:"url"`
+	LastSeenAt int64    `db:"last_seen_at" json:"last_seen_at"`
+	Links      []LinkV1 `db:"-" json:"links"`
+}
+
+// OffersV1 ...
+type OffersV1 struct {
+	ObjList []OfferV1 `db:"-" json:"offers_list"`
+	Total   int64     `db:"-" json:"total"`
+	Offset  int64     `db:"-" json:"offset"`
+	Amount  int64     `db:"-" json:"amount"`
+}
+
+func NewOffersFactory(version string) Objecter {
+	switch version {
+	case "v1":
+		return &OffersV1{ObjList: make([]OfferV1, 0)}
+	}
+
+	return nil
+}
+
+// MakeObj selects offers of the perfum params.Id, cheapest first
+func (obj *OffersV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+	if params.Id == "" {
+		return nil, errors.New("invalid args")
+	}
+
+	lf := getNameFields(params.Base.Lang.String)
+	offset := int64(DEFAULT_OFFSET)
+	if params.Base.Offset.Valid {
+		offset = params.Base.Offset.Int64
+	}
+	limit := config.Pagination.DefaultLimit
+	if params.Base.Limit.Valid {
+		limit = params.Base.Limit.Int64
+	}
+
+	list, err := store.Offers.List(ctx, lf, params.Id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	obj.ObjList = list
+
+	obj.Total = params.Total
+	obj.Offset = offset
+	obj.Amount = int64(len(obj.ObjList))
+
+	for i := range obj.ObjList {
+		obj.ObjList[i].Links = shopEntity.Links(obj.ObjList[i].ShopUuid)
+	}
+
+	return obj, nil
+}
+
+func (obj *OffersV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+func (obj *OffersV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Offers.Count(ctx, nil)
+}
+
+// ExtraCount returns number of offers of the perfums with uids
+func (obj *OffersV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Offers.Count(ctx, uids)
+}
+
+func (obj *OffersV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
+
+// OfferImportError describes a rejected line of an offer feed
+type OfferImportError struct {
+	Line int
+	Err  error
+}
+
+func (e OfferImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// OfferImportResult ...
+type OfferImportResult struct {
+	Inserted int
+	Updated  int
+	Errors   []OfferImportError
+}
+
+// ImportOffersCSV reads an offer feed and upserts its rows in one transaction.
+// An offer is identified by perfum, shop and volume, so a feed seen again only
+// refreshes price, url and last seen time. Invalid rows are skipped and
+// reported in the result.
+func ImportOffersCSV(ctx context.Context, in io.Reader) (*OfferImportResult, error) {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := importOffersCSV(txOfferTable{tx}, in)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// offerTable resolves the references of imported offers and upserts them
+type offerTable interface {
+	// idByUuid returns the id of the row of table with uuid, 0 if not found
+	idByUuid(table, uuid string) (int64, error)
+	// upsertOffer returns true if a new offer is inserted
+	upsertOffer(offer *OfferDB) (bool, error)
+}
+
+// txOfferTable imports offers in a transaction, which the caller rolls back
+// on error
+type txOfferTable struct {
+	tx *gorp.Transaction
+}
+
+// importOffersCSV upserts offers of the feed in table
+func importOffersCSV(table offerTable, in io.Reader) (*OfferImportResult, error) {
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range offerCsvColumns[:5] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s is missing", name)
+		}
+	}
+
+	result := &OfferImportResult{}
+	line := 1
+	for {
+		record, err := reader.Read()
+		line++
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			result.Errors = append(result.Errors, OfferImportError{Line: line, Err: err})
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		offer, rowErr, dbErr := parseOfferRecord(table, field)
+		if dbErr != nil {
+			return nil, OfferImportError{Line: line, Err: dbErr}
+		} else if rowErr != nil {
+			result.Errors = append(result.Errors, OfferImportError{Line: line, Err: rowErr})
+			continue
+		}
+
+		inserted, err := table.upsertOffer(offer)
+		if err != nil {
+			return nil, OfferImportError{Line: line, Err: err}
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// parseOfferRecord validates the record and resolves perfum and shop ids.
+// Database failures are returned as dbErr since they abort the transaction.
+func parseOfferRecord(table offerTable, field func(string) string) (offer *OfferDB, rowErr error, dbErr error) {
+	offer = &OfferDB{}
+
+	var err error
+	if !uuidRegex.MatchString(field("perfum_id")) {
+		return nil, fmt.Errorf("invalid perfum_id %q", field("perfum_id")), nil
+	}
+	if !uuidRegex.MatchString(field("shop_id")) {
+		return nil, fmt.Errorf("invalid shop_id %q", field("shop_id")), nil
+	}
+	if offer.PerfumInfoId, dbErr = table.idByUuid("parfum_info", field("perfum_id")); dbErr != nil {
+		return nil, nil, dbErr
+	} else if offer.PerfumInfoId == 0 {
+		return nil, fmt.Errorf("perfum %q is not found", field("perfum_id")), nil
+	}
+	if offer.ShopId, dbErr = table.idByUuid(shopEntity.Table, field("shop_id")); dbErr != nil {
+		return nil, nil, dbErr
+	} else if offer.ShopId == 0 {
+		return nil, fmt.Errorf("shop %q is not found", field("shop_id")), nil
+	}
+
+	if offer.Price, err = strconv.ParseFloat(field("price"), 64); err != nil || offer.Price < 0 {
+		return nil, fmt.Errorf("invalid price %q", field("price")), nil
+	}
+	if offer.Volume, err = strconv.ParseFloat(field("volume"), 64); err != nil || offer.Volume <= 0 {
+		return nil, fmt.Errorf("invalid volume %q", field("volume")), nil
+	}
+	offer.Currency = strings.ToUpper(field("currency"))
+	if !currencyRegex.MatchString(offer.Currency) {
+		return nil, fmt.Errorf("invalid currency %q", field("currency")), nil
+	}
+	if url := field("url"); url != "" {
+		offer.Url = sql.NullString{String: url, Valid: true}
+	}
+
+	offer.LastSeenAt = time.Now().Unix()
+	if lastSeen := field("last_seen"); lastSeen != "" {
+		if offer.LastSeenAt, err = strconv.ParseInt(lastSeen, 10, 64); err != nil {
+			t, err := time.Parse(time.RFC3339, lastSeen)
+			if err != nil {
+				return nil, fmt.Errorf("invalid last_seen %q", lastSeen), nil
+			}
+			offer.LastSeenAt = t.Unix()
+		}
+	}
+
+	return offer, nil, nil
+}
+
+func (t txOfferTable) idByUuid(table, uuid string) (int64, error) {
+	return t.tx.SelectInt("SELECT id FROM "+table+" WHERE uuid=$1", uuid)
+}
+
+func (t txOfferTable) upsertOffer(offer *OfferDB) (bool, error) {
+	existing := OfferDB{}
+	err := t.tx.SelectOne(&existing, "SELECT * FROM offers WHERE parfum_info_id=$1 AND shop_id=$2 AND volume=$3",
+		offer.PerfumInfoId, offer.ShopId, offer.Volume)
+	if err == sql.ErrNoRows {
+		if offer.Uuid, err = NewUuid(); err != nil {
+			return false, err
+		}
+		return true, t.tx.Insert(offer)
+	} else if err != nil {
+		return false, err
+	}
+
+	offer.Id = existing.Id
+	offer.Uuid = existing.Uuid
+	_, err = t.tx.Update(offer)
+	return false, err
+}
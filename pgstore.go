@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NewPgStore returns the store of the PostgreSQL database of dbmap
+func NewPgStore() *Store {
+	return &Store{
+		Users:     pgUserRepository{},
+		Images:    pgImageRepository{},
+		Perfums:   pgPerfumRepository{},
+		Entities:  pgEntityRepository{},
+		Reviews:   pgReviewRepository{},
+		Favorites: pgFavoriteRepository{},
+		Offers:    pgOfferRepository{},
+		Catalogue: pgCatalogueRepository{},
+	}
+}
+
+// listPage orders rows of q by name and selects the page of lq
+func listPage(q *SelectQuery, lq ListQuery) *SelectQuery {
+	return q.OrderBy("name ASC").Page(lq.Offset, lq.Limit)
+}
+
+// readSelect selects the rows of the read only query name into holder within
+// a span, the query is run again on transient errors
+func readSelect(ctx context.Context, holder interface{}, name, query string, args ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, name, query)
+	err := retryRead(ctx, name, holder, func() error {
+		_, err := dbWithContext(ctx).Select(holder, query, args...)
+		return err
+	})
+	endSpan(span, err)
+	return err
+}
+
+// readSelectInt selects the single integer value of the read only query name
+// within a span
+func readSelectInt(ctx context.Context, name, query string, args ...interface{}) (int64, error) {
+	ctx, span := startQuerySpan(ctx, name, query)
+	var value int64
+	err := retryRead(ctx, name, nil, func() (err error) {
+		value, err = dbWithContext(ctx).SelectInt(query, args...)
+		return err
+	})
+	endSpan(span, err)
+	return value, err
+}
+
+// readSelectOne selects the single row of the read only query name into
+// holder within a span, found is false if there is none
+func readSelectOne(ctx context.Context, holder interface{}, name, query string, args ...interface{}) (found bool, err error) {
+	ctx, span := startQuerySpan(ctx, name, query)
+	err = retryRead(ctx, name, nil, func() error {
+		return dbWithContext(ctx).SelectOne(holder, query, args...)
+	})
+	if err == sql.ErrNoRows {
+		err = nil
+	} else {
+		found = err == nil
+	}
+	endSpan(span, err)
+	return found, err
+}
+
+type pgUserRepository struct{}
+
+func (r pgUserRepository) Get(ctx context.Context, userId string) (*UserDB, error) {
+	return r.selectOne(ctx, "user", "SELECT * FROM users WHERE user_id=$1", userId)
+}
+
+func (pgUserRepository) selectOne(ctx context.Context, name, query, arg string) (*UserDB, error) {
+	var user UserDB
+	if found, err := readSelectOne(ctx, &user, name, query, arg); !found {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r pgUserRepository) GetByAccessToken(ctx context.Context, token string) (*UserDB, error) {
+	return r.selectOne(ctx, "user_by_access_token", "SELECT * FROM users WHERE access_token=$1", token)
+}
+
+func (r pgUserRepository) GetByRefreshToken(ctx context.Context, token string) (*UserDB, error) {
+	return r.selectOne(ctx, "user_by_refresh_token", "SELECT * FROM users WHERE refresh_token=$1", token)
+}
+
+func (pgUserRepository) Insert(ctx context.Context, user *UserDB) error {
+	return dbWithContext(ctx).Insert(user)
+}
+
+func (pgUserRepository) Update(ctx context.Context, user *UserDB) (bool, error) {
+	count, err := dbWithContext(ctx).Update(user)
+	return count > 0, err
+}
+
+func (pgUserRepository) Delete(ctx context.Context, user *UserDB) (bool, error) {
+	count, err := dbWithContext(ctx).Delete(user)
+	return count > 0, err
+}
+
+type pgImageRepository struct{}
+
+func (pgImageRepository) selectOne(ctx context.Context, name, query string, arg interface{}) (*ImageDB, error) {
+	image := ImageDB{}
+	if found, err := readSelectOne(ctx, &image, name, query, arg); !found {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r pgImageRepository) GetById(ctx context.Context, id int64) (*ImageDB, error) {
+	return r.selectOne(ctx, "image_by_id", "SELECT * FROM images WHERE id=$1", id)
+}
+
+func (r pgImageRepository) GetByUuid(ctx context.Context, uuid string) (*ImageDB, error) {
+	return r.selectOne(ctx, "image_by_uuid", "SELECT * FROM images WHERE uuid=$1", uuid)
+}
+
+type pgPerfumRepository struct{}
+
+func (pgPerfumRepository) IdByUuid(ctx context.Context, uuid string) (int64, error) {
+	return readSelectInt(ctx, "perfum_id_by_uuid", "SELECT id FROM parfum_info WHERE uuid=$1", uuid)
+}
+
+func (pgPerfumRepository) Count(ctx context.Context, filter PerfumFilter) (int64, error) {
+	uuids := idsCondition(filter.Uuids, "parfum_info.uuid")
+	e := filter.Entity
+	if e == nil {
+		return selectIntQuery(ctx, "select_count", selectCountQuery("parfum_info", "", uuids))
+	}
+
+	distinct := ""
+	if e.ForeignTable == "parfums" {
+		distinct = "parfum_info_id"
+		if !uuids.IsEmpty() {
+			uuids = Expr("parfum_info_id IN (SELECT parfum_info.id FROM parfum_info WHERE "+uuids.Sql+")", uuids.Args...)
+		}
+	}
+	entity := conditionSelectIdEqUuid(e.ForeignKey, e.Table, idsCondition([]string{filter.EntityUuid}, e.Table+".uuid"))
+	return selectIntQuery(ctx, "select_count", selectCountQuery(e.ForeignTable, distinct, And(entity, uuids)))
+}
+
+func (pgPerfumRepository) List(ctx context.Context, q ListQuery, filter PerfumFilter) ([]PerfumInfoV1, error) {
+	query := perfumInfoBaseQuery(q.Lang)
+	if e := filter.Entity; e != nil {
+		entity := idsCondition([]string{filter.EntityUuid}, e.Table+".uuid")
+		if e.ForeignTable == "parfums" {
+			conditionInnerJoinParfumsUuid(query, e, entity)
+		} else {
+			query.Where(conditionSelectIdEqUuid(e.ForeignKey, e.Table, entity))
+		}
+	}
+	query.Where(idsCondition(filter.Uuids, "parfum_info.uuid"))
+
+	list := []PerfumInfoV1{}
+	if err := selectQuery(ctx, &list, "perfum_info_base", listPage(query, q)); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (pgPerfumRepository) CountComposition(ctx context.Context, uuids []string) (int64, error) {
+	var cond SqlExpr
+	if len(uuids) > 0 {
+		cond = conditionSelectIdEqUuid("parfum_info_id", "parfum_info", idsCondition(uuids, "parfum_info.uuid"))
+	}
+	return selectIntQuery(ctx, "select_count", selectCountQuery("parfums", "", cond))
+}
+
+func (pgPerfumRepository) Composition(ctx context.Context, lang LangField, uuids []string) ([]PerfumCompositionDBRecordV1, error) {
+	query := selectPerfumsOnPerfumInfoUuidQuery(lang, idsCondition(uuids, "parfum_info.uuid"))
+
+	var records []PerfumCompositionDBRecordV1
+	if err := selectQuery(ctx, &records, "select_perfums_on_perfum_info_uuid", query); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (pgPerfumRepository) Search(ctx context.Context, params *SearchParams) ([]string, error) {
+	var results []string
+	if err := selectQuery(ctx, &results, "perfum_search", perfumSearchQuery(NewPerfumSearch(params))); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (pgPerfumRepository) SearchCount(ctx context.Context, params *SearchParams) (int64, error) {
+	return selectIntQuery(ctx, "perfum_search_count", perfumSearchCountQuery(NewPerfumSearch(params)))
+}
+
+// similarQuery selects perfums sharing at least one component with the
+// perfum $1 and what they share with it
+const similarQuery = "SELECT parfum_info.uuid AS info_uuid, shared.note_components, shared.components, " +
+	"CASE WHEN parfum_info.group_id=target.group_id THEN 1 ELSE 0 END AS same_group, " +
+	"CASE WHEN parfum_info.type_id=target.type_id THEN 1 ELSE 0 END AS same_type, " +
+	"CASE WHEN parfum_info.season_id=target.season_id THEN 1 ELSE 0 END AS same_season " +
+	"FROM parfum_info " +
+	"CROSS JOIN (SELECT id, group_id, type_id, season_id FROM parfum_info WHERE uuid=$1) AS target " +
+	"INNER JOIN (SELECT parfums.parfum_info_id, " +
+	"COUNT(DISTINCT (parfums.note_id, parfums.component_id)) FILTER (WHERE parfums.note_id=target_parfums.note_id) AS note_components, " +
+	"COUNT(DISTINCT parfums.component_id) AS components " +
+	"FROM parfums INNER JOIN (SELECT parfums.note_id, parfums.component_id FROM parfums " +
+	"INNER JOIN parfum_info ON parfums.parfum_info_id=parfum_info.id WHERE parfum_info.uuid=$1) AS target_parfums " +
+	"ON parfums.component_id=target_parfums.component_id GROUP BY parfums.parfum_info_id) AS shared " +
+	"ON shared.parfum_info_id=parfum_info.id " +
+	"WHERE parfum_info.id<>target.id"
+
+// similarityScoreQuery ranks the perfums of similarQuery by the weights of
+// similarity.go, the page is selected by $2 and $3
+var similarityScoreQuery = "SELECT shared_perfums.*, " +
+	strconv.Itoa(SIMILARITY_NOTE_COMPONENT_WEIGHT) + "*note_components+" +
+	strconv.Itoa(SIMILARITY_COMPONENT_WEIGHT) + "*components+" +
+	strconv.Itoa(SIMILARITY_GROUP_WEIGHT) + "*same_group+" +
+	strconv.Itoa(SIMILARITY_TYPE_WEIGHT) + "*same_type+" +
+	strconv.Itoa(SIMILARITY_SEASON_WEIGHT) + "*same_season AS score " +
+	"FROM (" + similarQuery + ") AS shared_perfums " +
+	"ORDER BY score DESC, info_uuid ASC OFFSET $2 LIMIT $3"
+
+func (pgPerfumRepository) Similar(ctx context.Context, uuid string, offset, limit int64) ([]similarityScoreRecord, error) {
+	var scores []similarityScoreRecord
+	if err := readSelect(ctx, &scores, "similarity_score", similarityScoreQuery, uuid, offset, limit); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (pgPerfumRepository) SimilarCount(ctx context.Context, uuid string) (int64, error) {
+	return readSelectInt(ctx, "similarity_count", "SELECT COUNT(*) FROM ("+similarQuery+") AS shared_perfums", uuid)
+}
+
+func (pgPerfumRepository) SharedComposition(ctx context.Context, lf LangField, uuid string, uuids []string) ([]PerfumCompositionDBRecordV1, error) {
+	query := "SELECT parfum_info.uuid AS info_uuid, notes.uuid AS note_uuid, notes." + lf.NotesName + " AS note_name, " +
+		"components.uuid AS component_uuid, components." + lf.ComponentsName + " AS component_name " +
+		"FROM parfums INNER JOIN parfum_info ON parfums.parfum_info_id=parfum_info.id " +
+		"INNER JOIN notes ON parfums.note_id=notes.id INNER JOIN components ON parfums.component_id=components.id " +
+		"INNER JOIN (SELECT parfums.note_id, parfums.component_id FROM parfums " +
+		"INNER JOIN parfum_info ON parfums.parfum_info_id=parfum_info.id WHERE parfum_info.uuid=$1) AS target_parfums " +
+		"ON parfums.note_id=target_parfums.note_id AND parfums.component_id=target_parfums.component_id " +
+		"WHERE parfum_info.uuid=ANY($2) ORDER BY info_uuid ASC, note_name ASC, component_name ASC"
+
+	var records []PerfumCompositionDBRecordV1
+	if err := readSelect(ctx, &records, "shared_composition", query, uuid, pq.Array(uuids)); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// recommendQuery returns the scoring query of the perfums not liked and not
+// reviewed by user, filtered by params, and its args
+func recommendQuery(params *RecommendParams) (string, []interface{}) {
+	args := []interface{}{params.UserId}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	liked := "SELECT parfum_info_id FROM favorites WHERE user_id=$1"
+	query := "SELECT parfum_info.uuid AS info_uuid, " +
+		"SUM(" + strconv.Itoa(RECOMMEND_NOTE_COMPONENT_WEIGHT) + "*COALESCE(note_profile.likes, 0)+" +
+		strconv.Itoa(RECOMMEND_COMPONENT_WEIGHT) + "*component_profile.likes) AS score, " +
+		"COUNT(DISTINCT parfums.component_id) AS matched_components " +
+		"FROM parfums INNER JOIN parfum_info ON parfums.parfum_info_id=parfum_info.id " +
+		"INNER JOIN (SELECT component_id, COUNT(DISTINCT parfum_info_id) AS likes FROM parfums " +
+		"WHERE parfum_info_id IN (" + liked + ") GROUP BY component_id) AS component_profile " +
+		"ON parfums.component_id=component_profile.component_id " +
+		"LEFT JOIN (SELECT note_id, component_id, COUNT(DISTINCT parfum_info_id) AS likes FROM parfums " +
+		"WHERE parfum_info_id IN (" + liked + ") GROUP BY note_id, component_id) AS note_profile " +
+		"ON parfums.note_id=note_profile.note_id AND parfums.component_id=note_profile.component_id " +
+		"WHERE parfum_info.id NOT IN (" + liked + ") " +
+		"AND parfum_info.id NOT IN (SELECT parfum_info_id FROM reviews WHERE user_id=$1)"
+
+	if params.GenderUid.Valid {
+		query += " AND parfum_info.gender_id IN (SELECT id FROM gender WHERE uuid=ANY(" + arg(pq.Array(params.GenderUid.String)) + "))"
+	}
+	if params.SeasonUid.Valid {
+		query += " AND parfum_info.season_id IN (SELECT id FROM seasons WHERE uuid=ANY(" + arg(pq.Array(params.SeasonUid.String)) + "))"
+	}
+	if params.ExcludeBrandUid.Valid {
+		query += " AND parfum_info.brand_id NOT IN (SELECT id FROM brands WHERE uuid=ANY(" + arg(pq.Array(params.ExcludeBrandUid.String)) + "))"
+	}
+	query += " GROUP BY parfum_info.uuid"
+
+	return query, args
+}
+
+func (pgPerfumRepository) Recommend(ctx context.Context, params *RecommendParams, offset, limit int64) ([]recommendScoreRecord, error) {
+	query, args := recommendQuery(params)
+	args = append(args, offset, limit)
+	query += " ORDER BY score DESC, info_uuid ASC OFFSET $" + strconv.Itoa(len(args)-1) + " LIMIT $" + strconv.Itoa(len(args))
+
+	var scores []recommendScoreRecord
+	if err := readSelect(ctx, &scores, "recommend", query, args...); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (pgPerfumRepository) RecommendCount(ctx context.Context, params *RecommendParams) (int64, error) {
+	query, args := recommendQuery(params)
+	return readSelectInt(ctx, "recommend_count", "SELECT COUNT(*) FROM ("+query+") AS recommendations", args...)
+}
+
+type pgEntityRepository struct{}
+
+// entitySearchCondition returns the uuid and name conditions of the search
+// request, empty if it has none
+func entitySearchCondition(e *EntityDesc, params *SearchParams, lf LangField) SqlExpr {
+	return And(
+		uidCondition(params.EntityUid.String, e.Table+".uuid"),
+		substringCondition(params.Entity.String, e.Table+"."+e.NameField(lf), params.CompareMode, params.CaseSensitive),
+	)
+}
+
+func (pgEntityRepository) Count(ctx context.Context, e *EntityDesc, filter EntityFilter) (int64, error) {
+	cond := idsCondition(filter.Uuids, e.Table+".uuid")
+	if params := filter.Search; params != nil {
+		if cond = entitySearchCondition(e, params, getNameFields(params.Base.Lang.String)); cond.IsEmpty() {
+			return 0, nil
+		}
+	}
+	return selectIntQuery(ctx, "select_count", selectCountQuery(e.Table, "", cond))
+}
+
+func (pgEntityRepository) List(ctx context.Context, e *EntityDesc, q ListQuery, filter EntityFilter) ([]EntityV1, error) {
+	cond := idsCondition(filter.Uuids, e.Table+".uuid")
+	list := []EntityV1{}
+	if filter.Search != nil {
+		if cond = entitySearchCondition(e, filter.Search, q.Lang); cond.IsEmpty() {
+			return list, nil
+		}
+	}
+
+	if err := selectQuery(ctx, &list, "select_entity", listPage(selectEntityQuery(e, q.Lang).Where(cond), q)); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (pgEntityRepository) PerfumsCount(ctx context.Context, e *EntityDesc) (map[string]int64, error) {
+	type DbItem struct {
+		Id  string `db:"id"`
+		Uid string `db:"uid"`
+	}
+
+	countQuery := "SELECT COUNT(*) FROM " + e.ForeignTable + " WHERE " + e.ForeignKey + "=$1"
+	if e.ForeignTable == "parfums" {
+		countQuery = "SELECT COUNT(DISTINCT parfum_info_id) FROM parfums WHERE " + e.ForeignKey + "=$1"
+	}
+
+	var items []DbItem
+	if err := readSelect(ctx, &items, "entity_ids", "SELECT "+e.Table+".id AS id, "+e.Table+".uuid AS uid FROM "+e.Table); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	for _, item := range items {
+		count, err := readSelectInt(ctx, "entity_perfums_count", countQuery, item.Id)
+		if err != nil {
+			return nil, err
+		}
+		counts[item.Uid] = count
+	}
+	return counts, nil
+}
+
+type pgReviewRepository struct{}
+
+func (pgReviewRepository) GetByUser(ctx context.Context, perfumInfoId int64, userId string) (*ReviewDB, error) {
+	var review ReviewDB
+	found, err := readSelectOne(ctx, &review, "review_by_user", "SELECT * FROM reviews WHERE parfum_info_id=$1 AND user_id=$2", perfumInfoId, userId)
+	if !found {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (pgReviewRepository) Insert(ctx context.Context, review *ReviewDB) error {
+	if err := dbWithContext(ctx).Insert(review); err != nil {
+		// unique (parfum_info_id, user_id) protects from concurrent inserts
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrReviewExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (pgReviewRepository) Update(ctx context.Context, review *ReviewDB) (bool, error) {
+	count, err := dbWithContext(ctx).Update(review)
+	return count > 0, err
+}
+
+func (pgReviewRepository) Delete(ctx context.Context, review *ReviewDB) (bool, error) {
+	count, err := dbWithContext(ctx).Delete(review)
+	return count > 0, err
+}
+
+func (pgReviewRepository) Count(ctx context.Context, uuids []string) (int64, error) {
+	if len(uuids) == 0 {
+		return readSelectInt(ctx, "review_count", "SELECT COUNT(*) FROM reviews")
+	}
+	return readSelectInt(ctx, "review_count", "SELECT COUNT(*) FROM reviews INNER JOIN parfum_info ON reviews.parfum_info_id=parfum_info.id WHERE parfum_info.uuid=ANY($1)", pq.Array(uuids))
+}
+
+func (pgReviewRepository) List(ctx context.Context, uuid string, offset, limit int64) ([]ReviewV1, error) {
+	query := "SELECT reviews.uuid, parfum_info.uuid AS info_uuid, reviews.user_id, reviews.score, " +
+		"COALESCE(reviews.longevity, 0) AS longevity, COALESCE(reviews.sillage, 0) AS sillage, " +
+		"COALESCE(reviews.text, '') AS text, reviews.created_at, reviews.updated_at " +
+		"FROM reviews INNER JOIN parfum_info ON reviews.parfum_info_id=parfum_info.id " +
+		"WHERE parfum_info.uuid=$1 ORDER BY reviews.created_at DESC OFFSET $2 LIMIT $3"
+
+	list := []ReviewV1{}
+	if err := readSelect(ctx, &list, "review_list", query, uuid, offset, limit); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type pgFavoriteRepository struct{}
+
+const favoritesInsertQuery = "INSERT INTO favorites (user_id, parfum_info_id, created_at) " +
+	"SELECT $1, parfum_info.id, $2 FROM parfum_info WHERE parfum_info.uuid=ANY($3) " +
+	"ON CONFLICT (user_id, parfum_info_id) DO NOTHING"
+
+func (pgFavoriteRepository) Add(ctx context.Context, userId string, uuids []string) (int64, error) {
+	res, err := dbWithContext(ctx).Exec(favoritesInsertQuery, userId, time.Now().Unix(), pq.Array(uuids))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (pgFavoriteRepository) Replace(ctx context.Context, userId string, uuids []string) error {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	exec := tx.WithContext(ctx)
+	if _, err := exec.Exec("DELETE FROM favorites WHERE user_id=$1 AND parfum_info_id NOT IN "+
+		"(SELECT id FROM parfum_info WHERE uuid=ANY($2))", userId, pq.Array(uuids)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(uuids) > 0 {
+		if _, err := exec.Exec(favoritesInsertQuery, userId, time.Now().Unix(), pq.Array(uuids)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (pgFavoriteRepository) Remove(ctx context.Context, userId string, uuids []string) (int64, error) {
+	query := "DELETE FROM favorites WHERE user_id=$1"
+	args := []interface{}{userId}
+	if len(uuids) > 0 {
+		query += " AND parfum_info_id IN (SELECT id FROM parfum_info WHERE uuid=ANY($2))"
+		args = append(args, pq.Array(uuids))
+	}
+	res, err := dbWithContext(ctx).Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (pgFavoriteRepository) Count(ctx context.Context, userIds []string) (int64, error) {
+	if len(userIds) == 0 {
+		return readSelectInt(ctx, "favorite_count", "SELECT COUNT(*) FROM favorites")
+	}
+	return readSelectInt(ctx, "favorite_count", "SELECT COUNT(*) FROM favorites WHERE user_id=ANY($1)", pq.Array(userIds))
+}
+
+func (pgFavoriteRepository) List(ctx context.Context, userId string, offset, limit int64) ([]string, error) {
+	var uuids []string
+	if err := readSelect(ctx, &uuids, "favorite_list", "SELECT parfum_info.uuid FROM favorites "+
+		"INNER JOIN parfum_info ON favorites.parfum_info_id=parfum_info.id WHERE favorites.user_id=$1 "+
+		"ORDER BY favorites.created_at DESC, parfum_info.uuid ASC OFFSET $2 LIMIT $3", userId, offset, limit); err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+type pgOfferRepository struct{}
+
+func (pgOfferRepository) Count(ctx context.Context, uuids []string) (int64, error) {
+	if len(uuids) == 0 {
+		return readSelectInt(ctx, "offer_count", "SELECT COUNT(*) FROM offers")
+	}
+	return readSelectInt(ctx, "offer_count", "SELECT COUNT(*) FROM offers INNER JOIN parfum_info ON offers.parfum_info_id=parfum_info.id WHERE parfum_info.uuid=ANY($1)", pq.Array(uuids))
+}
+
+func (pgOfferRepository) List(ctx context.Context, lf LangField, uuid string, offset, limit int64) ([]OfferV1, error) {
+	query := "SELECT offers.uuid, shops.uuid AS shop_uuid, shops." + lf.ShopsName + " AS shop_name, " +
+		"offers.price, offers.currency, offers.volume, COALESCE(offers.url, '') AS url, offers.last_seen_at " +
+		"FROM offers INNER JOIN parfum_info ON offers.parfum_info_id=parfum_info.id " +
+		"INNER JOIN shops ON offers.shop_id=shops.id " +
+		"WHERE parfum_info.uuid=$1 ORDER BY offers.price ASC, offers.last_seen_at DESC OFFSET $2 LIMIT $3"
+
+	list := []OfferV1{}
+	if err := readSelect(ctx, &list, "offer_list", query, uuid, offset, limit); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type pgCatalogueRepository struct{}
+
+func (pgCatalogueRepository) Import(ctx context.Context, items []CatalogueItem) (*CatalogueImportResult, error) {
+	return ImportCatalogue(ctx, items)
+}
+
+func (pgCatalogueRepository) Export(ctx context.Context) ([]CatalogueItem, error) {
+	return ExportCatalogue(ctx)
+}
@@ -0,0 +1,267 @@
+package main
+
+// Queries of perfums and reference entities. Each builder is named after the
+// text/template it replaced, the snapshots of testdata/sql are recorded per
+// name.
+
+// uidCondition matches field with any of the normalized values
+func uidCondition(values []string, field string) SqlExpr {
+	var exprs []SqlExpr
+	for _, value := range values {
+		if normalized := regex.FindString(value); normalized != "" {
+			exprs = append(exprs, Expr(field+"=?", normalized))
+		}
+	}
+	return Or(exprs...)
+}
+
+// substringCondition matches field with any of the normalized values as a
+// whole (cm=st), a prefix (bw), a suffix (ew) or a substring, ignoring case
+// unless cs=y
+func substringCondition(values []string, field string, cm, cs NullString) SqlExpr {
+	fold := !cs.Valid || cs.String != "y"
+	var exprs []SqlExpr
+	for _, value := range values {
+		normalized := regex.FindString(value)
+		if normalized == "" {
+			continue
+		}
+
+		switch cm.String {
+		case "st": //strict
+		case "bw": //begin with
+			normalized = normalized + "%"
+		case "ew": //end with
+			normalized = "%" + normalized
+		default: //at any position in the string
+			normalized = "%" + normalized + "%"
+		}
+		if fold {
+			exprs = append(exprs, Expr("LOWER("+field+") LIKE LOWER(?)", normalized))
+		} else {
+			exprs = append(exprs, Expr("("+field+") LIKE (?)", normalized))
+		}
+	}
+	return Or(exprs...)
+}
+
+// compareCondition compares field with any of values by op
+func compareCondition(values []int64, field, op string) SqlExpr {
+	var exprs []SqlExpr
+	for _, value := range values {
+		exprs = append(exprs, Expr(field+op+"?", value))
+	}
+	return Or(exprs...)
+}
+
+// idsCondition matches field with any of ids as they are
+func idsCondition(ids []string, field string) SqlExpr {
+	var exprs []SqlExpr
+	for _, id := range ids {
+		exprs = append(exprs, Expr(field+"=?", id))
+	}
+	return Or(exprs...)
+}
+
+// perfumInfoBaseQuery selects perfums with the names of their references and
+// review stats in the language lf
+func perfumInfoBaseQuery(lf LangField) *SelectQuery {
+	q := Select(
+		"parfum_info.id AS info_id",
+		"parfum_info.uuid AS info_uuid",
+		"parfum_info."+lf.PerfumInfo+" AS name",
+		"parfum_info.year AS info_year",
+		"descriptions.uuid AS description_uuid",
+		"descriptions."+lf.PerfumsDescription+" AS description",
+		"brands.uuid AS brand_uuid",
+		"brands."+lf.BrandsName+" AS brand_name",
+		"gender.uuid AS gender_uuid",
+		"gender."+lf.GenderName+" AS gender_name",
+		"groups.uuid AS group_uuid",
+		"groups."+lf.GroupsName+" AS group_name",
+		"countries.uuid AS country_uuid",
+		"countries."+lf.CountriesName+" AS country_name",
+		"seasons.uuid AS season_uuid",
+		"seasons."+lf.SeasonsName+" AS season_name",
+		"times_of_day.uuid AS tsod_uuid",
+		"times_of_day."+lf.TsodName+" AS tsod_name",
+		"types.uuid AS type_uuid",
+		"types."+lf.TypesName+" AS type_name",
+		"images.uuid AS img_uuid",
+		"stars.uuid AS stars_uuid",
+		"shops.uuid AS shop_uuid",
+		"COALESCE(reviews_stats.score_avg, 0) AS score_avg",
+		"COALESCE(reviews_stats.longevity_avg, 0) AS longevity_avg",
+		"COALESCE(reviews_stats.sillage_avg, 0) AS sillage_avg",
+		"COALESCE(reviews_stats.reviews_count, 0) AS reviews_count",
+	).From("parfum_info")
+	for _, ref := range []struct{ Table, ForeignKey string }{
+		{"descriptions", "description_id"},
+		{"brands", "brand_id"},
+		{"gender", "gender_id"},
+		{"groups", "group_id"},
+		{"countries", "country_id"},
+		{"types", "type_id"},
+		{"seasons", "season_id"},
+		{"times_of_day", "tsod_id"},
+		{"shops", "shop_id"},
+		{"images", "image_id"},
+		{"stars", "stars_id"},
+	} {
+		q.Join("LEFT JOIN", ref.Table, Expr("parfum_info."+ref.ForeignKey+"="+ref.Table+".id"))
+	}
+
+	stats := Select(
+		"parfum_info_id",
+		"AVG(score) AS score_avg",
+		"AVG(longevity) AS longevity_avg",
+		"AVG(sillage) AS sillage_avg",
+		"COUNT(*) AS reviews_count",
+	).From("reviews").GroupBy("parfum_info_id")
+	return q.JoinSub("LEFT JOIN", stats, "reviews_stats", Expr("reviews_stats.parfum_info_id=parfum_info.id"))
+}
+
+// conditionSelectIdEqUuid matches the reference field with the id of the
+// row of table matching cond
+func conditionSelectIdEqUuid(field, table string, cond SqlExpr) SqlExpr {
+	return Expr(field+"=(SELECT "+table+".id FROM "+table+" WHERE ("+cond.Sql+"))", cond.Args...)
+}
+
+// conditionInnerJoinParfumsUuid joins to q, selecting from parfum_info, the
+// perfums whose composition references the item of e matching cond
+func conditionInnerJoinParfumsUuid(q *SelectQuery, e *EntityDesc, cond SqlExpr) *SelectQuery {
+	ids := Select("parfum_info_id").Distinct().From("parfums").
+		Where(Expr(e.ForeignKey+"=(SELECT id FROM "+e.Table+" WHERE ("+cond.Sql+"))", cond.Args...))
+	perfums := Select("parfum_info.uuid AS info_uuid").FromSub(ids, "parfum_ids").
+		Join("INNER JOIN", "parfum_info", Expr("parfum_ids.parfum_info_id=parfum_info.id"))
+	return q.JoinSub("INNER JOIN", perfums, "perfums_entity", Expr("parfum_info.uuid=perfums_entity.info_uuid"))
+}
+
+// selectEntityQuery selects id, name in the language lf, uuid and image
+// uuid if any of the items of e
+func selectEntityQuery(e *EntityDesc, lf LangField) *SelectQuery {
+	columns := []string{e.Table + ".id", e.Table + "." + e.NameField(lf) + " AS name", e.Table + ".uuid AS entity_uuid"}
+	if !e.HasImage {
+		return Select(columns...).From(e.Table)
+	}
+	return Select(append(columns, "images.uuid AS img_uuid")...).From(e.Table).
+		Join("LEFT OUTER JOIN", "images", Expr(e.Table+".image_id=images.id"))
+}
+
+// selectPerfumsOnPerfumInfoUuidQuery selects the composition records of the
+// perfums matching cond with names in the language lf
+func selectPerfumsOnPerfumInfoUuidQuery(lf LangField, cond SqlExpr) *SelectQuery {
+	perfums := Select("parfum_info.id", "parfum_info.uuid AS info_uuid").From("parfum_info").Where(cond)
+	return Select(
+		"parfums.id AS perfum_id",
+		"parfums.uuid AS perfum_uuid",
+		"notes.uuid AS note_uuid",
+		"notes."+lf.NotesName+" AS note_name",
+		"components.uuid AS component_uuid",
+		"components."+lf.ComponentsName+" AS component_name",
+		"perfum_info.info_uuid",
+	).From("parfums").
+		Join("INNER JOIN", "notes", Expr("parfums.note_id=notes.id")).
+		Join("INNER JOIN", "components", Expr("parfums.component_id=components.id")).
+		JoinSub("INNER JOIN", perfums, "perfum_info", Expr("parfums.parfum_info_id=perfum_info.id")).
+		OrderBy("info_uuid ASC", "note_name ASC", "component_name ASC")
+}
+
+// selectCountQuery counts the rows of table matching cond, or the distinct
+// values of distinctField if it is not empty
+func selectCountQuery(table, distinctField string, cond SqlExpr) *SelectQuery {
+	count := "COUNT(*)"
+	if distinctField != "" {
+		count = "COUNT(DISTINCT(" + distinctField + "))"
+	}
+	return Select(count).From(table).Where(cond)
+}
+
+// PerfumSearch holds the conditions of a perfum search: on parfum_info and
+// its references, and on the composition records
+type PerfumSearch struct {
+	ListQuery
+	// joins are the references of parfum_info used by conditions
+	joins      []string
+	perfum     []SqlExpr
+	notes      bool
+	components bool
+	records    []SqlExpr
+}
+
+// NewPerfumSearch returns the conditions of the search request params
+func NewPerfumSearch(params *SearchParams) *PerfumSearch {
+	s := &PerfumSearch{ListQuery: NewListQuery(&params.Base)}
+	lf, cm, cs := s.Lang, params.CompareMode, params.CaseSensitive
+
+	s.perfum = append(s.perfum,
+		uidCondition(params.InfoUid.String, "parfum_info.uuid"),
+		substringCondition(params.Name.String, "parfum_info."+lf.PerfumInfo, cm, cs),
+		compareCondition(params.YearFrom.Int64, "parfum_info.year", ">="),
+		compareCondition(params.YearTo.Int64, "parfum_info.year", "<="),
+	)
+	ref := func(table, foreignKey, name string, uids, names NullSliceString) {
+		uid, substring := uidCondition(uids.String, table+".uuid"), substringCondition(names.String, table+"."+name, cm, cs)
+		if !uid.IsEmpty() || !substring.IsEmpty() {
+			s.joins = append(s.joins, table+" ON parfum_info."+foreignKey+"="+table+".id")
+			s.perfum = append(s.perfum, uid, substring)
+		}
+	}
+	ref("descriptions", "description_id", lf.PerfumsDescription, params.DescUid, params.Desc)
+	ref("brands", "brand_id", lf.BrandsName, params.BrandUid, params.Brand)
+	ref("gender", "gender_id", lf.GenderName, params.GenderUid, params.Gender)
+	ref("groups", "group_id", lf.GroupsName, params.GroupUid, params.Group)
+	ref("countries", "country_id", lf.CountriesName, params.CountryUid, params.Country)
+	ref("seasons", "season_id", lf.SeasonsName, params.SeasonUid, params.Season)
+	ref("times_of_day", "tsod_id", lf.TsodName, params.TsodUid, params.Tsod)
+	ref("types", "type_id", lf.TypesName, params.TypeUid, params.Type)
+
+	notes := []SqlExpr{uidCondition(params.NoteUid.String, "notes.uuid"), substringCondition(params.Note.String, "notes."+lf.NotesName, cm, cs)}
+	components := []SqlExpr{uidCondition(params.ComponentUid.String, "components.uuid"), substringCondition(params.Component.String, "components."+lf.ComponentsName, cm, cs)}
+	s.notes = !And(notes...).IsEmpty()
+	s.components = !And(components...).IsEmpty()
+	s.records = append(append(notes, components...), uidCondition(params.PerfumUid.String, "parfums.uuid"))
+
+	return s
+}
+
+// perfumInfo selects columns of the perfums matching the conditions on
+// parfum_info and its references
+func (s *PerfumSearch) perfumInfo(columns ...string) *SelectQuery {
+	q := Select(columns...).From("parfum_info")
+	for _, join := range s.joins {
+		q.joins = append(q.joins, Expr("LEFT JOIN "+join))
+	}
+	for _, cond := range s.perfum {
+		q.Where(cond)
+	}
+	return q
+}
+
+// composition selects column of the composition records matching the
+// conditions, joined with the perfums matching theirs
+func (s *PerfumSearch) composition(column string) *SelectQuery {
+	q := Select(column).From("parfums")
+	if s.notes {
+		q.Join("INNER JOIN", "notes", Expr("parfums.note_id=notes.id"))
+	}
+	if s.components {
+		q.Join("INNER JOIN", "components", Expr("parfums.component_id=components.id"))
+	}
+	q.JoinSub("INNER JOIN", s.perfumInfo("parfum_info.id", "parfum_info.uuid AS info_uuid"), "perfum_info",
+		Expr("parfums.parfum_info_id=perfum_info.id"))
+	for _, cond := range s.records {
+		q.Where(cond)
+	}
+	return q
+}
+
+// perfumSearchQuery selects the page of uuids of the perfums found
+func perfumSearchQuery(s *PerfumSearch) *SelectQuery {
+	return s.composition("perfum_info.info_uuid").Distinct().OrderBy("perfum_info.info_uuid ASC").Page(s.Offset, s.Limit)
+}
+
+// perfumSearchCountQuery counts the perfums found
+func perfumSearchCountQuery(s *PerfumSearch) *SelectQuery {
+	return s.composition("COUNT(DISTINCT(perfum_info.info_uuid))")
+}
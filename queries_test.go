@@ -0,0 +1,182 @@
+package main
+
+// Generated SQL is compared with the snapshots of testdata/sql, record them
+// after a change of queries with
+//
+//	go test -run TestQuerySnapshots -update ./...
+//
+// Search queries are built by concurrent requests, run these with the race
+// detector:
+//
+//	go test -race -run TestConcurrentSearchQueries ./...
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "record golden files and SQL snapshots")
+
+const (
+	SNAPSHOT_UUID       = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
+	SNAPSHOT_OTHER_UUID = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a02"
+)
+
+// withDefaultConfig sets the default config, which gives the page size of
+// queries, for the test
+func withDefaultConfig(t *testing.T) {
+	prev := config
+	t.Cleanup(func() { config = prev })
+	config = DefaultConfig()
+}
+
+func newTestSearchParams(url string) *SearchParams {
+	return NewSearchParams().Parse(httptest.NewRequest("GET", url, nil))
+}
+
+func newTestEntitySearchParams(e *EntityDesc, url string) *SearchParams {
+	r := httptest.NewRequest("GET", url, nil)
+	return NewSearchParams().Parse(r).ParseEntity(r, e.SearchKey)
+}
+
+// formatSnapshot returns the statement e with numbered placeholders followed
+// by the values of its args
+func formatSnapshot(e SqlExpr) string {
+	var b strings.Builder
+	b.WriteString(numberPlaceholders(e.Sql))
+	b.WriteString("\n")
+	for i, arg := range e.Args {
+		fmt.Fprintf(&b, "-- $%d = %#v\n", i+1, arg)
+	}
+	return b.String()
+}
+
+func checkSnapshot(t *testing.T, name string, e SqlExpr) {
+	t.Helper()
+
+	actual := formatSnapshot(e)
+	path := filepath.Join("testdata", "sql", name+".sql")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("snapshot %s is missing, record it with -update", path)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != actual {
+		t.Errorf("query differs from %s\nexpected:\n%s\nactual:\n%s", path, expected, actual)
+	}
+}
+
+// TestQuerySnapshots covers the queries of every former SQL template, named
+// after it
+func TestQuerySnapshots(t *testing.T) {
+	withDefaultConfig(t)
+	en, ru := NameFields["en"], NameFields["ru"]
+	page := ListQuery{Lang: en, Offset: 10, Limit: 20}
+	brand := idsCondition([]string{SNAPSHOT_UUID}, "brands.uuid")
+
+	cases := []struct {
+		Name string
+		Expr SqlExpr
+	}{
+		{"perfum_info_base", listPage(perfumInfoBaseQuery(en), page).Expr()},
+		{"perfum_info_base_uuids", listPage(perfumInfoBaseQuery(ru).Where(idsCondition([]string{SNAPSHOT_UUID, SNAPSHOT_OTHER_UUID}, "parfum_info.uuid")), page).Expr()},
+		{"condition_select_id_eq_uuid", conditionSelectIdEqUuid(brandEntity.ForeignKey, brandEntity.Table, brand)},
+		{"condition_innerjoin_parfums_uuid", listPage(conditionInnerJoinParfumsUuid(perfumInfoBaseQuery(en), noteEntity, idsCondition([]string{SNAPSHOT_UUID}, "notes.uuid")), page).Expr()},
+		{"select_entity", listPage(selectEntityQuery(brandEntity, en), page).Expr()},
+		{"select_entity_search", listPage(selectEntityQuery(noteEntity, ru).Where(entitySearchCondition(noteEntity, newTestEntitySearchParams(noteEntity, "/notes/find?note_id="+SNAPSHOT_UUID+"&note=Top&cm=bw"), ru)), page).Expr()},
+		{"select_perfums_on_perfum_info_uuid", selectPerfumsOnPerfumInfoUuidQuery(en, idsCondition([]string{SNAPSHOT_UUID}, "parfum_info.uuid")).Expr()},
+		{"select_count", selectCountQuery("parfum_info", "", SqlExpr{}).Expr()},
+		{"select_count_distinct", selectCountQuery("parfums", "parfum_info_id", conditionSelectIdEqUuid(noteEntity.ForeignKey, noteEntity.Table, idsCondition([]string{SNAPSHOT_UUID}, "notes.uuid"))).Expr()},
+		{"perfum_search", perfumSearchQuery(NewPerfumSearch(newTestSearchParams("/perfums/find?name=Shalimar&gender_id=" + SNAPSHOT_UUID + "&note=Heart&component=Rose&component=Iris&cm=ew&limit=5&offset=5"))).Expr()},
+		{"perfum_search_all", perfumSearchQuery(NewPerfumSearch(newTestSearchParams("/perfums/find"))).Expr()},
+		{"perfum_search_count", perfumSearchCountQuery(NewPerfumSearch(newTestSearchParams("/perfums/find?season=Spring&perfum_id=" + SNAPSHOT_UUID + "&cm=st"))).Expr()},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if n := strings.Count(c.Expr.Sql, "?"); n != len(c.Expr.Args) {
+				t.Errorf("%d placeholders for %d args", n, len(c.Expr.Args))
+			}
+			checkSnapshot(t, c.Name, c.Expr)
+		})
+	}
+}
+
+// searchQueryCase is a search url and the number of WHERE clauses its
+// perfum_search query has: one for perfum conditions and one for
+// composition conditions
+type searchQueryCase struct {
+	Url    string
+	Wheres int
+}
+
+var searchQueryCases = []searchQueryCase{
+	{"/perfums/find", 0},
+	{"/perfums/find?name=Shalimar", 1},
+	{"/perfums/find?brand=Guerlain&year_fr=1900&year_to=1950", 1},
+	{"/perfums/find?component=Rose", 1},
+	{"/perfums/find?note=Heart&component=Rose", 1},
+	{"/perfums/find?name=No&component=Rose&cm=bw", 2},
+	{"/perfums/find?country=France&season=Spring&note=Base&cs=y", 2},
+}
+
+func buildSearchQuery(url string) (string, []interface{}) {
+	return perfumSearchQuery(NewPerfumSearch(newTestSearchParams(url))).Build()
+}
+
+func TestConcurrentSearchQueries(t *testing.T) {
+	withDefaultConfig(t)
+
+	type built struct {
+		Sql  string
+		Args []interface{}
+	}
+	expected := make([]built, len(searchQueryCases))
+	for i, c := range searchQueryCases {
+		sql, args := buildSearchQuery(c.Url)
+		expected[i] = built{sql, args}
+		if wheres := strings.Count(sql, " WHERE "); wheres != c.Wheres {
+			t.Errorf("%s: query has %d WHERE, expected %d: %s", c.Url, wheres, c.Wheres, sql)
+		}
+		if strings.Contains(sql, " AND  WHERE ") || strings.Contains(sql, "perfum_info.id  AND ") {
+			t.Errorf("%s: misplaced AND: %s", c.Url, sql)
+		}
+	}
+
+	const workers, rounds = 16, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				i := (w + r) % len(searchQueryCases)
+				sql, args := buildSearchQuery(searchQueryCases[i].Url)
+				if sql != expected[i].Sql || !reflect.DeepEqual(args, expected[i].Args) {
+					t.Errorf("%s: concurrent query differs:\n%s %v\nexpected:\n%s %v", searchQueryCases[i].Url, sql, args, expected[i].Sql, expected[i].Args)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
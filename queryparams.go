@@ -8,7 +8,6 @@ import (
 
 const (
 	DEFAULT_OFFSET = 0
-	DEFAULT_LIMIT  = 10
 )
 
 var (
@@ -68,6 +67,16 @@ func (ns *NullSliceString) append(param string) {
 	}
 }
 
+// validUuids reports whether every value of ns is a uuid
+func (ns *NullSliceString) validUuids() bool {
+	for _, value := range ns.String {
+		if !uuidRegex.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
 // QueryParams ...
 type BaseParams struct {
 	Limit   NullInt64
@@ -99,13 +108,17 @@ func (params *BaseParams) Parse(r *http.Request) *BaseParams {
 	params.Version = getApiVersion(r.URL.Path)
 	query := r.URL.Query()
 	if param := query.Get("limit"); param != "" {
-		if params.Limit.Int64, err = strconv.ParseInt(param, 0, 64); err == nil {
+		if params.Limit.Int64, err = strconv.ParseInt(param, 0, 64); err == nil && params.Limit.Int64 >= 0 {
 			params.Limit.Valid = true
+			if params.Limit.Int64 > config.Pagination.MaxLimit {
+				params.Limit.Int64 = config.Pagination.MaxLimit
+			}
 		}
 	}
 
 	if param := query.Get("offset"); param != "" {
-		if params.Offset.Int64, err = strconv.ParseInt(param, 0, 64); err == nil {
+		if offset, err := strconv.ParseInt(param, 0, 64); err == nil && offset >= 0 {
+			params.Offset.Int64 = offset
 			params.Offset.Valid = true
 		}
 	}
@@ -123,6 +136,12 @@ func (params *BaseParams) Parse(r *http.Request) *BaseParams {
 	return params
 }
 
+// ValidIds reports whether the id filters of params are uuids, others fail
+// the uuid columns they are compared with
+func (params *BaseParams) ValidIds() bool {
+	return params.Ids.validUuids()
+}
+
 type SearchParams struct {
 	Base          BaseParams
 	InfoUid       NullSliceString
@@ -150,6 +169,8 @@ type SearchParams struct {
 	Note          NullSliceString
 	ComponentUid  NullSliceString
 	Component     NullSliceString
+	EntityUid     NullSliceString
+	Entity        NullSliceString
 	CompareMode   NullString
 	CaseSensitive NullString
 	Total         int64
@@ -200,3 +221,54 @@ func (sp *SearchParams) Parse(r *http.Request) *SearchParams {
 
 	return sp
 }
+
+// ValidIds reports whether the id filters of sp are uuids
+func (sp *SearchParams) ValidIds() bool {
+	ids := []*NullSliceString{&sp.InfoUid, &sp.DescUid, &sp.BrandUid, &sp.GenderUid, &sp.GroupUid, &sp.CountryUid,
+		&sp.SeasonUid, &sp.TsodUid, &sp.TypeUid, &sp.PerfumUid, &sp.NoteUid, &sp.ComponentUid, &sp.EntityUid}
+	for _, ns := range ids {
+		if !ns.validUuids() {
+			return false
+		}
+	}
+	return sp.Base.ValidIds()
+}
+
+// ParseEntity reads search values of a registry entity: <key>_id and <key>
+func (sp *SearchParams) ParseEntity(r *http.Request, key string) *SearchParams {
+	query := r.URL.Query()
+
+	sp.EntityUid.append(query.Get(key + "_id"))
+	sp.Entity.append(query.Get(key))
+
+	return sp
+}
+
+type RecommendParams struct {
+	Base            BaseParams
+	UserId          string
+	GenderUid       NullSliceString
+	SeasonUid       NullSliceString
+	ExcludeBrandUid NullSliceString
+	Total           int64
+}
+
+func NewRecommendParams() *RecommendParams {
+	return &RecommendParams{}
+}
+
+func (rp *RecommendParams) Parse(r *http.Request) *RecommendParams {
+	rp.Base.Parse(r)
+	query := r.URL.Query()
+
+	rp.GenderUid.append(query.Get("gender_id"))
+	rp.SeasonUid.append(query.Get("season_id"))
+	rp.ExcludeBrandUid.append(query.Get("exclude_brand_id"))
+
+	return rp
+}
+
+// ValidIds reports whether the id filters of rp are uuids
+func (rp *RecommendParams) ValidIds() bool {
+	return rp.GenderUid.validUuids() && rp.SeasonUid.validUuids() && rp.ExcludeBrandUid.validUuids() && rp.Base.ValidIds()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/unrolled/render"
+)
+
+// QueryTimeout is the middleware bounding the time the database queries of
+// a request may take by the query timeout of its route
+type QueryTimeout struct {
+	*RouteNamer
+}
+
+// NewQueryTimeout returns the middleware resolving route names with namer
+func NewQueryTimeout(namer *RouteNamer) *QueryTimeout {
+	return &QueryTimeout{RouteNamer: namer}
+}
+
+func (q *QueryTimeout) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	timeout := config.Database.RouteQueryTimeout(q.RouteName(r))
+	if timeout <= 0 {
+		next(rw, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	next(rw, r.WithContext(ctx))
+}
+
+// isQueryCanceledError reports whether the server cancelled the statement,
+// on a cancel request or its statement_timeout
+func isQueryCanceledError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// queryErrorStatus returns the status of a request failed with err: 504 if
+// its queries ran out of the time of ctx, 503 if they were cancelled, by the
+// client going away or the server, and 500 otherwise
+func queryErrorStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil || isQueryCanceledError(err):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// renderServerError answers the request failed with err by the status of
+// queryErrorStatus
+func renderServerError(w http.ResponseWriter, r *http.Request, err error) {
+	status := queryErrorStatus(r.Context(), err)
+	if status != http.StatusInternalServerError {
+		appLog.Warn("request queries are cancelled", F("request_id", RequestId(r)), F("path", r.URL.Path), F("status", status), F("error", err))
+	}
+	render.New().JSON(w, status, map[string]string{"status": strings.ToLower(http.StatusText(status))})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/urfave/negroni"
+)
+
+func TestQueryErrorStatus(t *testing.T) {
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		Name   string
+		Ctx    context.Context
+		Err    error
+		Status int
+	}{
+		{"Error", context.Background(), errors.New("syntax error"), http.StatusInternalServerError},
+		{"NoError", context.Background(), nil, http.StatusInternalServerError},
+		{"DeadlineExceeded", context.Background(), context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"ExpiredContext", expired, &pq.Error{Code: "57014"}, http.StatusGatewayTimeout},
+		{"Canceled", context.Background(), context.Canceled, http.StatusServiceUnavailable},
+		{"CanceledContext", canceled, errors.New("driver: bad connection"), http.StatusServiceUnavailable},
+		{"QueryCanceled", context.Background(), &pq.Error{Code: "57014"}, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		if status := queryErrorStatus(c.Ctx, c.Err); status != c.Status {
+			t.Errorf("%s: status is %d, expected %d", c.Name, status, c.Status)
+		}
+	}
+}
+
+func TestQueryTimeoutMiddleware(t *testing.T) {
+	prev := config
+	t.Cleanup(func() { config = prev })
+	config = DefaultConfig()
+	config.Database.QueryTimeout = 0
+	config.Database.QueryTimeouts = map[string]time.Duration{"Slow": 10 * time.Millisecond}
+
+	// the handler waits for its query like one running longer than the
+	// timeout of its route
+	wait := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			renderServerError(w, r, r.Context().Err())
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	router := mux.NewRouter()
+	router.Path("/slow").Name("Slow").HandlerFunc(wait)
+	router.Path("/fast").Name("Fast").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Errorf("route without timeout has a deadline")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	namer := &RouteNamer{}
+	namer.AddRouter(router)
+	handler := negroni.New(NewQueryTimeout(namer), negroni.Wrap(router))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status is %d, expected %d", w.Code, http.StatusGatewayTimeout)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["status"] != "gateway timeout" {
+		t.Errorf("body is %s: %v", w.Body, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status is %d, expected %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrolled/render"
+)
+
+// RATE_LIMIT_SWEEP_TAKES is the number of takes between removals of full
+// buckets of the memory store
+const RATE_LIMIT_SWEEP_TAKES = 1024
+
+// RateLimitPolicy allows Limit requests per Period to each client, up to
+// Limit of them at once. Routes of a policy share the buckets of clients.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int64
+	Period time.Duration
+}
+
+// tokenInterval returns the time a token takes to be added to a bucket
+func (p *RateLimitPolicy) tokenInterval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// RateLimitResult is the bucket of a client after taking a token for its
+// request
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int64
+	// RetryAfter is the time until a token is available if the request is
+	// not allowed
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets of clients. The memory store limits
+// the clients of each instance of the service, a store shared by instances
+// implements it to limit them across the service.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of key of policy at now, if it has
+	// one
+	Take(ctx context.Context, key string, policy *RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// rateLimitStore keeps the buckets of RateLimited routes
+var rateLimitStore RateLimitStore = NewMemoryRateLimitStore()
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in memory, full buckets are
+// removed as they are the same as no bucket
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (m *MemoryRateLimitStore) Take(ctx context.Context, key string, policy *RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.takes++; m.takes >= RATE_LIMIT_SWEEP_TAKES {
+		m.takes = 0
+		for k, b := range m.buckets {
+			if !now.Before(b.fullAt) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	limit := float64(policy.Limit)
+	interval := policy.tokenInterval()
+	b, found := m.buckets[key]
+	if !found {
+		b = &tokenBucket{tokens: limit, updatedAt: now}
+		m.buckets[key] = b
+	} else if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(limit, b.tokens+float64(elapsed)/float64(interval))
+		b.updatedAt = now
+	}
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	result.Remaining = int64(b.tokens)
+	result.Reset = time.Duration((limit - b.tokens) * float64(interval))
+	b.fullAt = now.Add(result.Reset)
+	return result, nil
+}
+
+// isTrustedProxy reports whether ip is one of the proxies of c
+func (c RateLimitConfig) isTrustedProxy(ip net.IP) bool {
+	for _, proxy := range c.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client of r. X-Forwarded-For is only
+// read from trusted proxies, the client is the last address of it which is
+// not a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !config.RateLimit.isTrustedProxy(ip) {
+		return host
+	}
+
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(value, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(addr))
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := net.ParseIP(forwarded[i])
+		if addr == nil {
+			break
+		}
+		host = addr.String()
+		if !config.RateLimit.isTrustedProxy(addr) {
+			break
+		}
+	}
+	return host
+}
+
+// rateLimitKey returns the bucket of the client of r: the authenticated user
+// or else the client ip
+func rateLimitKey(policy *RateLimitPolicy, r *http.Request) string {
+	if user := CurrentUser(r); user != nil {
+		return policy.Name + ":user:" + user.UserId
+	}
+	return ipRateLimitKey(policy, r)
+}
+
+// ipRateLimitKey returns the bucket of the client ip of r
+func ipRateLimitKey(policy *RateLimitPolicy, r *http.Request) string {
+	return policy.Name + ":ip:" + clientIP(r)
+}
+
+// ceilSeconds returns d in whole seconds rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimited limits the requests of next to policy for each client and
+// answers the exceeding ones with 429. The bucket of the client is described
+// by RateLimit headers.
+func RateLimited(policy *RateLimitPolicy, next http.HandlerFunc) http.HandlerFunc {
+	return rateLimited(policy, rateLimitKey, next)
+}
+
+// IPRateLimit is the middleware limiting requests to policy for each client
+// ip. It runs before the authentication, so requests with invalid tokens are
+// limited too.
+type IPRateLimit struct {
+	policy *RateLimitPolicy
+}
+
+// NewIPRateLimit returns the middleware limiting client ips to policy
+func NewIPRateLimit(policy *RateLimitPolicy) *IPRateLimit {
+	return &IPRateLimit{policy: policy}
+}
+
+func (l *IPRateLimit) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	rateLimited(l.policy, ipRateLimitKey, next)(rw, r)
+}
+
+func rateLimited(policy *RateLimitPolicy, key func(*RateLimitPolicy, *http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !config.RateLimit.Enabled {
+			next(w, r)
+			return
+		}
+
+		result, err := rateLimitStore.Take(r.Context(), key(policy, r), policy, time.Now())
+		if err != nil {
+			// requests are not refused while the store is failing
+			TracePrintError(err)
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.FormatInt(policy.Limit, 10))
+		h.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		h.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		h.Set("RateLimit-Policy", strconv.FormatInt(policy.Limit, 10)+";w="+ceilSeconds(policy.Period))
+		if !result.Allowed {
+			rateLimitedTotal.WithLabelValues(policy.Name).Inc()
+			h.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			render.New().JSON(w, http.StatusTooManyRequests, map[string]string{"status": "too many requests"})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/urfave/negroni"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	policy := &RateLimitPolicy{"test", 2, time.Minute}
+	m := NewMemoryRateLimitStore()
+	now := time.Now()
+
+	cases := []struct {
+		Name       string
+		Key        string
+		At         time.Duration
+		Allowed    bool
+		Remaining  int64
+		RetryAfter time.Duration
+		Reset      time.Duration
+	}{
+		{"First", "a", 0, true, 1, 0, 30 * time.Second},
+		{"Second", "a", 0, true, 0, 0, time.Minute},
+		{"Exceeded", "a", 0, false, 0, 30 * time.Second, time.Minute},
+		{"OtherKey", "b", 0, true, 1, 0, 30 * time.Second},
+		{"StillExceeded", "a", 15 * time.Second, false, 0, 15 * time.Second, 45 * time.Second},
+		{"Refilled", "a", 30 * time.Second, true, 0, 0, time.Minute},
+		{"Full", "a", 10 * time.Minute, true, 1, 0, 30 * time.Second},
+	}
+	for _, c := range cases {
+		result, err := m.Take(context.Background(), c.Key, policy, now.Add(c.At))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		expected := RateLimitResult{c.Allowed, c.Remaining, c.RetryAfter, c.Reset}
+		if result != expected {
+			t.Errorf("%s: result is %+v, expected %+v", c.Name, result, expected)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	policy := &RateLimitPolicy{"test", 10, time.Second}
+	m := NewMemoryRateLimitStore()
+	now := time.Now()
+
+	m.Take(context.Background(), "a", policy, now)
+	for i := 1; i < RATE_LIMIT_SWEEP_TAKES; i++ {
+		m.Take(context.Background(), "b", policy, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if _, found := m.buckets["a"]; found {
+		t.Errorf("full bucket is not removed")
+	}
+	if _, found := m.buckets["b"]; !found {
+		t.Errorf("bucket in use is removed")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	withDefaultConfig(t)
+	config.RateLimit.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1"}
+
+	cases := []struct {
+		Name       string
+		RemoteAddr string
+		Forwarded  []string
+		IP         string
+	}{
+		{"Direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"UntrustedProxy", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"TrustedProxy", "10.1.2.3:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"TrustedProxyIp", "192.168.1.1:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"SpoofedFirst", "10.1.2.3:1234", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"ProxyChain", "10.1.2.3:1234", []string{"198.51.100.1", "10.9.9.9"}, "198.51.100.1"},
+		{"OnlyProxies", "10.1.2.3:1234", []string{"10.9.9.9"}, "10.9.9.9"},
+		{"NoHeader", "10.1.2.3:1234", nil, "10.1.2.3"},
+		{"Invalid", "10.1.2.3:1234", []string{"198.51.100.1, unknown"}, "10.1.2.3"},
+		{"IPv6", "[2001:db8::1]:1234", nil, "2001:db8::1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.RemoteAddr
+		for _, value := range c.Forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if ip := clientIP(r); ip != c.IP {
+			t.Errorf("%s: client ip is %s, expected %s", c.Name, ip, c.IP)
+		}
+	}
+}
+
+// tokenUserRepository finds users by their access tokens and counts the
+// lookups
+type tokenUserRepository struct {
+	UserRepository
+	users   map[string]*UserDB
+	lookups int
+}
+
+func (r *tokenUserRepository) GetByAccessToken(ctx context.Context, token string) (*UserDB, error) {
+	r.lookups++
+	return r.users[token], nil
+}
+
+func TestRateLimited(t *testing.T) {
+	withDefaultConfig(t)
+	prevStore := rateLimitStore
+	t.Cleanup(func() { rateLimitStore = prevStore })
+	rateLimitStore = NewMemoryRateLimitStore()
+
+	policy := &RateLimitPolicy{"test", 2, time.Minute}
+	handler := RateLimited(policy, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(remoteAddr string, user *UserDB) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		if user != nil {
+			r = withIdentity(r, user, AccessTokenClaims{})
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := request("203.0.113.7:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status is %d, expected %d", w.Code, http.StatusOK)
+	}
+	headers := map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "30", "RateLimit-Policy": "2;w=60"}
+	for name, value := range headers {
+		if w.Header().Get(name) != value {
+			t.Errorf("%s is %q, expected %q", name, w.Header().Get(name), value)
+		}
+	}
+
+	request("203.0.113.7:4321", nil)
+	w = request("203.0.113.7:1234", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status is %d, expected %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Retry-After is %q, RateLimit-Remaining is %q", w.Header().Get("Retry-After"), w.Header().Get("RateLimit-Remaining"))
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["status"] != "too many requests" {
+		t.Errorf("body is %s: %v", w.Body, err)
+	}
+
+	if w = request("198.51.100.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("other ip: status is %d, expected %d", w.Code, http.StatusOK)
+	}
+
+	// users are limited apart from the ip they share
+	user := &UserDB{UserId: "test-user"}
+	for i, status := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w = request("203.0.113.7:1234", user); w.Code != status {
+			t.Errorf("user request %d: status is %d, expected %d", i, w.Code, status)
+		}
+	}
+	if w = request("203.0.113.7:1234", &UserDB{UserId: "other-user"}); w.Code != http.StatusOK {
+		t.Errorf("other user: status is %d, expected %d", w.Code, http.StatusOK)
+	}
+
+	// the ip limit runs before the access token is checked, the user limit
+	// behind it
+	prevAppStore := store
+	t.Cleanup(func() { store = prevAppStore })
+	users := &tokenUserRepository{users: map[string]*UserDB{}}
+	store = &Store{Users: users}
+	token := func(userId string, known bool) string {
+		claims, err := NewAccessToken("test-client", userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if known {
+			users.users[claims.tokenString] = &UserDB{UserId: userId, ExpiresAt: claims.ExpiresAt}
+		}
+		return claims.tokenString
+	}
+	private := negroni.New(
+		NewIPRateLimit(&RateLimitPolicy{"ip", 3, time.Minute}),
+		negroni.HandlerFunc(ValidateAccessToken),
+		negroni.WrapFunc(RateLimited(&RateLimitPolicy{"user", 1, time.Minute}, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	userToken, otherToken := token("test-user", true), token("other-user", true)
+	steps := []struct {
+		Name       string
+		RemoteAddr string
+		Token      string
+		Status     int
+		Lookups    int
+	}{
+		{"BogusToken", "203.0.113.9:1234", "bogus", http.StatusUnauthorized, 0},
+		{"User", "203.0.113.9:1234", userToken, http.StatusOK, 1},
+		{"UserLimited", "203.0.113.9:1234", userToken, http.StatusTooManyRequests, 2},
+		{"IpLimitedBogusToken", "203.0.113.9:1234", "bogus", http.StatusTooManyRequests, 2},
+		{"IpLimitedUnknownUser", "203.0.113.9:1234", token("unknown-user", false), http.StatusTooManyRequests, 2},
+		{"OtherIp", "198.51.100.9:1234", otherToken, http.StatusOK, 3},
+	}
+	for _, c := range steps {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.RemoteAddr
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+		w := httptest.NewRecorder()
+		private.ServeHTTP(w, r)
+		if w.Code != c.Status || users.lookups != c.Lookups {
+			t.Errorf("%s: status is %d, expected %d, users are looked up %d times, expected %d", c.Name, w.Code, c.Status, users.lookups, c.Lookups)
+		}
+	}
+
+	config.RateLimit.Enabled = false
+	if w = request("203.0.113.7:1234", nil); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("disabled: status is %d, RateLimit-Limit is %q", w.Code, w.Header().Get("RateLimit-Limit"))
+	}
+}
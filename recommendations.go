@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+// Weights of the recommendation score. Each component of a candidate perfum
+// scores the number of liked perfums containing it, more if it is in the
+// same note.
+const (
+	RECOMMEND_NOTE_COMPONENT_WEIGHT = 2
+	RECOMMEND_COMPONENT_WEIGHT      = 1
+)
+
+type recommendScoreRecord struct {
+	Uuid              string `db:"info_uuid"`
+	Score             int64  `db:"score"`
+	MatchedComponents int64  `db:"matched_components"`
+}
+
+// RecommendedPerfumV1 ...
+type RecommendedPerfumV1 struct {
+	PerfumInfoV1
+	Score             int64 `json:"score"`
+	MatchedComponents int64 `json:"matched_components"`
+}
+
+// RecommendationsV1 ...
+type RecommendationsV1 struct {
+	ObjList []RecommendedPerfumV1 `db:"-" json:"recommendations_list"`
+	Total   int64                 `db:"-" json:"total"`
+	Offset  int64                 `db:"-" json:"offset"`
+	Amount  int64                 `db:"-" json:"amount"`
+}
+
+func NewRecommendationsFactory(version string) Objecter {
+	switch version {
+	case "v1":
+		return &RecommendationsV1{ObjList: make([]RecommendedPerfumV1, 0)}
+	}
+
+	return nil
+}
+
+// MakeObj ranks perfums against the taste of user params.UserId
+func (obj *RecommendationsV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*RecommendParams)
+	if params.UserId == "" {
+		return nil, errors.New("invalid args")
+	}
+
+	offset := int64(DEFAULT_OFFSET)
+	if params.Base.Offset.Valid {
+		offset = params.Base.Offset.Int64
+	}
+	limit := config.Pagination.DefaultLimit
+	if params.Base.Limit.Valid {
+		limit = params.Base.Limit.Int64
+	}
+
+	scores, err := store.Perfums.Recommend(ctx, params, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Total = params.Total
+	obj.Offset = offset
+	if len(scores) == 0 {
+		return obj, nil
+	}
+
+	uuids := make([]string, 0, len(scores))
+	for _, record := range scores {
+		uuids = append(uuids, record.Uuid)
+	}
+
+	infoParams := MakeObjParams{Base: params.Base}
+	infoParams.Base.Ids = NullSliceString{String: uuids, Valid: true}
+	infoParams.Base.Offset = NullInt64{Int64: 0, Valid: true}
+	infoParams.Base.Limit = NullInt64{Int64: int64(len(uuids)), Valid: true}
+	infos := &PerfumsInfoV1{ObjList: make([]PerfumInfoV1, 0)}
+	if _, err := infos.MakeObj(ctx, &infoParams); err != nil {
+		return nil, err
+	}
+	infoByUuid := make(map[string]PerfumInfoV1)
+	for _, info := range infos.ObjList {
+		infoByUuid[info.Uuid] = info
+	}
+
+	for _, record := range scores {
+		if info, ok := infoByUuid[record.Uuid]; ok {
+			obj.ObjList = append(obj.ObjList, RecommendedPerfumV1{
+				PerfumInfoV1:      info,
+				Score:             record.Score,
+				MatchedComponents: record.MatchedComponents,
+			})
+		}
+	}
+	obj.Amount = int64(len(obj.ObjList))
+
+	return obj, nil
+}
+
+func (obj *RecommendationsV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+// Count returns number of perfums recommended by params
+func (obj *RecommendationsV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	params := pParams.(*RecommendParams)
+	if params.UserId == "" {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Perfums.RecommendCount(ctx, params)
+}
+
+func (obj *RecommendationsV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return obj.Count(ctx, &RecommendParams{UserId: uids[0]})
+}
+
+func (obj *RecommendationsV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
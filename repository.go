@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+)
+
+// Store groups the repositories objects and handlers read and write
+// through. It is the PostgreSQL store once the database is open, tests may
+// use a MemoryStore instead.
+type Store struct {
+	Users     UserRepository
+	Images    ImageRepository
+	Perfums   PerfumRepository
+	Entities  EntityRepository
+	Reviews   ReviewRepository
+	Favorites FavoriteRepository
+	Offers    OfferRepository
+	Catalogue CatalogueRepository
+}
+
+var store *Store
+
+// ListQuery is the language of names and the page of a list
+type ListQuery struct {
+	Lang   LangField
+	Offset int64
+	Limit  int64
+}
+
+// NewListQuery returns the list query of request params, with default offset
+// and limit if they are not given
+func NewListQuery(params *BaseParams) ListQuery {
+	q := ListQuery{
+		Lang:   getNameFields(params.Lang.String),
+		Offset: DEFAULT_OFFSET,
+		Limit:  config.Pagination.DefaultLimit,
+	}
+	if params.Offset.Valid {
+		q.Offset = params.Offset.Int64
+	}
+	if params.Limit.Valid {
+		q.Limit = params.Limit.Int64
+	}
+	return q
+}
+
+// PerfumFilter selects perfums, the zero filter selects all of them
+type PerfumFilter struct {
+	// Uuids are the perfums to select, any if empty
+	Uuids []string
+	// Entity and EntityUuid select perfums referencing the item of a
+	// registry entity, directly or through the composition
+	Entity     *EntityDesc
+	EntityUuid string
+}
+
+// EntityFilter selects items of a registry entity, the zero filter selects
+// all of them
+type EntityFilter struct {
+	// Uuids are the items to select, any if empty
+	Uuids []string
+	// Search selects items by the EntityUid and Entity search values
+	Search *SearchParams
+}
+
+// UserRepository stores users and their tokens. Lookups return nil without
+// error if the user is not found.
+type UserRepository interface {
+	Get(ctx context.Context, userId string) (*UserDB, error)
+	GetByAccessToken(ctx context.Context, token string) (*UserDB, error)
+	GetByRefreshToken(ctx context.Context, token string) (*UserDB, error)
+	Insert(ctx context.Context, user *UserDB) error
+	// Update and Delete return false if the user is not found
+	Update(ctx context.Context, user *UserDB) (bool, error)
+	Delete(ctx context.Context, user *UserDB) (bool, error)
+}
+
+// ImageRepository stores image files metadata. Lookups return nil without
+// error if the image is not found.
+type ImageRepository interface {
+	GetById(ctx context.Context, id int64) (*ImageDB, error)
+	GetByUuid(ctx context.Context, uuid string) (*ImageDB, error)
+}
+
+// PerfumRepository reads the perfums catalogue
+type PerfumRepository interface {
+	// IdByUuid returns 0 if the perfum is not found
+	IdByUuid(ctx context.Context, uuid string) (int64, error)
+	Count(ctx context.Context, filter PerfumFilter) (int64, error)
+	List(ctx context.Context, q ListQuery, filter PerfumFilter) ([]PerfumInfoV1, error)
+	// CountComposition returns the number of composition records of the
+	// perfums uuids, of all perfums if uuids is empty
+	CountComposition(ctx context.Context, uuids []string) (int64, error)
+	// Composition returns notes and components of the perfums uuids ordered
+	// by perfum, note and component name
+	Composition(ctx context.Context, lang LangField, uuids []string) ([]PerfumCompositionDBRecordV1, error)
+	// Search returns the page of uuids of the perfums found by params
+	Search(ctx context.Context, params *SearchParams) ([]string, error)
+	SearchCount(ctx context.Context, params *SearchParams) (int64, error)
+	// Similar returns the page of scores of perfums sharing a component
+	// with the perfum uuid, best first
+	Similar(ctx context.Context, uuid string, offset, limit int64) ([]similarityScoreRecord, error)
+	SimilarCount(ctx context.Context, uuid string) (int64, error)
+	// SharedComposition returns the notes and components the perfums uuids
+	// share with the perfum uuid, ordered as Composition
+	SharedComposition(ctx context.Context, lang LangField, uuid string, uuids []string) ([]PerfumCompositionDBRecordV1, error)
+	// Recommend returns the page of perfums recommended by params, best
+	// first
+	Recommend(ctx context.Context, params *RecommendParams, offset, limit int64) ([]recommendScoreRecord, error)
+	RecommendCount(ctx context.Context, params *RecommendParams) (int64, error)
+}
+
+// EntityRepository reads items of the registry entities
+type EntityRepository interface {
+	Count(ctx context.Context, e *EntityDesc, filter EntityFilter) (int64, error)
+	List(ctx context.Context, e *EntityDesc, q ListQuery, filter EntityFilter) ([]EntityV1, error)
+	// PerfumsCount returns the number of perfums of every item by uuid
+	PerfumsCount(ctx context.Context, e *EntityDesc) (map[string]int64, error)
+}
+
+// ReviewRepository stores reviews of perfums
+type ReviewRepository interface {
+	// GetByUser returns nil if user has not reviewed the perfum
+	GetByUser(ctx context.Context, perfumInfoId int64, userId string) (*ReviewDB, error)
+	// Insert returns ErrReviewExists if user has already reviewed the perfum
+	Insert(ctx context.Context, review *ReviewDB) error
+	Update(ctx context.Context, review *ReviewDB) (bool, error)
+	Delete(ctx context.Context, review *ReviewDB) (bool, error)
+	// Count returns the number of reviews of the perfums uuids, of all
+	// perfums if uuids is empty
+	Count(ctx context.Context, uuids []string) (int64, error)
+	// List returns the page of reviews of the perfum uuid, newest first
+	List(ctx context.Context, uuid string, offset, limit int64) ([]ReviewV1, error)
+}
+
+// FavoriteRepository stores perfums liked by users. Perfums are given by
+// uuid, unknown ones are skipped.
+type FavoriteRepository interface {
+	Add(ctx context.Context, userId string, uuids []string) (int64, error)
+	Replace(ctx context.Context, userId string, uuids []string) error
+	// Remove removes all perfums of user if uuids is empty
+	Remove(ctx context.Context, userId string, uuids []string) (int64, error)
+	// Count returns the number of perfums liked by the users, by all users
+	// if userIds is empty
+	Count(ctx context.Context, userIds []string) (int64, error)
+	// List returns the page of uuids of perfums liked by user, latest first
+	List(ctx context.Context, userId string, offset, limit int64) ([]string, error)
+}
+
+// OfferRepository reads shop offers of perfums
+type OfferRepository interface {
+	// Count returns the number of offers of the perfums uuids, of all
+	// perfums if uuids is empty
+	Count(ctx context.Context, uuids []string) (int64, error)
+	// List returns the page of offers of the perfum uuid, cheapest first
+	List(ctx context.Context, lang LangField, uuid string, offset, limit int64) ([]OfferV1, error)
+}
+
+// CatalogueRepository imports and exports the whole catalogue
+type CatalogueRepository interface {
+	Import(ctx context.Context, items []CatalogueItem) (*CatalogueImportResult, error)
+	Export(ctx context.Context) ([]CatalogueItem, error)
+}
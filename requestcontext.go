@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type of request context keys of the package, values of
+// other packages can't collide with them
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	tokenContextKey
+	requestInfoContextKey
+)
+
+// requestInfo is shared by the middlewares of a request, the handlers down
+// the chain fill it for the ones which run before them
+type requestInfo struct {
+	RequestId string
+	UserId    string
+}
+
+// withRequestInfo returns r with info in its context
+func withRequestInfo(r *http.Request, info *requestInfo) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestInfoContextKey, info))
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoContextKey).(*requestInfo)
+	return info
+}
+
+// RequestId returns the id of the request, empty outside of the access log
+func RequestId(r *http.Request) string {
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		return info.RequestId
+	}
+	return ""
+}
+
+// withIdentity returns r with the authenticated user and its token claims in
+// its context
+func withIdentity(r *http.Request, user *UserDB, claims AccessTokenClaims) *http.Request {
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		info.UserId = user.UserId
+	}
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx = context.WithValue(ctx, tokenContextKey, claims)
+	return r.WithContext(ctx)
+}
+
+// CurrentUser returns the authenticated user of the request or nil
+func CurrentUser(r *http.Request) *UserDB {
+	user, _ := r.Context().Value(userContextKey).(*UserDB)
+	return user
+}
+
+// CurrentToken returns the access token claims of the authenticated user,
+// ok is false if there is none
+func CurrentToken(r *http.Request) (claims AccessTokenClaims, ok bool) {
+	claims, ok = r.Context().Value(tokenContextKey).(AccessTokenClaims)
+	return claims, ok
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrolled/render"
+)
+
+const (
+	REVIEW_MIN_MARK     = 1
+	REVIEW_MAX_MARK     = 5
+	REVIEW_MAX_TEXT_LEN = 4000
+)
+
+var (
+	ErrReviewExists  = errors.New("review already exists")
+	ErrReviewInvalid = errors.New("review is not valid")
+)
+
+// ReviewDB ...
+type ReviewDB struct {
+	Id           int64          `db:"id"`
+	Uuid         string         `db:"uuid"`
+	PerfumInfoId int64          `db:"parfum_info_id"`
+	UserId       string         `db:"user_id"`
+	Score        int64          `db:"score"`
+	Longevity    sql.NullInt64  `db:"longevity"`
+	Sillage      sql.NullInt64  `db:"sillage"`
+	Text         sql.NullString `db:"text"`
+	CreatedAt    int64          `db:"created_at"`
+	UpdatedAt    int64          `db:"updated_at"`
+}
+
+// ReviewForm is the review submitted by user. Score is mandatory for a new
+// review, longevity, sillage and text are optional.
+type ReviewForm struct {
+	Score     NullInt64
+	Longevity NullInt64
+	Sillage   NullInt64
+	Text      NullString
+}
+
+func parseReviewMark(r *http.Request, name string, mark *NullInt64) error {
+	value := strings.TrimSpace(r.Form.Get(name))
+	if value == "" {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || v < REVIEW_MIN_MARK || v > REVIEW_MAX_MARK {
+		return ErrReviewInvalid
+	}
+	mark.Int64 = v
+	mark.Valid = true
+
+	return nil
+}
+
+// Parse reads review form values: score, longevity, sillage and text
+func (form *ReviewForm) Parse(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	if err := parseReviewMark(r, "score", &form.Score); err != nil {
+		return err
+	}
+	if err := parseReviewMark(r, "longevity", &form.Longevity); err != nil {
+		return err
+	}
+	if err := parseReviewMark(r, "sillage", &form.Sillage); err != nil {
+		return err
+	}
+
+	if _, ok := r.Form["text"]; ok {
+		form.Text.String = strings.TrimSpace(r.Form.Get("text"))
+		form.Text.Valid = true
+		if len(form.Text.String) > REVIEW_MAX_TEXT_LEN {
+			return ErrReviewInvalid
+		}
+	}
+
+	return nil
+}
+
+func (form *ReviewForm) apply(review *ReviewDB) {
+	if form.Score.Valid {
+		review.Score = form.Score.Int64
+	}
+	if form.Longevity.Valid {
+		review.Longevity = sql.NullInt64{Int64: form.Longevity.Int64, Valid: true}
+	}
+	if form.Sillage.Valid {
+		review.Sillage = sql.NullInt64{Int64: form.Sillage.Int64, Valid: true}
+	}
+	if form.Text.Valid {
+		review.Text = sql.NullString{String: form.Text.String, Valid: form.Text.String != ""}
+	}
+}
+
+// GetPerfumInfoIdByUuid returns 0 if perfum is not found
+func GetPerfumInfoIdByUuid(ctx context.Context, uuid string) (int64, error) {
+	if uuid == "" {
+		return 0, errors.New("bad arg")
+	}
+	return store.Perfums.IdByUuid(ctx, uuid)
+}
+
+// GetReviewByUser returns nil if user has not reviewed the perfum
+func GetReviewByUser(ctx context.Context, perfumInfoId int64, userId string) (*ReviewDB, error) {
+	if userId == "" {
+		return nil, errors.New("bad arg")
+	}
+	review, err := store.Reviews.GetByUser(ctx, perfumInfoId, userId)
+	if err != nil {
+		TracePrintError(err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// ReviewInsert ...
+func ReviewInsert(ctx context.Context, perfumInfoId int64, userId string, form *ReviewForm) (*ReviewDB, error) {
+	if userId == "" || form == nil {
+		return nil, errors.New("bad arg")
+	}
+	if !form.Score.Valid {
+		return nil, ErrReviewInvalid
+	}
+
+	existing, err := GetReviewByUser(ctx, perfumInfoId, userId)
+	if err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, ErrReviewExists
+	}
+
+	uuid, err := NewUuid()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	review := &ReviewDB{
+		Uuid:         uuid,
+		PerfumInfoId: perfumInfoId,
+		UserId:       userId,
+		CreatedAt:    now.Unix(),
+		UpdatedAt:    now.Unix(),
+	}
+	form.apply(review)
+	if err := store.Reviews.Insert(ctx, review); err == ErrReviewExists {
+		return nil, err
+	} else if err != nil {
+		TracePrintError(err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// Update ...
+func (review *ReviewDB) Update(ctx context.Context, form *ReviewForm) (bool, error) {
+	if review.Id == 0 || form == nil {
+		return false, errors.New("bad arg")
+	}
+
+	form.apply(review)
+	review.UpdatedAt = time.Now().Unix()
+	updated, err := store.Reviews.Update(ctx, review)
+	if err != nil {
+		TracePrintError(err)
+		return false, err
+	}
+	return updated, nil
+}
+
+// Delete ...
+func (review *ReviewDB) Delete(ctx context.Context) (bool, error) {
+	if review.Id == 0 {
+		return false, errors.New("bad arg")
+	}
+	deleted, err := store.Reviews.Delete(ctx, review)
+	if err != nil {
+		TracePrintError(err)
+		return false, err
+	}
+	return deleted, nil
+}
+
+// ReviewV1 ...
+type ReviewV1 struct {
+	Uuid       string   `db:"uuid" json:"id"`
+	PerfumUuid string   `db:"info_uuid" json:"perfum_id"`
+	UserId     string   `db:"user_id" json:"user_id"`
+	Score      int64    `db:"score" json:"score"`
+	Longevity  int64    `db:"longevity" json:"longevity"`
+	Sillage    int64    `db:"sillage" json:"sillage"`
+	Text       string   `db:"text" json:"text"`
+	CreatedAt  int64    `db:"created_at" json:"created_at"`
+	UpdatedAt  int64    `db:"updated_at" json:"updated_at"`
+	Links      []LinkV1 `db:"-" json:"links"`
+}
+
+func NewReviewV1(review *ReviewDB, perfumUuid string) *ReviewV1 {
+	obj := &ReviewV1{
+		Uuid:       review.Uuid,
+		PerfumUuid: perfumUuid,
+		UserId:     review.UserId,
+		Score:      review.Score,
+		Longevity:  review.Longevity.Int64,
+		Sillage:    review.Sillage.Int64,
+		Text:       review.Text.String,
+		CreatedAt:  review.CreatedAt,
+		UpdatedAt:  review.UpdatedAt,
+	}
+	obj.AddLinks()
+	return obj
+}
+
+func (obj *ReviewV1) AddLinks() *ReviewV1 {
+	obj.Links = []LinkV1{
+		LinkV1{
+			Href:   baseUrl + "/perfum/" + obj.PerfumUuid,
+			Rel:    "PerfumInfo",
+			Method: "GET",
+		},
+		LinkV1{
+			Href:   baseUrl + "/perfum/" + obj.PerfumUuid + "/reviews",
+			Rel:    "PerfumReviews",
+			Method: "GET",
+		},
+	}
+	return obj
+}
+
+func (obj *ReviewV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
+
+// ReviewsV1 ...
+type ReviewsV1 struct {
+	ObjList []ReviewV1 `db:"-" json:"reviews_list"`
+	Total   int64      `db:"-" json:"total"`
+	Offset  int64      `db:"-" json:"offset"`
+	Amount  int64      `db:"-" json:"amount"`
+}
+
+func NewReviewsFactory(version string) Objecter {
+	switch version {
+	case "v1":
+		return &ReviewsV1{ObjList: make([]ReviewV1, 0)}
+	}
+
+	return nil
+}
+
+// MakeObj selects reviews of the perfum params.Id, newest first
+func (obj *ReviewsV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+	if params.Id == "" {
+		return nil, errors.New("invalid args")
+	}
+
+	offset := int64(DEFAULT_OFFSET)
+	if params.Base.Offset.Valid {
+		offset = params.Base.Offset.Int64
+	}
+	limit := config.Pagination.DefaultLimit
+	if params.Base.Limit.Valid {
+		limit = params.Base.Limit.Int64
+	}
+
+	list, err := store.Reviews.List(ctx, params.Id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	obj.ObjList = list
+
+	obj.Total = params.Total
+	obj.Offset = offset
+	obj.Amount = int64(len(obj.ObjList))
+
+	for i := range obj.ObjList {
+		obj.ObjList[i].AddLinks()
+	}
+
+	return obj, nil
+}
+
+func (obj *ReviewsV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+func (obj *ReviewsV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Reviews.Count(ctx, nil)
+}
+
+// ExtraCount returns number of reviews of the perfums with uids
+func (obj *ReviewsV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Reviews.Count(ctx, uids)
+}
+
+func (obj *ReviewsV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
@@ -1,73 +1,80 @@
 package main
 
 import (
-	"os"
-
 	"github.com/gorilla/mux"
 	"github.com/urfave/negroni"
 )
 
 const (
-	API_PATH = "/api/v1"
-)
-
-var (
-	apiHost     = ""
-	apiPort     = ""
-	apiDNS      = ""
-	apiProtocol = ""
-	baseUrl     = ""
+	API_PATH   = "/api/v1"
+	ADMIN_PATH = "/admin"
 )
 
-func init() {
-	if apiHost = os.Getenv("OPENSHIFT_GO_IP"); apiHost == "" {
-		TraceFatal("Variable OPENSHIFT_GO_IP is not defined")
-		os.Exit(-1)
-	}
-
-	if apiPort = os.Getenv("OPENSHIFT_GO_PORT"); apiPort == "" {
-		TraceFatal("Variable OPENSHIFT_GO_PORT is not defined")
-		os.Exit(-1)
-	}
-
-	if apiProtocol = os.Getenv("FRAGRANCES_API_PROTOCOL"); apiProtocol == "" {
-		TraceFatal("Variable FRAGRANCES_API_PROTOCOL is not defined")
-		os.Exit(-1)
-	}
-
-	if apiDNS = os.Getenv("OPENSHIFT_APP_DNS"); apiDNS == "" {
-		TraceFatal("Variable OPENSHIFT_APP_DNS is not defined")
-		os.Exit(-1)
-	}
-
-	baseUrl = apiProtocol + "://" + apiDNS + API_PATH
-}
+// baseUrl is the url links in responses start with, set from config by main
+var baseUrl = ""
 
 // NewRouter ...
 func NewRouter() *mux.Router {
 	root := mux.NewRouter()
 
 	logger := NewLogger()
-	logger.SetDateFormat("02.01.2006 15:04:05.000")
+	metrics := NewMetrics(logger.RouteNamer)
+	tracing := NewTracing(logger.RouteNamer)
+	queryTimeout := NewQueryTimeout(logger.RouteNamer)
 
 	recovery := negroni.NewRecovery()
 
+	root.Path(METRICS_PATH).Methods("GET").Handler(negroni.New(
+		recovery,
+		negroni.Wrap(MetricsHandler())))
+
+	// probes are polled often, they bypass auth and access logs
+	root.Path(HEALTHZ_PATH).Methods("GET").Handler(negroni.New(
+		recovery,
+		negroni.WrapFunc(HealthzEndpoint)))
+	root.Path(READYZ_PATH).Methods("GET").Handler(negroni.New(
+		recovery,
+		negroni.WrapFunc(ReadyzEndpoint)))
+
 	publicRouter := mux.NewRouter().StrictSlash(true)
+	logger.AddRouter(publicRouter)
 	for _, route := range publicRoutes {
 		publicRouter.Methods(route.Method).Path(API_PATH + route.Pattern).Name(route.Name).Handler(route.Endpoint)
 		root.Path(API_PATH + route.Pattern).Handler(negroni.New(
 			recovery,
 			logger,
+			metrics,
+			tracing,
+			queryTimeout,
 			negroni.Wrap(publicRouter)))
 	}
 
+	adminRouter := mux.NewRouter().PathPrefix(ADMIN_PATH).Subrouter().StrictSlash(true)
+	logger.AddRouter(adminRouter)
+	for _, route := range adminRoutes {
+		adminRouter.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(route.Endpoint)
+	}
+	root.PathPrefix(ADMIN_PATH).Handler(negroni.New(
+		recovery,
+		logger,
+		metrics,
+		tracing,
+		queryTimeout,
+		negroni.HandlerFunc(ValidateAdminToken),
+		negroni.Wrap(adminRouter)))
+
 	privateRouter := mux.NewRouter().PathPrefix(API_PATH).Subrouter().StrictSlash(true)
+	logger.AddRouter(privateRouter)
 	for _, route := range privateRoutes {
 		privateRouter.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(route.Endpoint)
 	}
 	root.PathPrefix(API_PATH).Handler(negroni.New(
 		recovery,
 		logger,
+		metrics,
+		tracing,
+		queryTimeout,
+		NewIPRateLimit(privateRateLimit),
 		negroni.HandlerFunc(ValidateAccessToken),
 		negroni.Wrap(privateRouter)))
 
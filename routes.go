@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"time"
 )
 
 //Route ...
@@ -15,6 +16,16 @@ type Route struct {
 // Routes ...
 type Routes []Route
 
+// Rate limit policies of routes, clients are users of private routes and ips
+// of public ones. privateRateLimit limits the ips of all private requests
+// before their tokens are checked.
+var (
+	loginRateLimit   = &RateLimitPolicy{"login", 20, time.Minute}
+	tokenRateLimit   = &RateLimitPolicy{"token", 20, time.Minute}
+	searchRateLimit  = &RateLimitPolicy{"search", 30, time.Minute}
+	privateRateLimit = &RateLimitPolicy{"private", 300, time.Minute}
+)
+
 // Unauthorized. Path prefix: /
 // Handle without middleware
 var publicRoutes = Routes{
@@ -22,17 +33,47 @@ var publicRoutes = Routes{
 		"Login",
 		"POST",
 		"/login",
-		LoginEndpoint,
+		RateLimited(loginRateLimit, LoginEndpoint),
 	},
 	Route{
 		"Token",
 		"POST",
 		"/token",
-		TokenEndpoint,
+		RateLimited(tokenRateLimit, TokenEndpoint),
+	},
+}
+
+// Admin. Path prefix: /admin
+// Authorized by the static admin token
+var adminRoutes = Routes{
+	Route{
+		"GetLogLevel",
+		"GET",
+		"/loglevel",
+		GetLogLevelEndpoint,
+	},
+	Route{
+		"SetLogLevel",
+		"PUT",
+		"/loglevel",
+		SetLogLevelEndpoint,
+	},
+	Route{
+		"ExportCatalogue",
+		"GET",
+		"/catalogue",
+		ExportCatalogueEndpoint,
+	},
+	Route{
+		"ImportCatalogue",
+		"PUT",
+		"/catalogue",
+		ImportCatalogueEndpoint,
 	},
 }
 
-var privateRoutes = Routes{
+// Reference entity routes are generated from entityRegistry
+var privateRoutes = append(Routes{
 	Route{
 		"GetUser",
 		"GET",
@@ -82,208 +123,64 @@ var privateRoutes = Routes{
 		DeleteUserFavoritesEndpoint,
 	},
 	Route{
-		"GetBrands",
-		"GET",
-		"/brands",
-		GetBrandsEndpoint,
-	},
-	Route{
-		"GetBrandsFind",
-		"GET",
-		"/brands/find",
-		GetBrandsFindEndpoint,
-	},
-	Route{
-		"GetBrand",
-		"GET",
-		"/brand/{brandId}",
-		GetBrandEndpoint,
-	},
-	Route{
-		"GetPerfumsByBrand",
-		"GET",
-		"/brand/{brandId}/perfums",
-		GetBrandPerfumsEndpoint,
-	},
-	Route{
-		"GetComponents",
-		"GET",
-		"/components",
-		GetComponentsEndpoint,
-	},
-	Route{
-		"GetComponentsFind",
-		"GET",
-		"/components/find",
-		GetComponentsFindEndpoint,
-	},
-	Route{
-		"GetComponent",
-		"GET",
-		"/component/{componentId}",
-		GetComponentEndpoint,
-	},
-	Route{
-		"GetPerfumsByComponent",
-		"GET",
-		"/component/{componentId}/perfums",
-		GetComponentPerfumsEndpoint,
-	},
-	Route{
-		"GetCountries",
-		"GET",
-		"/countries",
-		GetCountriesEndpoint,
-	},
-	Route{
-		"GetCountriesFind",
-		"GET",
-		"/countries/find",
-		GetCountriesFindEndpoint,
-	},
-	Route{
-		"GetCountry",
-		"GET",
-		"/country/{countryId}",
-		GetCountryEndpoint,
-	},
-	Route{
-		"GetPerfumsByCountry",
-		"GET",
-		"/country/{countryId}/perfums",
-		GetCountryPerfumsEndpoint,
-	},
-	Route{
-		"GetGenders",
-		"GET",
-		"/genders",
-		GetGendersEndpoint,
-	},
-	Route{
-		"GetGender",
+		"GetUserRecommendations",
 		"GET",
-		"/gender/{genderId}",
-		GetGenderEndpoint,
+		"/user/{userId}/recommendations",
+		GetUserRecommendationsEndpoint,
 	},
 	Route{
-		"GetPerfumsByGender",
-		"GET",
-		"/gender/{genderId}/perfums",
-		GetGenderPerfumsEndpoint,
-	},
-	Route{
-		"GetGroups",
-		"GET",
-		"/groups",
-		GetGroupsEndpoint,
-	},
-	Route{
-		"GetGroupsFind",
-		"GET",
-		"/groups/find",
-		GetGroupsFindEndpoint,
-	},
-	Route{
-		"GetGroup",
-		"GET",
-		"/group/{groupId}",
-		GetGroupEndpoint,
-	},
-	Route{
-		"GetPerfumsByGroup",
-		"GET",
-		"/group/{groupId}/perfums",
-		GetGroupPerfumsEndpoint,
-	},
-	Route{
-		"GetNotes",
-		"GET",
-		"/notes",
-		GetNotesEndpoint,
-	},
-	Route{
-		"GetNote",
-		"GET",
-		"/note/{noteId}",
-		GetNoteEndpoint,
-	},
-	Route{
-		"GetPerfumsByNote",
-		"GET",
-		"/note/{noteId}/perfums",
-		GetNotePerfumsEndpoint,
-	},
-	Route{
-		"GetSeasons",
-		"GET",
-		"/seasons",
-		GetSeasonsEndpoint,
-	},
-	Route{
-		"GetSeason",
-		"GET",
-		"/season/{seasonId}",
-		GetSeasonEndpoint,
-	},
-	Route{
-		"GetPerfumsBySeason",
-		"GET",
-		"/season/{seasonId}/perfums",
-		GetSeasonPerfumsEndpoint,
-	},
-	Route{
-		"GetTimesOfDay",
+		"GetPerfums",
 		"GET",
-		"/timesofday",
-		GetTimesOfDayEndpoint,
+		"/perfums",
+		GetPerfumsEndpoint,
 	},
 	Route{
-		"GetTimeOfDay",
+		"GetPerfumsFind",
 		"GET",
-		"/timeofday/{tsodId}",
-		GetTimeOfDayEndpoint,
+		"/perfums/find",
+		RateLimited(searchRateLimit, GetPerfumsFindEndpoint),
 	},
 	Route{
-		"GetPerfumsByTimeOfDay",
+		"GetPerfumDetails",
 		"GET",
-		"/timeofday/{tsodId}/perfums",
-		GetTimeOfDayPerfumsEndpoint,
+		"/perfum/{perfumId}",
+		GetPerfumDetailedInfoEndpoint,
 	},
 	Route{
-		"GetTypes",
+		"GetPerfumReviews",
 		"GET",
-		"/types",
-		GetTypesEndpoint,
+		"/perfum/{perfumId}/reviews",
+		GetPerfumReviewsEndpoint,
 	},
 	Route{
-		"GetType",
-		"GET",
-		"/type/{typeId}",
-		GetTypeEndpoint,
+		"CreatePerfumReview",
+		"POST",
+		"/perfum/{perfumId}/reviews",
+		CreatePerfumReviewEndpoint,
 	},
 	Route{
-		"GetPerfumsByType",
-		"GET",
-		"/type/{typeId}/perfums",
-		GetTypePerfumsEndpoint,
+		"UpdatePerfumReview",
+		"PUT",
+		"/perfum/{perfumId}/reviews",
+		UpdatePerfumReviewEndpoint,
 	},
 	Route{
-		"GetPerfums",
-		"GET",
-		"/perfums",
-		GetPerfumsEndpoint,
+		"DeletePerfumReview",
+		"DELETE",
+		"/perfum/{perfumId}/reviews",
+		DeletePerfumReviewEndpoint,
 	},
 	Route{
-		"GetPerfumDetails",
+		"GetPerfumOffers",
 		"GET",
-		"/perfums/find",
-		GetPerfumsFindEndpoint,
+		"/perfum/{perfumId}/offers",
+		GetPerfumOffersEndpoint,
 	},
 	Route{
-		"GetPerfumDetails",
+		"GetPerfumSimilar",
 		"GET",
-		"/perfum/{perfumId}",
-		GetPerfumDetailedInfoEndpoint,
+		"/perfum/{perfumId}/similar",
+		GetPerfumSimilarEndpoint,
 	},
 	Route{
 		"GetImagesSmall",
@@ -297,4 +194,16 @@ var privateRoutes = Routes{
 		"/image/{imageId}/large",
 		GetLargeImageEndpoint,
 	},
+}, entityRoutes()...)
+
+// isRouteName reports whether a public, admin or private route is named name
+func isRouteName(name string) bool {
+	for _, routes := range []Routes{publicRoutes, adminRoutes, privateRoutes} {
+		for _, route := range routes {
+			if route.Name == name {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,445 @@
+package main
+
+// Route tests run the router against a MemoryStore, or against PostgreSQL in
+// the integration build. Both share the fixtures ids and the request helpers
+// of this file.
+//
+// TestRoutes compares responses with the golden files of testdata/golden in
+// both builds, uuids and timestamps are normalized. Record them after a change
+// of responses with
+//
+//	go test -tags integration -run TestRoutes -update ./...
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	TEST_CLIENT_ID     = "test-client"
+	TEST_USER_ID       = "seed-user-1"
+	TEST_OTHER_USER_ID = "seed-user-2"
+	TEST_UNKNOWN_UUID  = "00000000-0000-4000-8000-000000000000"
+
+	// perfums of fixtures/perfums.json
+	TEST_PERFUM_CHANEL   = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
+	TEST_PERFUM_DIOR     = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a02"
+	TEST_PERFUM_SHALIMAR = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a03"
+)
+
+var (
+	// testServer serves the router requested by doRequest
+	testServer *httptest.Server
+	// testIssuer signs id tokens of the login flow
+	testIssuer *fakeIssuer
+	// uuid of the image stored in the test data dir
+	testImageUuid string
+	// routes hit by the test cases, METHOD and path template
+	testRoutesHit = map[string]bool{}
+
+	uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// values of these keys change on every run
+	volatileKeys = map[string]bool{
+		"access_token":  true,
+		"refresh_token": true,
+		"created_at":    true,
+		"updated_at":    true,
+		"last_seen_at":  true,
+	}
+)
+
+// mintTokens issues access and refresh tokens of userId the way login does
+// and stores them, the user is created if needed
+func mintTokens(t *testing.T, userId string) (accessToken, refreshToken string) {
+	t.Helper()
+
+	access, err := NewAccessToken(TEST_CLIENT_ID, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := NewRefreshToken(TEST_CLIENT_ID, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := GetUserByUserId(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil {
+		_, err = UserInsert(context.Background(), userId, access.tokenString, refresh.tokenString, access.ExpiresAt)
+	} else {
+		_, err = user.Update(context.Background(), access.tokenString, refresh.tokenString, access.ExpiresAt)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access.tokenString, refresh.tokenString
+}
+
+// routeCase is a request and the expected status, the response is compared
+// with the golden file of the case name
+type routeCase struct {
+	Name   string
+	Method string
+	Path   string
+	// Token is the bearer token, empty for none
+	Token string
+	// Form is sent url encoded in the body
+	Form url.Values
+	// Status is checked if not zero, besides the golden file
+	Status int
+}
+
+// fixtureUuid returns the uuid and name of the first row of the fixture of
+// the entity, as the seed generates it
+func fixtureUuid(t *testing.T, e *EntityDesc) (uuid, name string) {
+	t.Helper()
+	rows, err := readSeedCsv(filepath.Join(SEED_DIR, e.Table+".csv"))
+	if err != nil || len(rows) == 0 {
+		t.Fatalf("fixture of %s: %v", e.Table, err)
+	}
+	columns := nameColumns(e)
+	name = rows[0][columns[len(columns)-1]]
+	if uuid = rows[0]["id"]; uuid == "" {
+		uuid = seedUuid(e.Table, name)
+	}
+	return uuid, name
+}
+
+func entityCases(t *testing.T, token string) []routeCase {
+	var cases []routeCase
+	for _, e := range entityRegistry {
+		uuid, name := fixtureUuid(t, e)
+		cases = append(cases,
+			routeCase{Name: e.PluralName, Method: "GET", Path: "/" + e.PluralPath, Token: token, Status: http.StatusOK},
+			routeCase{Name: e.PluralName + "Paged", Method: "GET", Path: "/" + e.PluralPath + "?limit=1&offset=1&lang=en", Token: token, Status: http.StatusOK},
+			routeCase{Name: e.Name, Method: "GET", Path: "/" + e.Path + "/" + uuid, Token: token, Status: http.StatusOK},
+			routeCase{Name: e.Name + "Unknown", Method: "GET", Path: "/" + e.Path + "/" + TEST_UNKNOWN_UUID, Token: token, Status: http.StatusNotFound},
+			routeCase{Name: e.Name + "InvalidId", Method: "GET", Path: "/" + e.Path + "/abc", Token: token, Status: http.StatusNotFound},
+			routeCase{Name: e.Name + "PerfumsInvalidId", Method: "GET", Path: "/" + e.Path + "/abc/perfums", Token: token, Status: http.StatusNotFound},
+			routeCase{Name: e.Name + "Perfums", Method: "GET", Path: "/" + e.Path + "/" + uuid + "/perfums", Token: token, Status: http.StatusOK},
+			routeCase{Name: e.Name + "Unauthorized", Method: "GET", Path: "/" + e.Path + "/" + uuid, Status: http.StatusUnauthorized},
+		)
+		if e.SearchKey != "" {
+			cases = append(cases,
+				routeCase{Name: e.PluralName + "FindById", Method: "GET", Path: "/" + e.PluralPath + "/find?" + e.SearchKey + "_id=" + uuid, Token: token, Status: http.StatusOK},
+				routeCase{Name: e.PluralName + "FindInvalidId", Method: "GET", Path: "/" + e.PluralPath + "/find?" + e.SearchKey + "_id=abc", Token: token, Status: http.StatusBadRequest},
+				routeCase{Name: e.PluralName + "FindByName", Method: "GET", Path: "/" + e.PluralPath + "/find?" + e.SearchKey + "=" + url.QueryEscape(name), Token: token, Status: http.StatusOK},
+			)
+		}
+	}
+	return cases
+}
+
+func TestRoutes(t *testing.T) {
+	setupRouteTests(t)
+	token, _ := mintTokens(t, TEST_USER_ID)
+	otherToken, _ := mintTokens(t, TEST_OTHER_USER_ID)
+	deletedToken, _ := mintTokens(t, "test-user-deleted")
+	_, refreshToken := mintTokens(t, "test-user-refresh")
+
+	user := "/user/" + TEST_USER_ID
+	cases := []routeCase{
+		// public
+		{Name: "LoginNoIdToken", Method: "POST", Path: "/login", Form: url.Values{}, Status: http.StatusBadRequest},
+		{Name: "LoginInvalidIdToken", Method: "POST", Path: "/login", Form: url.Values{"id_token": {"not-a-jwt"}}, Status: http.StatusBadRequest},
+		{Name: "Login", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdToken(t, "test-user-login", nil)}}, Status: http.StatusOK},
+		{Name: "LoginExistingUser", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdToken(t, "test-user-login", nil)}}, Status: http.StatusOK},
+		{Name: "LoginExpiredIdToken", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdToken(t, "test-user-login", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})}}, Status: http.StatusBadRequest},
+		{Name: "LoginOtherAudience", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdToken(t, "test-user-login", jwt.MapClaims{"aud": "other-project"})}}, Status: http.StatusBadRequest},
+		{Name: "LoginOtherIssuer", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdToken(t, "test-user-login", jwt.MapClaims{"iss": secureTokenIssuer + "fragrances-test"})}}, Status: http.StatusBadRequest},
+		{Name: "LoginUnknownKey", Method: "POST", Path: "/login", Form: url.Values{"id_token": {testIssuer.mustIdTokenOfKey(t, "unknown-key")}}, Status: http.StatusBadRequest},
+		{Name: "Token", Method: "POST", Path: "/token", Form: url.Values{"grant_type": {"refresh_token"}, "client_id": {TEST_CLIENT_ID}, "refresh_token": {refreshToken}}, Status: http.StatusOK},
+		{Name: "TokenNoGrantType", Method: "POST", Path: "/token", Form: url.Values{"client_id": {TEST_CLIENT_ID}}, Status: http.StatusBadRequest},
+		{Name: "TokenUnsupportedGrantType", Method: "POST", Path: "/token", Form: url.Values{"grant_type": {"password"}, "client_id": {TEST_CLIENT_ID}}, Status: http.StatusBadRequest},
+		{Name: "TokenUnknownClient", Method: "POST", Path: "/token", Form: url.Values{"grant_type": {"refresh_token"}, "client_id": {"other"}, "refresh_token": {refreshToken}}, Status: http.StatusBadRequest},
+		{Name: "TokenInvalidRefreshToken", Method: "POST", Path: "/token", Form: url.Values{"grant_type": {"refresh_token"}, "client_id": {TEST_CLIENT_ID}, "refresh_token": {"not-a-jwt"}}, Status: http.StatusUnauthorized},
+
+		// access token
+		{Name: "NoAccessToken", Method: "GET", Path: "/perfums", Status: http.StatusUnauthorized},
+		{Name: "InvalidAccessToken", Method: "GET", Path: "/perfums", Token: "not-a-jwt", Status: http.StatusUnauthorized},
+
+		// user
+		{Name: "User", Method: "GET", Path: user, Token: token, Status: http.StatusOK},
+		{Name: "UserForbidden", Method: "GET", Path: user, Token: otherToken, Status: http.StatusForbidden},
+		{Name: "UserDeleteForbidden", Method: "DELETE", Path: user, Token: otherToken, Status: http.StatusForbidden},
+		{Name: "UserDelete", Method: "DELETE", Path: "/user/test-user-deleted", Token: deletedToken, Status: http.StatusOK},
+		{Name: "UserDeletedToken", Method: "GET", Path: "/user/test-user-deleted", Token: deletedToken, Status: http.StatusUnauthorized},
+		{Name: "LogoutForbidden", Method: "PUT", Path: user + "/logout", Token: otherToken, Status: http.StatusForbidden},
+
+		// favorites
+		{Name: "Favorites", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+		{Name: "FavoritesForbidden", Method: "GET", Path: user + "/favorites", Token: otherToken, Status: http.StatusForbidden},
+		{Name: "FavoritesAdd", Method: "POST", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {TEST_PERFUM_DIOR}}, Status: http.StatusCreated},
+		{Name: "FavoritesAddNone", Method: "POST", Path: user + "/favorites", Token: token, Form: url.Values{}, Status: http.StatusBadRequest},
+		{Name: "FavoritesAddInvalid", Method: "POST", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {"perfum"}}, Status: http.StatusBadRequest},
+		{Name: "FavoritesAdded", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+		{Name: "FavoritesReplace", Method: "PUT", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {TEST_PERFUM_CHANEL + "," + TEST_PERFUM_DIOR}}, Status: http.StatusOK},
+		{Name: "FavoritesReplaceInvalid", Method: "PUT", Path: user + "/favorites", Token: token, Form: url.Values{"perfum_id": {"perfum"}}, Status: http.StatusBadRequest},
+		{Name: "FavoritesRemove", Method: "DELETE", Path: user + "/favorites?perfum_id=" + TEST_PERFUM_DIOR, Token: token, Status: http.StatusOK},
+		{Name: "FavoritesRemoveForbidden", Method: "DELETE", Path: user + "/favorites", Token: otherToken, Status: http.StatusForbidden},
+		{Name: "FavoritesRemoved", Method: "GET", Path: user + "/favorites", Token: token, Status: http.StatusOK},
+
+		// recommendations
+		{Name: "Recommendations", Method: "GET", Path: user + "/recommendations", Token: token, Status: http.StatusOK},
+		{Name: "RecommendationsForbidden", Method: "GET", Path: user + "/recommendations", Token: otherToken, Status: http.StatusForbidden},
+		{Name: "RecommendationsInvalidFilter", Method: "GET", Path: user + "/recommendations?gender_id=x", Token: token, Status: http.StatusBadRequest},
+
+		// perfums
+		{Name: "Perfums", Method: "GET", Path: "/perfums", Token: token, Status: http.StatusOK},
+		{Name: "PerfumsPaged", Method: "GET", Path: "/perfums?limit=2&offset=1&lang=en", Token: token, Status: http.StatusOK},
+		{Name: "PerfumsNegativeOffset", Method: "GET", Path: "/perfums?limit=1&offset=-2", Token: token, Status: http.StatusOK},
+		{Name: "PerfumsFind", Method: "GET", Path: "/perfums/find?name=Shalimar", Token: token, Status: http.StatusOK},
+		{Name: "PerfumsFindPrefix", Method: "GET", Path: "/perfums/find?brand=guer&cm=bw", Token: token, Status: http.StatusOK},
+		{Name: "PerfumsFindComponent", Method: "GET", Path: "/perfums/find?component=" + url.QueryEscape("Роза"), Token: token, Status: http.StatusOK},
+		{Name: "PerfumsFindNothing", Method: "GET", Path: "/perfums/find?name=Unknown", Token: token},
+		{Name: "PerfumsFindInvalidId", Method: "GET", Path: "/perfums/find?id=abc", Token: token, Status: http.StatusBadRequest},
+		{Name: "PerfumsInvalidId", Method: "GET", Path: "/perfums?id=abc", Token: token, Status: http.StatusBadRequest},
+		{Name: "Perfum", Method: "GET", Path: "/perfum/" + TEST_PERFUM_CHANEL, Token: token, Status: http.StatusOK},
+		{Name: "PerfumWithImage", Method: "GET", Path: "/perfum/" + TEST_PERFUM_SHALIMAR + "?lang=en", Token: token, Status: http.StatusOK},
+		{Name: "PerfumUnknown", Method: "GET", Path: "/perfum/" + TEST_UNKNOWN_UUID, Token: token, Status: http.StatusNotFound},
+		{Name: "PerfumInvalidId", Method: "GET", Path: "/perfum/abc", Token: token, Status: http.StatusNotFound},
+		{Name: "PerfumOffers", Method: "GET", Path: "/perfum/" + TEST_PERFUM_CHANEL + "/offers", Token: token, Status: http.StatusOK},
+		{Name: "PerfumOffersUnknown", Method: "GET", Path: "/perfum/" + TEST_UNKNOWN_UUID + "/offers", Token: token, Status: http.StatusNotFound},
+		{Name: "PerfumOffersInvalidId", Method: "GET", Path: "/perfum/abc/offers", Token: token, Status: http.StatusNotFound},
+		{Name: "PerfumSimilar", Method: "GET", Path: "/perfum/" + TEST_PERFUM_CHANEL + "/similar", Token: token, Status: http.StatusOK},
+		{Name: "PerfumSimilarUnknown", Method: "GET", Path: "/perfum/" + TEST_UNKNOWN_UUID + "/similar", Token: token, Status: http.StatusNotFound},
+		{Name: "PerfumSimilarInvalidId", Method: "GET", Path: "/perfum/abc/similar", Token: token, Status: http.StatusNotFound},
+
+		// reviews
+		{Name: "Reviews", Method: "GET", Path: "/perfum/" + TEST_PERFUM_CHANEL + "/reviews", Token: token, Status: http.StatusOK},
+		{Name: "ReviewsUnknown", Method: "GET", Path: "/perfum/" + TEST_UNKNOWN_UUID + "/reviews", Token: token, Status: http.StatusNotFound},
+		{Name: "ReviewsInvalidId", Method: "GET", Path: "/perfum/abc/reviews", Token: token, Status: http.StatusNotFound},
+		{Name: "ReviewCreateInvalidId", Method: "POST", Path: "/perfum/abc/reviews", Token: token, Form: url.Values{"score": {"4"}}, Status: http.StatusNotFound},
+		{Name: "ReviewCreate", Method: "POST", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Form: url.Values{"score": {"4"}, "text": {"Green and bright"}}, Status: http.StatusCreated},
+		{Name: "ReviewCreateExisting", Method: "POST", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Form: url.Values{"score": {"3"}}, Status: http.StatusConflict},
+		{Name: "ReviewCreateNoScore", Method: "POST", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: otherToken, Form: url.Values{"text": {"no score"}}, Status: http.StatusBadRequest},
+		{Name: "ReviewCreateInvalidScore", Method: "POST", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: otherToken, Form: url.Values{"score": {"9"}}, Status: http.StatusBadRequest},
+		{Name: "ReviewCreateUnknownPerfum", Method: "POST", Path: "/perfum/" + TEST_UNKNOWN_UUID + "/reviews", Token: token, Form: url.Values{"score": {"4"}}, Status: http.StatusNotFound},
+		{Name: "ReviewUpdate", Method: "PUT", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Form: url.Values{"sillage": {"3"}}, Status: http.StatusOK},
+		{Name: "ReviewUpdateInvalid", Method: "PUT", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Form: url.Values{"longevity": {"0"}}, Status: http.StatusBadRequest},
+		{Name: "ReviewUpdateMissing", Method: "PUT", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: otherToken, Form: url.Values{"score": {"2"}}, Status: http.StatusNotFound},
+		{Name: "ReviewsCreated", Method: "GET", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Status: http.StatusOK},
+		{Name: "ReviewDelete", Method: "DELETE", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Status: http.StatusOK},
+		{Name: "ReviewDeleteMissing", Method: "DELETE", Path: "/perfum/" + TEST_PERFUM_DIOR + "/reviews", Token: token, Status: http.StatusNotFound},
+
+		// images
+		{Name: "ImageSmall", Method: "GET", Path: "/image/" + testImageUuid + "/small", Token: token, Status: http.StatusOK},
+		{Name: "ImageLarge", Method: "GET", Path: "/image/" + testImageUuid + "/large", Token: token, Status: http.StatusOK},
+		{Name: "ImageSmallUnknown", Method: "GET", Path: "/image/" + TEST_UNKNOWN_UUID + "/small", Token: token, Status: http.StatusNotFound},
+		{Name: "ImageLargeUnknown", Method: "GET", Path: "/image/" + TEST_UNKNOWN_UUID + "/large", Token: token, Status: http.StatusNotFound},
+		{Name: "ImageSmallInvalidId", Method: "GET", Path: "/image/abc/small", Token: token, Status: http.StatusNotFound},
+	}
+	cases = append(cases, entityCases(t, token)...)
+	// logout invalidates the token of the other user, so it goes last
+	cases = append(cases, routeCase{Name: "Logout", Method: "PUT", Path: "/user/" + TEST_OTHER_USER_ID + "/logout", Token: otherToken, Status: http.StatusOK})
+	// totals of the fixtures and of the changes of the previous cases
+	totals := map[string]int64{
+		"Perfums": 4, "PerfumsPaged": 4, "PerfumsFind": 1, "PerfumsFindPrefix": 2, "PerfumsFindComponent": 3,
+		"Reviews": 2, "ReviewsCreated": 1, "Favorites": 2, "FavoritesAdded": 3, "FavoritesRemoved": 1,
+	}
+
+	matcher := newRouteMatcher()
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			testRoutesHit[matcher.match(t, c)] = true
+			status, body := doRequest(t, c)
+			if c.Status != 0 && status != c.Status {
+				t.Errorf("status is %d, expected %d: %s", status, c.Status, body)
+			}
+			if total, ok := totals[c.Name]; ok {
+				checkListTotal(t, c.Name, body, total)
+			}
+			checkGolden(t, c.Name, status, body)
+		})
+	}
+}
+
+// TestRoutesCovered checks every public and private route has a case
+func TestRoutesCovered(t *testing.T) {
+	if len(testRoutesHit) == 0 {
+		t.Skip("TestRoutes is not run")
+	}
+	routes := append(append(Routes{}, publicRoutes...), privateRoutes...)
+	for _, route := range routes {
+		if key := route.Method + " " + API_PATH + route.Pattern; !testRoutesHit[key] {
+			t.Errorf("route %s %s is not tested", route.Name, key)
+		}
+	}
+}
+
+// routeMatcher finds the public or private route a request is handled by
+type routeMatcher struct {
+	router *mux.Router
+}
+
+func newRouteMatcher() *routeMatcher {
+	router := mux.NewRouter()
+	for _, route := range append(append(Routes{}, publicRoutes...), privateRoutes...) {
+		router.Methods(route.Method).Path(API_PATH + route.Pattern).Handler(route.Endpoint)
+	}
+	return &routeMatcher{router: router}
+}
+
+func (rm *routeMatcher) match(t *testing.T, c routeCase) string {
+	t.Helper()
+	r := httptest.NewRequest(c.Method, API_PATH+c.Path, nil)
+	var match mux.RouteMatch
+	if !rm.router.Match(r, &match) || match.Route == nil {
+		t.Fatalf("no route for %s %s", c.Method, c.Path)
+	}
+	template, _ := match.Route.GetPathTemplate()
+	return c.Method + " " + template
+}
+
+// checkListTotal checks the total of the list response body of case name
+func checkListTotal(t *testing.T, name string, body []byte, total int64) {
+	t.Helper()
+
+	var list struct {
+		Total int64 `json:"total"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("%s: %v: %s", name, err, body)
+	}
+	if list.Total != total {
+		t.Errorf("%s: total is %d, expected %d: %s", name, list.Total, total, body)
+	}
+}
+
+// writeTestImageFiles writes the small and large image files of the test
+// image in dataDir
+func writeTestImageFiles(dataDir string) error {
+	for _, size := range []string{"small", "large"} {
+		dir := filepath.Join(dataDir, "images", size)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "shalimar.txt"), []byte(size+" image of shalimar\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func doRequest(t *testing.T, c routeCase) (int, []byte) {
+	t.Helper()
+
+	var body io.Reader
+	if c.Form != nil {
+		body = strings.NewReader(c.Form.Encode())
+	}
+	r, err := http.NewRequest(c.Method, testServer.URL+API_PATH+c.Path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, b
+}
+
+// normalize replaces volatile values and uuids of a json response, uuids
+// are numbered in order of appearance so references between them are kept
+func normalize(body []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+
+	uuids := map[string]string{}
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if volatileKeys[key] {
+					value[key] = "<" + key + ">"
+					continue
+				}
+				value[key] = walk(value[key])
+			}
+		case []interface{}:
+			for i := range value {
+				value[i] = walk(value[i])
+			}
+		case string:
+			return uuidPattern.ReplaceAllStringFunc(value, func(uuid string) string {
+				if _, ok := uuids[uuid]; !ok {
+					uuids[uuid] = "<uuid-" + strconv.Itoa(len(uuids)+1) + ">"
+				}
+				return uuids[uuid]
+			})
+		}
+		return v
+	}
+	return walk(v)
+}
+
+func checkGolden(t *testing.T, name string, status int, body []byte) {
+	t.Helper()
+
+	actual, err := json.MarshalIndent(map[string]interface{}{"status": status, "body": normalize(body)}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s is missing, record it with -update", path)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("response differs from %s\nexpected:\n%s\nactual:\n%s", path, expected, actual)
+	}
+}
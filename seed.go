@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp/v3"
+)
+
+// SEED_DIR is the directory of fixtures in the repo dir, used if seed is run
+// without one
+const SEED_DIR = "fixtures"
+
+// Fixtures of a seed directory, every file is optional:
+//
+//	<table>.csv      reference table of entityRegistry (brands.csv, notes.csv, ...)
+//	                 with columns id, name columns of the table (name or
+//	                 name_ru and name_en) and, for tables with images,
+//	                 small_image and large_image
+//	perfums.json     catalogue of perfums, see CatalogueItem
+//	users.csv        user_id
+//	offers.csv       offer feed, see ImportOffersCSV
+//	reviews.csv      perfum_id, user_id, score, longevity, sillage, text
+//	favorites.csv    user_id, perfum_id
+//
+// Rows without id get a uuid derived from their name and perfums without id
+// are found by name and brand, so seeding the same fixtures again updates the
+// rows instead of duplicating them. References to reference tables and
+// perfums are uuids or names. Image paths are relative to the data dir.
+
+// SeedResult is the number of rows upserted per table
+type SeedResult map[string]int
+
+// Seed loads the fixtures of dir in one transaction
+func Seed(ctx context.Context, dir string) (SeedResult, error) {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SeedResult{}
+	steps := []func(*gorp.Transaction, string, SeedResult) error{seedUsers}
+	for _, e := range entityRegistry {
+		steps = append(steps, seedEntity(e))
+	}
+	steps = append(steps, seedPerfums, seedOffers, seedReviews, seedFavorites)
+
+	for _, step := range steps {
+		if err := step(tx, dir, result); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// seedUuid returns the name based uuid (version 5) of a fixture row
+func seedUuid(parts ...string) string {
+	sum := sha1.Sum([]byte("fragrancesapi/seed/" + strings.Join(parts, "/")))
+	b := sum[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// readSeedCsv returns the records of the csv file keyed by column names, nil
+// if the file does not exist
+func readSeedCsv(path string) ([]map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(record))
+		for i, name := range records[0] {
+			row[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func seedUsers(tx *gorp.Transaction, dir string, result SeedResult) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "users.csv"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i, row := range rows {
+		if row["user_id"] == "" {
+			return fmt.Errorf("users.csv line %d: user_id is empty", i+2)
+		}
+		if _, err := tx.Exec("INSERT INTO users (user_id, created_at, updated_at) VALUES ($1, $2, $2) "+
+			"ON CONFLICT (user_id) DO NOTHING", row["user_id"], now); err != nil {
+			return err
+		}
+		result["users"]++
+	}
+	return nil
+}
+
+// seedEntity returns the step upserting rows of the reference table of e
+func seedEntity(e *EntityDesc) func(*gorp.Transaction, string, SeedResult) error {
+	return func(tx *gorp.Transaction, dir string, result SeedResult) error {
+		file := e.Table + ".csv"
+		rows, err := readSeedCsv(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+
+		columns := nameColumns(e)
+		for i, row := range rows {
+			values := []interface{}{}
+			for _, column := range columns {
+				if row[column] == "" {
+					return fmt.Errorf("%s line %d: %s is empty", file, i+2, column)
+				}
+				values = append(values, row[column])
+			}
+
+			uuid := row["id"]
+			if uuid == "" {
+				uuid = seedUuid(e.Table, row[columns[len(columns)-1]])
+			} else if !uuidRegex.MatchString(uuid) {
+				return fmt.Errorf("%s line %d: invalid id %q", file, i+2, uuid)
+			}
+
+			insertColumns := append([]string{"uuid"}, columns...)
+			values = append([]interface{}{uuid}, values...)
+			if e.HasImage && (row["small_image"] != "" || row["large_image"] != "") {
+				imageId, err := tx.SelectNullInt("SELECT image_id FROM "+e.Table+" WHERE uuid=$1", uuid)
+				if err != nil {
+					return err
+				}
+				if imageId.Int64, err = saveImage(tx, imageId, CatalogueImage{Small: row["small_image"], Large: row["large_image"]}); err != nil {
+					return err
+				}
+				imageId.Valid = true
+				insertColumns = append(insertColumns, "image_id")
+				values = append(values, imageId)
+			}
+
+			placeholders := make([]string, len(insertColumns))
+			updates := make([]string, 0, len(insertColumns)-1)
+			for j, column := range insertColumns {
+				placeholders[j] = "$" + strconv.Itoa(j+1)
+				if column != "uuid" {
+					updates = append(updates, column+"=EXCLUDED."+column)
+				}
+			}
+			if _, err := tx.Exec("INSERT INTO "+e.Table+" ("+strings.Join(insertColumns, ", ")+") VALUES ("+
+				strings.Join(placeholders, ", ")+") ON CONFLICT (uuid) DO UPDATE SET "+strings.Join(updates, ", "),
+				values...); err != nil {
+				return err
+			}
+			result[e.Table]++
+		}
+		return nil
+	}
+}
+
+func seedPerfums(tx *gorp.Transaction, dir string, result SeedResult) error {
+	file, err := os.Open(filepath.Join(dir, "perfums.json"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	items, err := ReadCatalogue(file, CATALOGUE_FORMAT_JSON)
+	if err != nil {
+		return fmt.Errorf("perfums.json: %v", err)
+	}
+	perfums, err := importCatalogue(tx, items)
+	if err != nil {
+		return fmt.Errorf("perfums.json: %v", err)
+	}
+	if len(perfums.Errors) > 0 {
+		return fmt.Errorf("perfums.json item %d: %s", perfums.Errors[0].Item, perfums.Errors[0].Error)
+	}
+	result["parfum_info"] += perfums.Inserted + perfums.Updated
+	return nil
+}
+
+func seedOffers(tx *gorp.Transaction, dir string, result SeedResult) error {
+	file, err := os.Open(filepath.Join(dir, "offers.csv"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offers, err := importOffersCSV(txOfferTable{tx}, file)
+	if err != nil {
+		return fmt.Errorf("offers.csv: %v", err)
+	}
+	if len(offers.Errors) > 0 {
+		return fmt.Errorf("offers.csv: %v", offers.Errors[0])
+	}
+	result["offers"] += offers.Inserted + offers.Updated
+	return nil
+}
+
+// seedPerfumId resolves the perfum_id column of a fixture row
+func seedPerfumId(tx *gorp.Transaction, ref string) (int64, error) {
+	if ref == "" {
+		return 0, fmt.Errorf("perfum_id is empty")
+	}
+	column := "name"
+	if uuidRegex.MatchString(ref) {
+		column = "uuid"
+	}
+	id, err := tx.SelectInt("SELECT id FROM parfum_info WHERE "+column+"=$1 ORDER BY id LIMIT 1", ref)
+	if err != nil {
+		return 0, err
+	} else if id == 0 {
+		return 0, fmt.Errorf("perfum %q is not found", ref)
+	}
+	return id, nil
+}
+
+func seedReviews(tx *gorp.Transaction, dir string, result SeedResult) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "reviews.csv"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i, row := range rows {
+		review := ReviewDB{UserId: row["user_id"], CreatedAt: now, UpdatedAt: now}
+		if review.PerfumInfoId, err = seedPerfumId(tx, row["perfum_id"]); err != nil {
+			return fmt.Errorf("reviews.csv line %d: %v", i+2, err)
+		}
+		if review.Score, err = strconv.ParseInt(row["score"], 10, 64); err != nil {
+			return fmt.Errorf("reviews.csv line %d: invalid score %q", i+2, row["score"])
+		}
+		for column, field := range map[string]*sql.NullInt64{"longevity": &review.Longevity, "sillage": &review.Sillage} {
+			if row[column] == "" {
+				continue
+			}
+			if field.Int64, err = strconv.ParseInt(row[column], 10, 64); err != nil {
+				return fmt.Errorf("reviews.csv line %d: invalid %s %q", i+2, column, row[column])
+			}
+			field.Valid = true
+		}
+		if row["text"] != "" {
+			review.Text = sql.NullString{String: row["text"], Valid: true}
+		}
+
+		if _, err := tx.Exec("INSERT INTO reviews (uuid, parfum_info_id, user_id, score, longevity, sillage, text, created_at, updated_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8) ON CONFLICT (parfum_info_id, user_id) DO UPDATE SET "+
+			"score=EXCLUDED.score, longevity=EXCLUDED.longevity, sillage=EXCLUDED.sillage, text=EXCLUDED.text, updated_at=EXCLUDED.updated_at",
+			seedUuid("reviews", row["perfum_id"], review.UserId), review.PerfumInfoId, review.UserId,
+			review.Score, review.Longevity, review.Sillage, review.Text, now); err != nil {
+			return fmt.Errorf("reviews.csv line %d: %v", i+2, err)
+		}
+		result["reviews"]++
+	}
+	return nil
+}
+
+func seedFavorites(tx *gorp.Transaction, dir string, result SeedResult) error {
+	rows, err := readSeedCsv(filepath.Join(dir, "favorites.csv"))
+	if err != nil {
+		return err
+	}
+
+	// rows are favorited a second apart in file order, all before now, so
+	// favorites added later are listed first
+	now := time.Now().Unix()
+	for i, row := range rows {
+		createdAt := now - int64(len(rows)-i)
+		perfumId, err := seedPerfumId(tx, row["perfum_id"])
+		if err != nil {
+			return fmt.Errorf("favorites.csv line %d: %v", i+2, err)
+		}
+		if _, err := tx.Exec("INSERT INTO favorites (user_id, parfum_info_id, created_at) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (user_id, parfum_info_id) DO NOTHING", row["user_id"], perfumId, createdAt); err != nil {
+			return fmt.Errorf("favorites.csv line %d: %v", i+2, err)
+		}
+		result["favorites"]++
+	}
+	return nil
+}
+
+// runSeed loads the fixtures of dir and reports upserted rows to out
+func runSeed(ctx context.Context, dir string, out io.Writer) error {
+	if dir == "" {
+		dir = filepath.Join(config.Paths.RepoDir, SEED_DIR)
+	}
+	result, err := Seed(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]string, 0, len(result))
+	for table := range result {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(out, "%s\t%d\n", table, result[table])
+	}
+	return nil
+}
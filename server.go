@@ -1,31 +1,230 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	_ "gopkg.in/gorp.v1"
-	"log"
+	_ "github.com/go-gorp/gorp/v3"
+	"net"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 )
 
-var err error
+var configFile = flag.String("config", os.Getenv("FRAGRANCES_CONFIG"), "path of the YAML config file")
+
+const usage = `Usage: %s [flags] [command]
+
+Serves the API, or runs the command and exits:
+
+  migrate up|down [steps]|status   apply, revert or list schema migrations
+  seed [dir]                       load fixtures, the repo fixtures by default
+  catalogue import|export file     import or export the perfum catalogue,
+                                   json or csv by the file extension
+  offers import file               import the offers of a CSV feed
+
+`
+
+// commands run instead of the server, with the database opened
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"migrate": func(ctx context.Context, args []string) error {
+		return runMigrate(ctx, dbmap.Db, args, os.Stdout)
+	},
+	"seed": func(ctx context.Context, args []string) error {
+		dir := ""
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		return runSeed(ctx, dir, os.Stdout)
+	},
+	"catalogue": func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errors.New("catalogue needs import or export and a file")
+		}
+		return runCatalogue(ctx, args[0], args[1])
+	},
+	"offers": func(ctx context.Context, args []string) error {
+		if len(args) != 2 || args[0] != "import" {
+			return errors.New("offers needs import and a file")
+		}
+		return runOffersImport(ctx, args[1])
+	},
+}
 
 func main() {
-	dbmap := InitDb()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	command, found := commands[flag.Arg(0)]
+	if flag.NArg() > 0 && !found {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := LoadConfig(*configFile)
+	if err != nil {
+		TraceFatalError(err)
+	}
+	config = cfg
+	baseUrl = config.BaseUrl()
+	if err := InitAppLog(config.Log); err != nil {
+		TraceFatalError(err)
+	}
+	if err := InitAccessLog(config.Log); err != nil {
+		TraceFatalError(err)
+	}
+	if err := LoadOAuthCredentials(config.Paths.DataDir); err != nil {
+		TraceFatalError(err)
+	}
+
+	// cancelled on SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if command != nil {
+		if err := runCommand(ctx, command, flag.Args()[1:]); err != nil {
+			TraceFatalError(err)
+		}
+		return
+	}
+
+	shutdownTracing, err := InitTracing(config.Tracing)
+	if err != nil {
+		TraceFatalError(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// background jobs are stopped before the database is closed
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	dbmap, err := InitDb(jobsCtx)
+	if err != nil {
+		TraceFatalError(err)
+	}
 	defer dbmap.Db.Close()
-	api := NewRouter()
-	bind := fmt.Sprintf("%s:%s", apiHost, apiPort)
+	defer backgroundJobs.Wait()
+	defer stopJobs()
 
+	server := &http.Server{
+		Addr:         net.JoinHostPort(config.Server.Host, config.Server.Port),
+		Handler:      NewRouter(),
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
+		IdleTimeout:  config.Server.IdleTimeout,
+	}
+	gracePeriod := config.Server.ShutdownGracePeriod
+
+	debugServer := NewDebugServer()
+	if debugServer != nil {
+		go func() {
+			appLog.Info("debug server started", F("bind", debugServer.Addr))
+			if err := debugServer.ListenAndServe(); err != http.ErrServerClosed {
+				appLog.Error("debug server stopped", F("error", err))
+			}
+		}()
+	}
+
+	serverErr := make(chan error, 1)
 	go func() {
-		// for pprof
-		log.Println(http.ListenAndServe(":6060", nil))
+		appLog.Info("server started", F("bind", server.Addr))
+		serverErr <- server.ListenAndServe()
 	}()
 
-	log.Println("Server started at:", bind)
-	err = http.ListenAndServe(bind, api)
+	select {
+	case err := <-serverErr:
+		TraceFatal("couldn't start server at " + server.Addr + ": " + err.Error())
+	case <-ctx.Done():
+	}
+	stop()
+
+	appLog.Info("server is shutting down", F("grace_period", gracePeriod.String()))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		appLog.Error("in-flight requests are not drained", F("error", err))
+	}
+	if debugServer != nil {
+		debugServer.Close()
+	}
+	appLog.Info("server stopped")
+}
+
+// runCommand opens the database without background jobs and runs command
+func runCommand(ctx context.Context, command func(context.Context, []string) error, args []string) error {
+	db, err := OpenDb(ctx, config.Database)
 	if err != nil {
-		log.Fatalln("Couldn't start server at:", bind)
-		os.Exit(-1)
+		return err
+	}
+	defer db.Close()
+	dbmap = NewDbMap(db)
+	store = NewPgStore()
+	return command(ctx, args)
+}
+
+func runCatalogue(ctx context.Context, command, path string) error {
+	format := CATALOGUE_FORMAT_JSON
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = CATALOGUE_FORMAT_CSV
+	}
+
+	switch command {
+	case "import":
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		items, err := ReadCatalogue(file, format)
+		if err != nil {
+			return err
+		}
+		result, err := store.Catalogue.Import(ctx, items)
+		if err != nil {
+			return err
+		}
+		for _, itemErr := range result.Errors {
+			appLog.Warn("catalogue item is skipped", F("item", itemErr.Item), F("error", itemErr.Error))
+		}
+		appLog.Info("catalogue is imported", F("inserted", result.Inserted), F("updated", result.Updated), F("skipped", len(result.Errors)))
+		return nil
+	case "export":
+		items, err := store.Catalogue.Export(ctx)
+		if err != nil {
+			return err
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := WriteCatalogue(file, items, format); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+	return errors.New("unknown catalogue command " + command + ", use import or export")
+}
+
+func runOffersImport(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := ImportOffersCSV(ctx, file)
+	if err != nil {
+		return err
+	}
+	for _, rowErr := range result.Errors {
+		appLog.Warn("offer is skipped", F("line", rowErr.Line), F("error", rowErr.Err))
 	}
+	appLog.Info("offers are imported", F("inserted", result.Inserted), F("updated", result.Updated), F("skipped", len(result.Errors)))
+	return nil
 }
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+// Weights of the similarity score. A component shared in the same note counts
+// more than a component found in different notes of two perfums.
+const (
+	SIMILARITY_NOTE_COMPONENT_WEIGHT = 3
+	SIMILARITY_COMPONENT_WEIGHT      = 1
+	SIMILARITY_GROUP_WEIGHT          = 4
+	SIMILARITY_TYPE_WEIGHT           = 2
+	SIMILARITY_SEASON_WEIGHT         = 1
+)
+
+// similarityScoreRecord ...
+type similarityScoreRecord struct {
+	Uuid           string `db:"info_uuid"`
+	NoteComponents int64  `db:"note_components"`
+	Components     int64  `db:"components"`
+	SameGroup      int64  `db:"same_group"`
+	SameType       int64  `db:"same_type"`
+	SameSeason     int64  `db:"same_season"`
+	Score          int64  `db:"score"`
+}
+
+// similarityScore returns the score of the shared components and references
+// of r by the weights above
+func similarityScore(r *similarityScoreRecord) int64 {
+	return r.NoteComponents*SIMILARITY_NOTE_COMPONENT_WEIGHT +
+		r.Components*SIMILARITY_COMPONENT_WEIGHT +
+		r.SameGroup*SIMILARITY_GROUP_WEIGHT +
+		r.SameType*SIMILARITY_TYPE_WEIGHT +
+		r.SameSeason*SIMILARITY_SEASON_WEIGHT
+}
+
+// SimilarPerfumV1 is a perfum with its similarity score and the explanation:
+// notes with components shared with the requested perfum.
+type SimilarPerfumV1 struct {
+	PerfumInfoV1
+	Score            int64        `json:"score"`
+	SharedComponents int64        `json:"shared_components"`
+	SameGroup        bool         `json:"same_group"`
+	SameType         bool         `json:"same_type"`
+	SameSeason       bool         `json:"same_season"`
+	SharedNotes      []NoteItemV1 `json:"shared_notes"`
+}
+
+// SimilarPerfumsV1 ...
+type SimilarPerfumsV1 struct {
+	ObjList []SimilarPerfumV1 `db:"-" json:"similar_perfums_list"`
+	Total   int64             `db:"-" json:"total"`
+	Offset  int64             `db:"-" json:"offset"`
+	Amount  int64             `db:"-" json:"amount"`
+}
+
+func NewSimilarPerfumsFactory(version string) Objecter {
+	switch version {
+	case "v1":
+		return &SimilarPerfumsV1{ObjList: make([]SimilarPerfumV1, 0)}
+	}
+
+	return nil
+}
+
+// MakeObj ranks perfums similar to params.Id
+func (obj *SimilarPerfumsV1) MakeObj(ctx context.Context, pParams interface{}) (Objecter, error) {
+	if pParams == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+	if params.Id == "" {
+		return nil, errors.New("invalid args")
+	}
+
+	offset := int64(DEFAULT_OFFSET)
+	if params.Base.Offset.Valid {
+		offset = params.Base.Offset.Int64
+	}
+	limit := config.Pagination.DefaultLimit
+	if params.Base.Limit.Valid {
+		limit = params.Base.Limit.Int64
+	}
+
+	scores, err := store.Perfums.Similar(ctx, params.Id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Total = params.Total
+	obj.Offset = offset
+	if len(scores) == 0 {
+		return obj, nil
+	}
+
+	uuids := make([]string, 0, len(scores))
+	for _, record := range scores {
+		uuids = append(uuids, record.Uuid)
+	}
+
+	infoParams := MakeObjParams{Base: params.Base}
+	infoParams.Base.Ids = NullSliceString{String: uuids, Valid: true}
+	infoParams.Base.Offset = NullInt64{Int64: 0, Valid: true}
+	infoParams.Base.Limit = NullInt64{Int64: int64(len(uuids)), Valid: true}
+	infos := &PerfumsInfoV1{ObjList: make([]PerfumInfoV1, 0)}
+	if _, err := infos.MakeObj(ctx, &infoParams); err != nil {
+		return nil, err
+	}
+	infoByUuid := make(map[string]PerfumInfoV1)
+	for _, info := range infos.ObjList {
+		infoByUuid[info.Uuid] = info
+	}
+
+	sharedNotes, err := obj.sharedNotes(ctx, params.Id, uuids, getNameFields(params.Base.Lang.String))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range scores {
+		info, ok := infoByUuid[record.Uuid]
+		if !ok {
+			continue
+		}
+		notes := sharedNotes[record.Uuid]
+		if notes == nil {
+			notes = []NoteItemV1{}
+		}
+		obj.ObjList = append(obj.ObjList, SimilarPerfumV1{
+			PerfumInfoV1:     info,
+			Score:            record.Score,
+			SharedComponents: record.Components,
+			SameGroup:        record.SameGroup == 1,
+			SameType:         record.SameType == 1,
+			SameSeason:       record.SameSeason == 1,
+			SharedNotes:      notes,
+		})
+	}
+	obj.Amount = int64(len(obj.ObjList))
+
+	return obj, nil
+}
+
+// sharedNotes returns notes with components that perfums uuids share with
+// the perfum uid, keyed by perfum uuid
+func (obj *SimilarPerfumsV1) sharedNotes(ctx context.Context, uid string, uuids []string, lf LangField) (map[string][]NoteItemV1, error) {
+	records, err := store.Perfums.SharedComposition(ctx, lf, uid, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make(map[string][]NoteItemV1)
+	for _, record := range records {
+		list := notes[record.PerfumInfoUuid]
+		if len(list) == 0 || list[len(list)-1].Id != record.NoteUuid {
+			note := NewNoteItemV1(record.NoteUuid, record.NoteName)
+			note.Links = noteEntity.Links(note.Id)
+			list = append(list, *note)
+		}
+		list[len(list)-1].AddComponentItem(NewComponentItemV1(record.ComponentUuid, record.ComponentName))
+		list[len(list)-1].ComponentCount++
+		notes[record.PerfumInfoUuid] = list
+	}
+
+	return notes, nil
+}
+
+func (obj *SimilarPerfumsV1) MakeExtraObj(ctx context.Context, params *MakeObjParams, uids []string) (Objecter, error) {
+	return obj, nil
+}
+
+// Count returns number of perfums similar to params.Id
+func (obj *SimilarPerfumsV1) Count(ctx context.Context, pParams interface{}) (int64, error) {
+	if pParams == nil {
+		return 0, errors.New("invalid args")
+	}
+
+	params := pParams.(*MakeObjParams)
+	return obj.ExtraCount(ctx, []string{params.Id})
+}
+
+func (obj *SimilarPerfumsV1) ExtraCount(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, errors.New("invalid args")
+	}
+
+	return store.Perfums.SimilarCount(ctx, uids[0])
+}
+
+func (obj *SimilarPerfumsV1) Json(w http.ResponseWriter, status int) error {
+	render := render.New()
+	return render.JSON(w, status, obj)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SqlExpr is a fragment of SQL, its values are ? placeholders bound to Args
+// in order. Placeholders are numbered when the statement is built.
+type SqlExpr struct {
+	Sql  string
+	Args []interface{}
+}
+
+// Expr returns the fragment sql with the values of its placeholders
+func Expr(sql string, args ...interface{}) SqlExpr {
+	return SqlExpr{Sql: sql, Args: args}
+}
+
+// IsEmpty is true for the fragment of no condition
+func (e SqlExpr) IsEmpty() bool {
+	return e.Sql == ""
+}
+
+// joinExprs joins non empty exprs with sep, each one in parentheses if wrap
+// is set and there are several of them
+func joinExprs(exprs []SqlExpr, sep string, wrap bool) SqlExpr {
+	var parts []SqlExpr
+	for _, e := range exprs {
+		if !e.IsEmpty() {
+			parts = append(parts, e)
+		}
+	}
+
+	var sql []string
+	var args []interface{}
+	for _, e := range parts {
+		if wrap && len(parts) > 1 {
+			sql = append(sql, "("+e.Sql+")")
+		} else {
+			sql = append(sql, e.Sql)
+		}
+		args = append(args, e.Args...)
+	}
+	return SqlExpr{Sql: strings.Join(sql, sep), Args: args}
+}
+
+// And joins the non empty conditions exprs
+func And(exprs ...SqlExpr) SqlExpr {
+	return joinExprs(exprs, " AND ", true)
+}
+
+// Or matches any of the non empty conditions exprs, they are not put in
+// parentheses, so they should not contain AND
+func Or(exprs ...SqlExpr) SqlExpr {
+	return joinExprs(exprs, " OR ", false)
+}
+
+// SelectQuery builds a SELECT statement
+type SelectQuery struct {
+	distinct bool
+	columns  []string
+	from     SqlExpr
+	joins    []SqlExpr
+	where    []SqlExpr
+	groupBy  []string
+	orderBy  []string
+	offset   *int64
+	limit    *int64
+}
+
+// Select starts the statement selecting columns
+func Select(columns ...string) *SelectQuery {
+	return &SelectQuery{columns: columns}
+}
+
+// Distinct selects distinct rows
+func (q *SelectQuery) Distinct() *SelectQuery {
+	q.distinct = true
+	return q
+}
+
+// From selects from table, which may have an alias
+func (q *SelectQuery) From(table string) *SelectQuery {
+	q.from = Expr(table)
+	return q
+}
+
+// FromSub selects from the rows of sub named alias
+func (q *SelectQuery) FromSub(sub *SelectQuery, alias string) *SelectQuery {
+	q.from = sub.subquery(alias)
+	return q
+}
+
+// Join adds the join kind ("LEFT JOIN", "INNER JOIN", ...) of table on the
+// condition on
+func (q *SelectQuery) Join(kind, table string, on SqlExpr) *SelectQuery {
+	q.joins = append(q.joins, Expr(kind+" "+table+" ON "+on.Sql, on.Args...))
+	return q
+}
+
+// JoinSub adds the join kind of the rows of sub named alias on the
+// condition on
+func (q *SelectQuery) JoinSub(kind string, sub *SelectQuery, alias string, on SqlExpr) *SelectQuery {
+	s := sub.subquery(alias)
+	q.joins = append(q.joins, Expr(kind+" "+s.Sql+" ON "+on.Sql, append(s.Args, on.Args...)...))
+	return q
+}
+
+// Where adds the condition cond, conditions are joined with AND. An empty
+// condition is skipped.
+func (q *SelectQuery) Where(cond SqlExpr) *SelectQuery {
+	if !cond.IsEmpty() {
+		q.where = append(q.where, cond)
+	}
+	return q
+}
+
+// GroupBy groups rows by columns
+func (q *SelectQuery) GroupBy(columns ...string) *SelectQuery {
+	q.groupBy = append(q.groupBy, columns...)
+	return q
+}
+
+// OrderBy sorts rows by columns, each one with its direction
+func (q *SelectQuery) OrderBy(columns ...string) *SelectQuery {
+	q.orderBy = append(q.orderBy, columns...)
+	return q
+}
+
+// Page skips offset rows and returns up to limit of them
+func (q *SelectQuery) Page(offset, limit int64) *SelectQuery {
+	q.offset, q.limit = &offset, &limit
+	return q
+}
+
+func (q *SelectQuery) subquery(alias string) SqlExpr {
+	e := q.Expr()
+	return Expr("("+e.Sql+") AS "+alias, e.Args...)
+}
+
+// Expr returns the statement with ? placeholders
+func (q *SelectQuery) Expr() SqlExpr {
+	var sql strings.Builder
+	var args []interface{}
+
+	sql.WriteString("SELECT ")
+	if q.distinct {
+		sql.WriteString("DISTINCT ")
+	}
+	sql.WriteString(strings.Join(q.columns, ", "))
+	sql.WriteString(" FROM ")
+	sql.WriteString(q.from.Sql)
+	args = append(args, q.from.Args...)
+	for _, join := range q.joins {
+		sql.WriteString(" ")
+		sql.WriteString(join.Sql)
+		args = append(args, join.Args...)
+	}
+	if where := And(q.where...); !where.IsEmpty() {
+		sql.WriteString(" WHERE ")
+		sql.WriteString(where.Sql)
+		args = append(args, where.Args...)
+	}
+	if len(q.groupBy) > 0 {
+		sql.WriteString(" GROUP BY ")
+		sql.WriteString(strings.Join(q.groupBy, ", "))
+	}
+	if len(q.orderBy) > 0 {
+		sql.WriteString(" ORDER BY ")
+		sql.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	if q.offset != nil {
+		sql.WriteString(" OFFSET ?")
+		args = append(args, *q.offset)
+	}
+	if q.limit != nil {
+		sql.WriteString(" LIMIT ?")
+		args = append(args, *q.limit)
+	}
+	return SqlExpr{Sql: sql.String(), Args: args}
+}
+
+// Build returns the statement with $n placeholders and its args
+func (q *SelectQuery) Build() (string, []interface{}) {
+	e := q.Expr()
+	return numberPlaceholders(e.Sql), e.Args
+}
+
+// numberPlaceholders replaces ? placeholders of sql with $1, $2, ...
+func numberPlaceholders(sql string) string {
+	var b strings.Builder
+	n := 0
+	for _, c := range sql {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SQL_LOG_DRIVER is the postgres driver logging queries at debug level
+const SQL_LOG_DRIVER = "postgres-log"
+
+func init() {
+	sql.Register(SQL_LOG_DRIVER, &loggedDriver{&pq.Driver{}})
+}
+
+// logQuery writes the query with its bind args and duration at debug level
+func logQuery(query string, args []driver.NamedValue, start time.Time, err error) {
+	if !appLog.Enabled(LOG_DEBUG) {
+		return
+	}
+
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		values = append(values, fmt.Sprintf("%v", arg.Value))
+	}
+	fields := []LogField{
+		F("query", query),
+		F("args", values),
+		F("duration_ms", float64(time.Since(start).Nanoseconds())/float64(time.Millisecond)),
+	}
+	if err != nil {
+		fields = append(fields, F("error", err))
+	}
+	appLog.Debug("sql", fields...)
+}
+
+type loggedDriver struct {
+	driver.Driver
+}
+
+func (d *loggedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{conn}, nil
+}
+
+// loggedConn logs queries executed on the wrapped connection. Optional
+// driver interfaces are passed through.
+type loggedConn struct {
+	driver.Conn
+}
+
+func (c *loggedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	logQuery(query, args, start, err)
+	return rows, err
+}
+
+func (c *loggedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	logQuery(query, args, start, err)
+	return res, err
+}
+
+func (c *loggedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *loggedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *loggedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *loggedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,4 @@
+SELECT parfum_info.id AS info_id, parfum_info.uuid AS info_uuid, parfum_info.name AS name, parfum_info.year AS info_year, descriptions.uuid AS description_uuid, descriptions.description_en AS description, brands.uuid AS brand_uuid, brands.name AS brand_name, gender.uuid AS gender_uuid, gender.name_en AS gender_name, groups.uuid AS group_uuid, groups.name_en AS group_name, countries.uuid AS country_uuid, countries.name_en AS country_name, seasons.uuid AS season_uuid, seasons.name_en AS season_name, times_of_day.uuid AS tsod_uuid, times_of_day.name_en AS tsod_name, types.uuid AS type_uuid, types.name_en AS type_name, images.uuid AS img_uuid, stars.uuid AS stars_uuid, shops.uuid AS shop_uuid, COALESCE(reviews_stats.score_avg, 0) AS score_avg, COALESCE(reviews_stats.longevity_avg, 0) AS longevity_avg, COALESCE(reviews_stats.sillage_avg, 0) AS sillage_avg, COALESCE(reviews_stats.reviews_count, 0) AS reviews_count FROM parfum_info LEFT JOIN descriptions ON parfum_info.description_id=descriptions.id LEFT JOIN brands ON parfum_info.brand_id=brands.id LEFT JOIN gender ON parfum_info.gender_id=gender.id LEFT JOIN groups ON parfum_info.group_id=groups.id LEFT JOIN countries ON parfum_info.country_id=countries.id LEFT JOIN types ON parfum_info.type_id=types.id LEFT JOIN seasons ON parfum_info.season_id=seasons.id LEFT JOIN times_of_day ON parfum_info.tsod_id=times_of_day.id LEFT JOIN shops ON parfum_info.shop_id=shops.id LEFT JOIN images ON parfum_info.image_id=images.id LEFT JOIN stars ON parfum_info.stars_id=stars.id LEFT JOIN (SELECT parfum_info_id, AVG(score) AS score_avg, AVG(longevity) AS longevity_avg, AVG(sillage) AS sillage_avg, COUNT(*) AS reviews_count FROM reviews GROUP BY parfum_info_id) AS reviews_stats ON reviews_stats.parfum_info_id=parfum_info.id INNER JOIN (SELECT parfum_info.uuid AS info_uuid FROM (SELECT DISTINCT parfum_info_id FROM parfums WHERE note_id=(SELECT id FROM notes WHERE (notes.uuid=$1))) AS parfum_ids INNER JOIN parfum_info ON parfum_ids.parfum_info_id=parfum_info.id) AS perfums_entity ON parfum_info.uuid=perfums_entity.info_uuid ORDER BY name ASC OFFSET $2 LIMIT $3
+-- $1 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
+-- $2 = 10
+-- $3 = 20
@@ -0,0 +1,7 @@
+SELECT DISTINCT perfum_info.info_uuid FROM parfums INNER JOIN notes ON parfums.note_id=notes.id INNER JOIN components ON parfums.component_id=components.id INNER JOIN (SELECT parfum_info.id, parfum_info.uuid AS info_uuid FROM parfum_info LEFT JOIN gender ON parfum_info.gender_id=gender.id WHERE (LOWER(parfum_info.name) LIKE LOWER($1)) AND (gender.uuid=$2)) AS perfum_info ON parfums.parfum_info_id=perfum_info.id WHERE (LOWER(notes.name_ru) LIKE LOWER($3)) AND (LOWER(components.name_ru) LIKE LOWER($4)) ORDER BY perfum_info.info_uuid ASC OFFSET $5 LIMIT $6
+-- $1 = "%Shalimar"
+-- $2 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
+-- $3 = "%Heart"
+-- $4 = "%Rose"
+-- $5 = 5
+-- $6 = 5
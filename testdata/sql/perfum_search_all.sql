@@ -0,0 +1,3 @@
+SELECT DISTINCT perfum_info.info_uuid FROM parfums INNER JOIN (SELECT parfum_info.id, parfum_info.uuid AS info_uuid FROM parfum_info) AS perfum_info ON parfums.parfum_info_id=perfum_info.id ORDER BY perfum_info.info_uuid ASC OFFSET $1 LIMIT $2
+-- $1 = 0
+-- $2 = 10
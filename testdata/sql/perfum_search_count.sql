@@ -0,0 +1,3 @@
+SELECT COUNT(DISTINCT(perfum_info.info_uuid)) FROM parfums INNER JOIN (SELECT parfum_info.id, parfum_info.uuid AS info_uuid FROM parfum_info LEFT JOIN seasons ON parfum_info.season_id=seasons.id WHERE LOWER(seasons.name_ru) LIKE LOWER($1)) AS perfum_info ON parfums.parfum_info_id=perfum_info.id WHERE parfums.uuid=$2
+-- $1 = "Spring"
+-- $2 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
@@ -0,0 +1 @@
+SELECT COUNT(*) FROM parfum_info
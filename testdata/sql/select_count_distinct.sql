@@ -0,0 +1,2 @@
+SELECT COUNT(DISTINCT(parfum_info_id)) FROM parfums WHERE note_id=(SELECT notes.id FROM notes WHERE (notes.uuid=$1))
+-- $1 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
@@ -0,0 +1,3 @@
+SELECT brands.id, brands.name AS name, brands.uuid AS entity_uuid, images.uuid AS img_uuid FROM brands LEFT OUTER JOIN images ON brands.image_id=images.id ORDER BY name ASC OFFSET $1 LIMIT $2
+-- $1 = 10
+-- $2 = 20
@@ -0,0 +1,5 @@
+SELECT notes.id, notes.name_ru AS name, notes.uuid AS entity_uuid FROM notes WHERE (notes.uuid=$1) AND (LOWER(notes.name_ru) LIKE LOWER($2)) ORDER BY name ASC OFFSET $3 LIMIT $4
+-- $1 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
+-- $2 = "Top%"
+-- $3 = 10
+-- $4 = 20
@@ -0,0 +1,2 @@
+SELECT parfums.id AS perfum_id, parfums.uuid AS perfum_uuid, notes.uuid AS note_uuid, notes.name_en AS note_name, components.uuid AS component_uuid, components.name_en AS component_name, perfum_info.info_uuid FROM parfums INNER JOIN notes ON parfums.note_id=notes.id INNER JOIN components ON parfums.component_id=components.id INNER JOIN (SELECT parfum_info.id, parfum_info.uuid AS info_uuid FROM parfum_info WHERE parfum_info.uuid=$1) AS perfum_info ON parfums.parfum_info_id=perfum_info.id ORDER BY info_uuid ASC, note_name ASC, component_name ASC
+-- $1 = "4c8e2a70-1d3b-4f5e-9a6c-7b8d9e0f1a01"
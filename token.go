@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // RefreshTokenClaims ...
@@ -29,9 +31,10 @@ type AccessTokenClaims struct {
 }
 
 const (
-	accessTokenDuration  = 24     //in hours
-	refreshTokenDuration = 24 * 7 //in hours
-	certificateURL       = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+	// certificateURL and secureTokenIssuer are of the Google secure token
+	// service, used unless configured otherwise
+	certificateURL    = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+	secureTokenIssuer = "https://securetoken.google.com/"
 )
 
 var (
@@ -57,25 +60,22 @@ func init() {
 		TraceFatalError(err)
 		os.Exit(-1)
 	}
+}
 
-	if resourcesDir := os.Getenv("OPENSHIFT_DATA_DIR"); resourcesDir == "" {
-		TraceFatalError(err)
-		os.Exit(-1)
-	}
-
-	b, err := ioutil.ReadFile(filepath.Join(resourcesDir, "client_secret.json"))
+// LoadOAuthCredentials reads credentials of the OAuth client from
+// client_secret.json of dir
+func LoadOAuthCredentials(dir string) error {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "client_secret.json"))
 	if err != nil {
-		TraceFatalError(err)
-		os.Exit(-1)
-		return
+		return err
 	}
 
-	oAuthCred, err = CredentialsFromJSON(b)
+	cred, err := CredentialsFromJSON(b)
 	if err != nil {
-		TraceFatalError(err)
-		os.Exit(-1)
-		return
+		return err
 	}
+	oAuthCred = cred
+	return nil
 }
 
 func generateRandomSign(n int) ([]byte, error) {
@@ -95,7 +95,7 @@ func NewAccessToken(audience, subject string) (AccessTokenClaims, error) {
 		StandardClaims: jwt.StandardClaims{
 			Audience:  audience,
 			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: time.Now().Add(time.Duration(accessTokenDuration) * time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(config.Auth.AccessTokenLifetime).Unix(),
 			Issuer:    "fragrances-api",
 			Subject:   subject,
 		},
@@ -117,7 +117,7 @@ func NewRefreshToken(audience, subject string) (RefreshTokenClaims, error) {
 		StandardClaims: jwt.StandardClaims{
 			Audience:  audience,
 			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: time.Now().Add(time.Duration(refreshTokenDuration) * time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(config.Auth.RefreshTokenLifetime).Unix(),
 			Issuer:    "fragrances-api",
 			Subject:   subject,
 		},
@@ -169,14 +169,17 @@ func certExpirationTime(h http.Header) time.Duration {
 	return time.Duration(remainingTime) * time.Second
 }
 
-func lookupPublicKey(kid string) ([]byte, error) {
-	var err error
+func lookupPublicKey(ctx context.Context, kid string) (key []byte, err error) {
+	ctx, span := startSpan(ctx, "lookupPublicKey", attribute.String("cert.kid", kid))
+	defer func() { endSpan(span, err) }()
 
 	if cert, found := certCache.Get(kid); found {
+		span.SetAttributes(attribute.Bool("cert.cache_hit", true))
 		return []byte(cert.(string)), nil
 	}
+	span.SetAttributes(attribute.Bool("cert.cache_hit", false))
 
-	req, err := http.NewRequest("GET", certificateURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Auth.CertURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -186,6 +189,7 @@ func lookupPublicKey(kid string) ([]byte, error) {
 		return nil, err
 	}
 	defer res.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
 
 	if res.StatusCode != http.StatusOK {
 		return nil, errors.New("Response not OK")
@@ -238,14 +242,22 @@ func parseIdTokenClaims(claims jwt.MapClaims) IdTokenClaims {
 	return idToken
 }
 
+// idTokenIssuer returns the expected issuer of id tokens
+func idTokenIssuer() string {
+	if config.Auth.IdTokenIssuer != "" {
+		return config.Auth.IdTokenIssuer
+	}
+	return secureTokenIssuer + oAuthCred.ProjectID
+}
+
 //CheckIdToken ...
-func CheckIdToken(tokenString string) (IdTokenClaims, error) {
+func CheckIdToken(ctx context.Context, tokenString string) (IdTokenClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, errors.New("Unexpected signing method")
 		}
 		if kid, ok := token.Header["kid"]; ok {
-			if key, err := lookupPublicKey(kid.(string)); err == nil {
+			if key, err := lookupPublicKey(ctx, kid.(string)); err == nil {
 				return jwt.ParseRSAPublicKeyFromPEM(key)
 			}
 		}
@@ -262,7 +274,7 @@ func CheckIdToken(tokenString string) (IdTokenClaims, error) {
 		if idToken.Exp > float64(time.Now().Unix()) &&
 			idToken.Iat <= float64(time.Now().Unix()) &&
 			idToken.Aud == oAuthCred.ProjectID &&
-			idToken.Iss == "https://securetoken.google.com/"+oAuthCred.ProjectID &&
+			idToken.Iss == idTokenIssuer() &&
 			idToken.Sub != "" &&
 			idToken.Sub == idToken.UserId {
 			return idToken, nil
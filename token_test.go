@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLookupPublicKey(t *testing.T) {
+	issuer, err := newFakeIssuer("fragrances-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+	prevCertURL := config.Auth.CertURL
+	defer func() { config.Auth.CertURL = prevCertURL }()
+	config.Auth.CertURL = issuer.CertURL()
+
+	ctx := context.Background()
+	certCache.Flush()
+	defer certCache.Flush()
+	issuer.SetCacheHeaders(3600, 600)
+	requests := issuer.CertRequests()
+
+	for i := 0; i < 2; i++ {
+		key, err := lookupPublicKey(ctx, issuer.kid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(key) != issuer.cert {
+			t.Fatalf("certificate is %q, expected %q", key, issuer.cert)
+		}
+	}
+	if n := issuer.CertRequests() - requests; n != 1 {
+		t.Errorf("certificates are requested %d times, expected once", n)
+	}
+	if _, found := certCache.Get(issuer.kid); !found {
+		t.Error("certificate is not cached")
+	}
+
+	// an unknown key refreshes the certificates
+	if _, err := lookupPublicKey(ctx, "unknown-key"); err == nil {
+		t.Error("unknown key is found")
+	}
+	if n := issuer.CertRequests() - requests; n != 2 {
+		t.Errorf("certificates are requested %d times, expected twice", n)
+	}
+
+	// expired certificates are not cached
+	certCache.Flush()
+	issuer.SetCacheHeaders(3600, 3600)
+	if _, err := lookupPublicKey(ctx, issuer.kid); err == nil {
+		t.Error("certificate with expired max-age is accepted")
+	}
+}
+
+func TestCertExpirationTime(t *testing.T) {
+	cases := []struct {
+		CacheControl string
+		Age          string
+		Expiration   time.Duration
+	}{
+		{"public, max-age=19302, must-revalidate, no-transform", "0", 19302 * time.Second},
+		{"public, max-age=19302, must-revalidate, no-transform", "302", 19000 * time.Second},
+		{"public, max-age=100", "100", 0},
+		{"public, max-age=100", "", 0},
+		{"no-cache", "0", 0},
+	}
+	for _, c := range cases {
+		h := http.Header{}
+		h.Set("Cache-Control", c.CacheControl)
+		h.Set("Age", c.Age)
+		if expiration := certExpirationTime(h); expiration != c.Expiration {
+			t.Errorf("expiration of %q and age %q is %v, expected %v", c.CacheControl, c.Age, expiration, c.Expiration)
+		}
+	}
+}
@@ -1,32 +1,211 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// LogLevel ...
+type LogLevel int32
+
+const (
+	LOG_DEBUG LogLevel = iota
+	LOG_INFO
+	LOG_WARN
+	LOG_ERROR
+	LOG_FATAL
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error", "fatal"}
+
+func (level LogLevel) String() string {
+	if level < LOG_DEBUG || level > LOG_FATAL {
+		return strconv.Itoa(int(level))
+	}
+	return logLevelNames[level]
+}
+
+// ParseLogLevel ...
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return LogLevel(i), nil
+		}
+	}
+	return LOG_INFO, errors.New("unknown log level " + name)
+}
+
+// LogField is a key value pair attached to a log record
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// F makes a log field
+func F(key string, value interface{}) LogField {
+	return LogField{Key: key, Value: value}
+}
+
+// AppLogger is the leveled application logger writing json or logfmt records.
+// The level can be changed at runtime.
+type AppLogger struct {
+	out    *log.Logger
+	format string
+	level  int32
+}
+
+// appLog is created at package initialization, before init functions which
+// may already log, and is replaced by InitAppLog once config is loaded
+var appLog = NewAppLogger(os.Stderr, LOG_FORMAT_LOGFMT, LOG_INFO)
+
+// InitAppLog replaces appLog by the logger of the log config
+func InitAppLog(c LogConfig) error {
+	level, err := ParseLogLevel(c.Level)
+	if err != nil {
+		return err
+	}
+	appLog = NewAppLogger(os.Stderr, c.Format, level)
+	return nil
+}
+
+// NewAppLogger returns logger writing records of level and above to out
+func NewAppLogger(out io.Writer, format string, level LogLevel) *AppLogger {
+	if format != LOG_FORMAT_JSON {
+		format = LOG_FORMAT_LOGFMT
+	}
+	return &AppLogger{out: log.New(out, "", 0), format: format, level: int32(level)}
+}
+
+func (l *AppLogger) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32(&l.level))
+}
+
+func (l *AppLogger) SetLevel(level LogLevel) {
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// Enabled reports whether records of level are written
+func (l *AppLogger) Enabled(level LogLevel) bool {
+	return level >= l.Level()
+}
+
+// Log writes the record if level is enabled
+func (l *AppLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields = append([]LogField{
+		F("time", time.Now().Format(time.RFC3339Nano)),
+		F("level", level.String()),
+		F("msg", msg),
+	}, fields...)
+
+	buff := &bytes.Buffer{}
+	if l.format == LOG_FORMAT_JSON {
+		buff.WriteByte('{')
+		for i, field := range fields {
+			if i > 0 {
+				buff.WriteByte(',')
+			}
+			key, _ := json.Marshal(field.Key)
+			buff.Write(key)
+			buff.WriteByte(':')
+			if err, ok := field.Value.(error); ok {
+				field.Value = err.Error()
+			}
+			value, err := json.Marshal(field.Value)
+			if err != nil {
+				value, _ = json.Marshal(fmt.Sprint(field.Value))
+			}
+			buff.Write(value)
+		}
+		buff.WriteByte('}')
+	} else {
+		for i, field := range fields {
+			if i > 0 {
+				buff.WriteByte(' ')
+			}
+			writeLogfmtPair(buff, field.Key, fmt.Sprint(field.Value))
+		}
+	}
+	l.out.Println(buff.String())
+}
+
+func (l *AppLogger) Debug(msg string, fields ...LogField) {
+	l.Log(LOG_DEBUG, msg, fields...)
+}
+
+func (l *AppLogger) Info(msg string, fields ...LogField) {
+	l.Log(LOG_INFO, msg, fields...)
+}
+
+func (l *AppLogger) Warn(msg string, fields ...LogField) {
+	l.Log(LOG_WARN, msg, fields...)
+}
+
+func (l *AppLogger) Error(msg string, fields ...LogField) {
+	l.Log(LOG_ERROR, msg, fields...)
+}
+
+// writeLogfmtPair writes key=value quoting the value if needed
+func writeLogfmtPair(buff *bytes.Buffer, key, value string) {
+	buff.WriteString(key)
+	buff.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		buff.WriteString(strconv.Quote(value))
+	} else {
+		buff.WriteString(value)
+	}
+}
+
+// callerFields returns the function and position of the caller of the
+// Trace* helper
+func callerFields() []LogField {
+	pc, fn, line, _ := runtime.Caller(2)
+	return []LogField{
+		F("func", runtime.FuncForPC(pc).Name()),
+		F("caller", filepath.Base(fn)+":"+strconv.Itoa(line)),
+	}
+}
+
 func TracePrint(s string) {
-	pc, fn, line, _ := runtime.Caller(1)
-	log.Printf("[error] in %s[%s:%d] %s", runtime.FuncForPC(pc).Name(), filepath.Base(fn), line, s)
+	appLog.Error(s, callerFields()...)
 }
 
 func TracePrintError(err error) {
-	pc, fn, line, _ := runtime.Caller(1)
-	log.Printf("[error] in %s[%s:%d] %v", runtime.FuncForPC(pc).Name(), filepath.Base(fn), line, err)
+	appLog.Error(fmt.Sprint(err), callerFields()...)
 }
 
 func TraceFatal(s string) {
-	pc, fn, line, _ := runtime.Caller(1)
-	log.Fatalf("[fatal] in %s[%s:%d] %s", runtime.FuncForPC(pc).Name(), filepath.Base(fn), line, s)
+	appLog.Log(LOG_FATAL, s, callerFields()...)
+	os.Exit(1)
 }
 
 func TraceFatalError(err error) {
-	pc, fn, line, _ := runtime.Caller(1)
-	log.Fatalf("[fatal] in %s[%s:%d] %v", runtime.FuncForPC(pc).Name(), filepath.Base(fn), line, err)
+	appLog.Log(LOG_FATAL, fmt.Sprint(err), callerFields()...)
+	os.Exit(1)
+}
+
+func TraceWarn(s string) {
+	appLog.Warn(s, callerFields()...)
+}
+
+func TraceInfo(s string) {
+	appLog.Info(s, callerFields()...)
 }
 
 func TraceDebug(s string) {
-	pc, fn, line, _ := runtime.Caller(1)
-	log.Printf("[debug] in %s[%s:%d] %s", runtime.FuncForPC(pc).Name(), filepath.Base(fn), line, s)
+	appLog.Debug(s, callerFields()...)
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TRACING_SERVICE_NAME = "fragrancesapi"
+
+	TRACING_EXPORTER_NONE   = "none"
+	TRACING_EXPORTER_STDOUT = "stdout"
+	TRACING_EXPORTER_OTLP   = "otlp"
+)
+
+var tracer = otel.Tracer("github.com/rpiskun/fragrancesapi")
+
+// InitTracing installs the tracer provider of the exporter of c: none,
+// stdout or otlp. The otlp exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* variables. The returned function flushes and stops
+// the exporter.
+func InitTracing(c TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch c.Exporter {
+	case TRACING_EXPORTER_NONE:
+		return func(context.Context) error { return nil }, nil
+	case TRACING_EXPORTER_STDOUT:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case TRACING_EXPORTER_OTLP:
+		exporter, err = otlptracehttp.New(context.Background())
+	default:
+		return nil, errors.New("unknown tracing exporter " + c.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", TRACING_SERVICE_NAME),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracing is a middleware starting a server span named after the route,
+// continuing the trace of W3C traceparent header
+type Tracing struct {
+	*RouteNamer
+}
+
+// NewTracing returns the middleware resolving route names with namer
+func NewTracing(namer *RouteNamer) *Tracing {
+	return &Tracing{RouteNamer: namer}
+}
+
+func (t *Tracing) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	route := t.RouteName(r)
+	if route == "" {
+		route = "unmatched"
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+		))
+	defer span.End()
+
+	next(rw, r.WithContext(ctx))
+
+	status := rw.(negroni.ResponseWriter).Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// startSpan starts a client span of an outgoing call
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records err if any and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func startQuerySpan(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return startSpan(ctx, "sql "+name,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.query", name),
+		attribute.String("db.statement", query),
+	)
+}
+
+// selectQuery selects the rows of the query q named name into holder
+func selectQuery(ctx context.Context, holder interface{}, name string, q *SelectQuery) error {
+	query, args := q.Build()
+	return readSelect(ctx, holder, name, query, args...)
+}
+
+// selectIntQuery selects the single integer value of the query q named name
+func selectIntQuery(ctx context.Context, name string, q *SelectQuery) (int64, error) {
+	query, args := q.Build()
+	return readSelectInt(ctx, name, query, args...)
+}
+
+// jsonRenderer is a response rendered as json
+type jsonRenderer interface {
+	Json(w http.ResponseWriter, status int) error
+}
+
+// renderJson renders obj as the response of r within a span, so the time
+// spent encoding large responses is told apart from their queries
+func renderJson(w http.ResponseWriter, r *http.Request, obj jsonRenderer, status int) error {
+	_, span := tracer.Start(r.Context(), "render json", trace.WithSpanKind(trace.SpanKindInternal))
+	err := obj.Json(w, status)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	testSpansOnce sync.Once
+	testSpans     *tracetest.InMemoryExporter
+)
+
+// resetSpans returns the exporter of the spans ended from now on. The tracer
+// of the package delegates to the first provider set, so it is set once.
+func resetSpans() *tracetest.InMemoryExporter {
+	testSpansOnce.Do(func() {
+		testSpans = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(testSpans)))
+	})
+	testSpans.Reset()
+	return testSpans
+}
+
+func spanNames(spans *tracetest.InMemoryExporter) map[string]bool {
+	names := map[string]bool{}
+	for _, span := range spans.GetSpans() {
+		names[span.Name] = true
+	}
+	return names
+}
+
+func TestRenderJsonSpan(t *testing.T) {
+	spans := resetSpans()
+
+	w := httptest.NewRecorder()
+	review := NewReviewV1(&ReviewDB{Score: 4}, SNAPSHOT_UUID)
+	if err := renderJson(w, httptest.NewRequest("GET", "/", nil), review, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !spanNames(spans)["render json"] {
+		t.Errorf("status is %d, spans are %v", w.Code, spanNames(spans))
+	}
+}
+
+func TestReadQuerySpans(t *testing.T) {
+	spans := resetSpans()
+	withFlakyDb(t)
+
+	ctx := context.Background()
+	if _, err := (pgPerfumRepository{}).SimilarCount(ctx, SNAPSHOT_UUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (pgOfferRepository{}).Count(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	names := spanNames(spans)
+	for _, name := range []string{"sql similarity_count", "sql offer_count"} {
+		if !names[name] {
+			t.Errorf("span %s is not recorded: %v", name, names)
+		}
+	}
+}
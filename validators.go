@@ -5,7 +5,6 @@ import (
 	"errors"
 	// "fmt"
 
-	"github.com/gorilla/context"
 	"github.com/unrolled/render"
 	// "io"
 	// "io/ioutil"
@@ -41,8 +40,12 @@ func ValidateAccessToken(w http.ResponseWriter, r *http.Request, next http.Handl
 		return
 	}
 
-	user, err := GetUserByAccessToken(tok)
+	user, err := GetUserByAccessToken(r.Context(), tok)
 	if err != nil {
+		renderServerError(w, r, err)
+		return
+	}
+	if user == nil {
 		jsonRender.JSON(w, http.StatusUnauthorized, map[string]string{"status": "unauthorized"})
 		return
 	}
@@ -55,7 +58,5 @@ func ValidateAccessToken(w http.ResponseWriter, r *http.Request, next http.Handl
 	// fmt.Println("Access token:", tok)
 	// fmt.Println("URL:", r.URL.RequestURI())
 
-	context.Set(r, "user", user)
-	context.Set(r, "token", accessTokenClaims)
-	next(w, r)
+	next(w, withIdentity(r, user, accessTokenClaims))
 }